@@ -1,2 +1,16 @@
 // Package with some helpful tools to use APM with your app.
+//
+// Besides NewRelic, it has a vendor-neutral tracer compatible with OpenTelemetry,
+// propagating traces with W3C traceparent header:
+//
+//  exporter := fdapm.NewOTLPExporter(fdapm.OTLPConfig{ServiceName: "orders"})
+//  defer exporter.Shutdown(ctx)
+//  tracer := fdapm.NewTracer(exporter)
+//
+//  router.Use(fdapm.TraceMiddleware(tracer))
+//  client.Use(fdapm.TraceTransport(tracer))
+//
+//  // inside of handlers
+//  ctx, span := fdapm.StartSpan(ctx, "load-restaurant")
+//  defer span.End()
 package fdapm
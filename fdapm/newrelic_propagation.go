@@ -0,0 +1,55 @@
+package fdapm
+
+import (
+	"context"
+
+	newrelic "github.com/newrelic/go-agent"
+)
+
+// NewRelicPayloadAttribute is the message attribute used to send NewRelic
+// distributed trace payloads.
+const NewRelicPayloadAttribute = "newrelic"
+
+type newRelicPayloadKey struct{}
+
+// NewRelicPropagator send the distributed trace payload of the NewRelic
+// transaction through message attributes. It implements pubsub.Propagator.
+//
+// Consumers should start a transaction for each message and accept the payload:
+//
+//  txn := app.StartTransaction("process-order", nil, nil)
+//  defer txn.End()
+//  fdapm.AcceptNewRelicPayload(msg.Context(), txn)
+type NewRelicPropagator struct{}
+
+// Inject create a payload of the transaction in ctx, if there is one.
+func (NewRelicPropagator) Inject(ctx context.Context, attrs map[string]string) {
+	txn := newrelic.FromContext(ctx)
+	if txn == nil {
+		return
+	}
+
+	if payload := txn.CreateDistributedTracePayload().HTTPSafe(); payload != "" {
+		attrs[NewRelicPayloadAttribute] = payload
+	}
+}
+
+// Extract keep the payload in the context, it's accepted later with
+// AcceptNewRelicPayload when the transaction is started.
+func (NewRelicPropagator) Extract(ctx context.Context, attrs map[string]string) context.Context {
+	if payload := attrs[NewRelicPayloadAttribute]; payload != "" {
+		return context.WithValue(ctx, newRelicPayloadKey{}, payload)
+	}
+	return ctx
+}
+
+// AcceptNewRelicPayload link txn to the transaction that published the message,
+// nothing is done if ctx doesn't have a payload.
+func AcceptNewRelicPayload(ctx context.Context, txn newrelic.Transaction) error {
+	payload, _ := ctx.Value(newRelicPayloadKey{}).(string)
+	if payload == "" {
+		return nil
+	}
+
+	return txn.AcceptDistributedTracePayload(newrelic.TransportQueue, payload)
+}
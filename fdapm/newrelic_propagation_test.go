@@ -0,0 +1,30 @@
+package fdapm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdapm"
+	"github.com/foodora/go-ranger/fdapm/apmmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRelicPropagator(t *testing.T) {
+	txn := apmmock.NewNRTransaction(t)
+	ctx := fdapm.SetNewRelicTransaction(context.Background(), txn)
+
+	attrs := map[string]string{}
+	fdapm.NewRelicPropagator{}.Inject(ctx, attrs)
+	assert.True(t, txn.CreateDistributedTracePayloadInvoked)
+
+	// consumer without payload
+	consumer := apmmock.NewNRTransaction(t)
+	ctx = fdapm.NewRelicPropagator{}.Extract(context.Background(), attrs)
+	assert.NoError(t, fdapm.AcceptNewRelicPayload(ctx, consumer))
+	assert.False(t, consumer.AcceptDistributedTracePayloadInvoked)
+
+	attrs[fdapm.NewRelicPayloadAttribute] = "payload"
+	ctx = fdapm.NewRelicPropagator{}.Extract(context.Background(), attrs)
+	assert.NoError(t, fdapm.AcceptNewRelicPayload(ctx, consumer))
+	assert.True(t, consumer.AcceptDistributedTracePayloadInvoked)
+}
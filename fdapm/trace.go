@@ -0,0 +1,403 @@
+package fdapm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// TraceID identify a trace, it's shared by all its spans.
+type TraceID [16]byte
+
+// IsValid return false when all bytes are zero.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identify a span inside of a trace.
+type SpanID [8]byte
+
+// IsValid return false when all bytes are zero.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// FlagsSampled is set in SpanContext.TraceFlags when the trace is recorded.
+const FlagsSampled byte = 0x01
+
+// SpanContext is the part of a span that is propagated to other services.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	TraceFlags byte
+	// TraceState is vendor specific data, propagated as is.
+	TraceState string
+	// Remote is true when it was received from another service.
+	Remote bool
+}
+
+// IsValid return true when trace and span ids are informed.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Sampled return true when the trace is recorded.
+func (sc SpanContext) Sampled() bool {
+	return sc.TraceFlags&FlagsSampled != 0
+}
+
+// SpanKind describe the relationship of the span with other spans,
+// values are the same used by OpenTelemetry.
+type SpanKind int
+
+// Kinds of spans.
+const (
+	SpanKindInternal SpanKind = iota + 1
+	SpanKindServer
+	SpanKindClient
+	SpanKindProducer
+	SpanKindConsumer
+)
+
+// StatusCode of a span, values are the same used by OpenTelemetry.
+type StatusCode int
+
+// Status of spans, StatusUnset is used unless the span failed or
+// it was explicitly set as StatusOK.
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+// SpanEvent is something that happened during a span, like an error.
+type SpanEvent struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]interface{}
+}
+
+// SpanData is the immutable copy of an ended span sent to exporters.
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	SpanContext   SpanContext
+	Parent        SpanContext
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    map[string]interface{}
+	Events        []SpanEvent
+	Status        StatusCode
+	StatusMessage string
+}
+
+// SpanExporter receive spans when they end, only sampled spans are exported.
+type SpanExporter interface {
+	// ExportSpan is called in the same goroutine that ended the span, so it
+	// should not block.
+	ExportSpan(*SpanData)
+	// Shutdown send spans that were not exported yet.
+	Shutdown(ctx context.Context) error
+}
+
+// Tracer create spans and send them to the exporter.
+type Tracer struct {
+	exporter SpanExporter
+	// SampleRate is the fraction of traces started by this service that are
+	// recorded, between 0 and 1. Traces started by other services follow
+	// their decision.
+	SampleRate float64
+}
+
+// NewTracer create a tracer that records all traces.
+func NewTracer(exporter SpanExporter) *Tracer {
+	return &Tracer{
+		exporter:   exporter,
+		SampleRate: 1,
+	}
+}
+
+// Start create a span, child of the span in ctx or of the span context received
+// from another service (check fdapm.Extract). The span needs to be ended calling
+// Span.End(), and the returned context has it as the current span.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+
+	sc := SpanContext{
+		TraceID: parent.TraceID,
+		SpanID:  newSpanID(),
+	}
+	if parent.IsValid() {
+		sc.TraceFlags = parent.TraceFlags
+		sc.TraceState = parent.TraceState
+	} else {
+		sc.TraceID = newTraceID()
+		if t.sample(sc.TraceID) {
+			sc.TraceFlags = FlagsSampled
+		}
+	}
+
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:        name,
+			Kind:        kind,
+			SpanContext: sc,
+			Parent:      parent,
+			StartTime:   time.Now(),
+		},
+	}
+
+	return SetSpan(ctx, span), span
+}
+
+// sample decide based on the trace id, that way services that start traces with
+// the same id take the same decision.
+func (t *Tracer) sample(id TraceID) bool {
+	switch {
+	case t.SampleRate >= 1:
+		return true
+	case t.SampleRate <= 0:
+		return false
+	}
+	return binary.BigEndian.Uint64(id[8:]) < uint64(t.SampleRate*math.MaxUint64)
+}
+
+// Span is an operation inside of a trace, like a request or a database query.
+// All methods can be called with a nil span, that way code doesn't need to check
+// if tracing is enabled.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// StartSpan create a child of the current span in ctx using the same tracer, when
+// ctx has no span it returns nil, that way code can be instrumented without care
+// if tracing is enabled:
+//
+//  ctx, span := fdapm.StartSpan(ctx, "load-restaurant")
+//  defer span.End()
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	parent := CurrentSpan(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, SpanKindInternal)
+}
+
+// SpanContext return the identification of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// IsRecording return true when the span will be exported.
+func (s *Span) IsRecording() bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.ended && s.data.SpanContext.Sampled()
+}
+
+// SetName change the name of the span, like when the route is known only
+// after the span started.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if !s.ended {
+		s.data.Name = name
+	}
+	s.mu.Unlock()
+}
+
+// SetAttribute add an attribute to the span, values can be strings, bools, integers,
+// floats or slices of strings. Other types are converted to string.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]interface{})
+	}
+	s.data.Attributes[key] = normalizeAttribute(value)
+}
+
+// AddEvent record something that happened during the span.
+func (s *Span) AddEvent(name string, attrs map[string]interface{}) {
+	if s == nil {
+		return
+	}
+
+	event := SpanEvent{Name: name, Time: time.Now()}
+	if len(attrs) > 0 {
+		event.Attributes = make(map[string]interface{}, len(attrs))
+		for k, v := range attrs {
+			event.Attributes[k] = normalizeAttribute(v)
+		}
+	}
+
+	s.mu.Lock()
+	if !s.ended {
+		s.data.Events = append(s.data.Events, event)
+	}
+	s.mu.Unlock()
+}
+
+// SetStatus set the status of the span, message is only used with StatusError.
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// OK is final, as it's set explicitly by the application
+	if s.ended || s.data.Status == StatusOK {
+		return
+	}
+	s.data.Status = code
+	s.data.StatusMessage = ""
+	if code == StatusError {
+		s.data.StatusMessage = message
+	}
+}
+
+// RecordError add the error as an "exception" event and set the status as error.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.AddEvent("exception", map[string]interface{}{
+		"exception.type":    fmt.Sprintf("%T", err),
+		"exception.message": err.Error(),
+	})
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End the span and send it to the exporter, after that changes are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if data.SpanContext.Sampled() && s.tracer.exporter != nil {
+		s.tracer.exporter.ExportSpan(&data)
+	}
+}
+
+func normalizeAttribute(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string, bool, int64, float64:
+		return v
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	case []string:
+		return append([]string(nil), v...)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+type traceContextKey struct {
+	name string
+}
+
+var (
+	spanContextKey       = &traceContextKey{"span"}
+	remoteSpanContextKey = &traceContextKey{"remote-span-context"}
+)
+
+// SetSpan set the current span into context.
+func SetSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey, span)
+}
+
+// CurrentSpan get the current span from context, it's nil when there is no span.
+func CurrentSpan(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey).(*Span)
+	return span
+}
+
+// SetRemoteSpanContext set the span context received from another service into
+// context, the next span started will be its child.
+func SetRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteSpanContextKey, sc)
+}
+
+// SpanContextFromContext return the span context of the current span, or the one
+// received from another service when there is no current span.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := CurrentSpan(ctx); span != nil {
+		return span.SpanContext()
+	}
+
+	sc, _ := ctx.Value(remoteSpanContextKey).(SpanContext)
+	return sc
+}
@@ -0,0 +1,50 @@
+package fdapm
+
+import (
+	"context"
+	"sync"
+)
+
+var _ SpanExporter = &InMemoryExporter{}
+
+// InMemoryExporter keep spans in memory, it's useful in tests:
+//
+//  exporter := fdapm.NewInMemoryExporter()
+//  router.Use(fdapm.TraceMiddleware(fdapm.NewTracer(exporter)))
+//  ...
+//  spans := exporter.Spans()
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*SpanData
+}
+
+// NewInMemoryExporter create an empty exporter.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpan implements SpanExporter.
+func (e *InMemoryExporter) ExportSpan(span *SpanData) {
+	e.mu.Lock()
+	e.spans = append(e.spans, span)
+	e.mu.Unlock()
+}
+
+// Shutdown implements SpanExporter.
+func (e *InMemoryExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+// Spans return spans exported in the order they ended.
+func (e *InMemoryExporter) Spans() []*SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*SpanData(nil), e.spans...)
+}
+
+// Reset remove all spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
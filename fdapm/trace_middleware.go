@@ -0,0 +1,47 @@
+package fdapm
+
+import (
+	"net/http"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdhttp/fdmiddleware"
+)
+
+// TraceMiddleware create a server span for each request, continuing traces
+// received with traceparent header. Spans are named by the endpoint path, like
+// "GET /v1/people/:id", and handlers can access it with fdapm.CurrentSpan(ctx)
+// or start children with fdapm.StartSpan(ctx, name).
+func TraceMiddleware(tracer *Tracer) fdmiddleware.Middleware {
+	return fdmiddleware.MiddlewareFunc(func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, req *http.Request) {
+			ctx := Extract(req.Context(), req.Header)
+			ctx, span := tracer.Start(ctx, req.Method, SpanKindServer)
+			defer span.End()
+
+			span.SetAttribute("http.method", req.Method)
+			span.SetAttribute("http.target", req.URL.RequestURI())
+			span.SetAttribute("http.user_agent", req.UserAgent())
+			span.SetAttribute("net.peer.addr", req.RemoteAddr)
+
+			// route is only known after the router matched the request
+			fdhttp.OnEndpointMatch(ctx, func(e *fdhttp.Endpoint) {
+				span.SetName(req.Method + " " + e.Path)
+				span.SetAttribute("http.route", e.Path)
+			})
+
+			lr := &fdmiddleware.LogResponse{ResponseWriter: w}
+			next.ServeHTTP(lr, req.WithContext(ctx))
+
+			statusCode := lr.StatusCode
+			if statusCode == 0 {
+				statusCode = http.StatusOK
+			}
+			span.SetAttribute("http.status_code", statusCode)
+			if statusCode >= 500 {
+				span.SetStatus(StatusError, http.StatusText(statusCode))
+			}
+		}
+
+		return http.HandlerFunc(fn)
+	})
+}
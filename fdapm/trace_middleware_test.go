@@ -0,0 +1,62 @@
+package fdapm_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdapm"
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceMiddleware(t *testing.T) {
+	exporter := fdapm.NewInMemoryExporter()
+
+	router := fdhttp.NewRouter()
+	router.Use(fdapm.TraceMiddleware(fdapm.NewTracer(exporter)))
+	router.GET("/v1/people/:id", func(ctx context.Context) (int, interface{}) {
+		_, span := fdapm.StartSpan(ctx, "load-person")
+		span.End()
+		return http.StatusInternalServerError, nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/people/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.Spans()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+
+	child, server := spans[0], spans[1]
+	assert.Equal(t, "load-person", child.Name)
+	assert.Equal(t, server.SpanContext.SpanID, child.Parent.SpanID)
+
+	assert.Equal(t, "GET /v1/people/:id", server.Name)
+	assert.Equal(t, fdapm.SpanKindServer, server.Kind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID.String())
+	assert.Equal(t, "/v1/people/:id", server.Attributes["http.route"])
+	assert.Equal(t, "/v1/people/1", server.Attributes["http.target"])
+	assert.Equal(t, int64(http.StatusInternalServerError), server.Attributes["http.status_code"])
+	assert.Equal(t, fdapm.StatusError, server.Status)
+}
+
+func TestTraceMiddleware_NotFound(t *testing.T) {
+	exporter := fdapm.NewInMemoryExporter()
+
+	router := fdhttp.NewRouter()
+	router.Use(fdapm.TraceMiddleware(fdapm.NewTracer(exporter)))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/not-found", nil))
+
+	spans := exporter.Spans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "GET", spans[0].Name)
+		assert.Equal(t, int64(http.StatusNotFound), spans[0].Attributes["http.status_code"])
+		assert.Equal(t, fdapm.StatusUnset, spans[0].Status)
+	}
+}
@@ -0,0 +1,396 @@
+package fdapm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _ SpanExporter = &OTLPExporter{}
+
+// Defaults used by NewOTLPExporter when OTLPConfig fields are not informed.
+var (
+	DefaultOTLPEndpoint      = "http://localhost:4318/v1/traces"
+	DefaultOTLPBatchSize     = 512
+	DefaultOTLPQueueSize     = 2048
+	DefaultOTLPFlushInterval = 5 * time.Second
+	DefaultOTLPTimeout       = 10 * time.Second
+)
+
+// otlpScopeName identify spans created by this package.
+const otlpScopeName = "github.com/foodora/go-ranger/fdapm"
+
+// OTLPConfig configure the OTLP/HTTP exporter.
+type OTLPConfig struct {
+	// Endpoint of the collector, like "http://localhost:4318/v1/traces".
+	Endpoint string
+	// Headers sent in each request, like authentication tokens.
+	Headers map[string]string
+	// ServiceName is sent as the "service.name" resource attribute.
+	ServiceName string
+	// ResourceAttributes describe the service, like "deployment.environment".
+	ResourceAttributes map[string]interface{}
+	// BatchSize is the maximum number of spans sent in a request.
+	BatchSize int
+	// QueueSize is the maximum number of spans waiting to be sent, spans
+	// are dropped when the queue is full.
+	QueueSize int
+	// FlushInterval is the maximum time a span waits to be sent.
+	FlushInterval time.Duration
+	// Client is used to send requests, by default one with DefaultOTLPTimeout.
+	Client *http.Client
+}
+
+// OTLPExporter send spans to an OpenTelemetry collector using OTLP/HTTP with JSON
+// encoding. Spans are sent in batches by a background goroutine, call Shutdown
+// before the application exits to send the remaining ones.
+type OTLPExporter struct {
+	cfg      OTLPConfig
+	resource otlpResource
+
+	queue   chan *SpanData
+	flush   chan chan error
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	dropped uint64
+}
+
+// NewOTLPExporter create the exporter and start sending spans.
+func NewOTLPExporter(cfg OTLPConfig) *OTLPExporter {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = DefaultOTLPEndpoint
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultOTLPBatchSize
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultOTLPQueueSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultOTLPFlushInterval
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: DefaultOTLPTimeout}
+	}
+
+	attrs := make(map[string]interface{}, len(cfg.ResourceAttributes)+1)
+	for k, v := range cfg.ResourceAttributes {
+		attrs[k] = normalizeAttribute(v)
+	}
+	if cfg.ServiceName != "" {
+		attrs["service.name"] = cfg.ServiceName
+	}
+
+	e := &OTLPExporter{
+		cfg:      cfg,
+		resource: otlpResource{Attributes: otlpAttributes(attrs)},
+		queue:    make(chan *SpanData, cfg.QueueSize),
+		flush:    make(chan chan error),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go e.run()
+
+	return e
+}
+
+// ExportSpan implements SpanExporter, spans are dropped when the queue is full.
+func (e *OTLPExporter) ExportSpan(span *SpanData) {
+	select {
+	case e.queue <- span:
+	default:
+		atomic.AddUint64(&e.dropped, 1)
+	}
+}
+
+// Dropped return the number of spans dropped because the queue was full.
+func (e *OTLPExporter) Dropped() uint64 {
+	return atomic.LoadUint64(&e.dropped)
+}
+
+// Flush send all spans in the queue.
+func (e *OTLPExporter) Flush(ctx context.Context) error {
+	reply := make(chan error, 1)
+
+	select {
+	case e.flush <- reply:
+	case <-e.done:
+		return errors.New("fdapm: otlp exporter was shutdown")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown send the remaining spans and stop the exporter.
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.once.Do(func() {
+		close(e.stop)
+	})
+
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *OTLPExporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*SpanData, 0, e.cfg.BatchSize)
+
+	// send the batch, logging errors when nobody is waiting
+	send := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := e.send(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	// drain move all spans waiting in the queue to batches
+	drain := func() error {
+		var firstErr error
+		for {
+			select {
+			case span := <-e.queue:
+				batch = append(batch, span)
+				if len(batch) >= e.cfg.BatchSize {
+					if err := send(); err != nil && firstErr == nil {
+						firstErr = err
+					}
+				}
+			default:
+				if err := send(); err != nil && firstErr == nil {
+					firstErr = err
+				}
+				return firstErr
+			}
+		}
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= e.cfg.BatchSize {
+				if err := send(); err != nil {
+					defaultLogger.Printf("Unable to export spans: %s", err)
+				}
+			}
+		case <-ticker.C:
+			if err := send(); err != nil {
+				defaultLogger.Printf("Unable to export spans: %s", err)
+			}
+		case reply := <-e.flush:
+			reply <- drain()
+		case <-e.stop:
+			if err := drain(); err != nil {
+				defaultLogger.Printf("Unable to export spans: %s", err)
+			}
+			return
+		}
+	}
+}
+
+func (e *OTLPExporter) send(batch []*SpanData) error {
+	spans := make([]otlpSpan, len(batch))
+	for i, s := range batch {
+		spans[i] = newOTLPSpan(s)
+	}
+
+	body, err := json.Marshal(&otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: e.resource,
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: otlpScopeName},
+				Spans: spans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("fdapm: otlp collector returned %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// OTLP JSON encoding, check opentelemetry-proto. Trace and span ids are
+// hex encoded and 64 bits integers are sent as strings.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	TraceState        string         `json:"traceState,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpValue `json:"values"`
+}
+
+func newOTLPSpan(s *SpanData) otlpSpan {
+	span := otlpSpan{
+		TraceID:           s.SpanContext.TraceID.String(),
+		SpanID:            s.SpanContext.SpanID.String(),
+		TraceState:        s.SpanContext.TraceState,
+		Name:              s.Name,
+		Kind:              s.Kind,
+		StartTimeUnixNano: strconv.FormatInt(s.StartTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.EndTime.UnixNano(), 10),
+		Attributes:        otlpAttributes(s.Attributes),
+		Status:            otlpStatus{Code: s.Status, Message: s.StatusMessage},
+	}
+	if s.Parent.IsValid() {
+		span.ParentSpanID = s.Parent.SpanID.String()
+	}
+
+	for _, ev := range s.Events {
+		span.Events = append(span.Events, otlpEvent{
+			TimeUnixNano: strconv.FormatInt(ev.Time.UnixNano(), 10),
+			Name:         ev.Name,
+			Attributes:   otlpAttributes(ev.Attributes),
+		})
+	}
+
+	return span
+}
+
+// otlpAttributes convert attributes sorted by key, that way requests are stable.
+func otlpAttributes(attrs map[string]interface{}) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]otlpKeyValue, len(keys))
+	for i, k := range keys {
+		kvs[i] = otlpKeyValue{Key: k, Value: newOTLPValue(attrs[k])}
+	}
+	return kvs
+}
+
+func newOTLPValue(v interface{}) otlpValue {
+	switch v := v.(type) {
+	case string:
+		return otlpValue{StringValue: &v}
+	case bool:
+		return otlpValue{BoolValue: &v}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return otlpValue{IntValue: &s}
+	case float64:
+		return otlpValue{DoubleValue: &v}
+	case []string:
+		values := make([]otlpValue, len(v))
+		for i := range v {
+			values[i] = otlpValue{StringValue: &v[i]}
+		}
+		return otlpValue{ArrayValue: &otlpArrayValue{Values: values}}
+	}
+
+	s := fmt.Sprint(v)
+	return otlpValue{StringValue: &s}
+}
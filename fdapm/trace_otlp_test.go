@@ -0,0 +1,110 @@
+package fdapm_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/fdapm"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	fdapm.SetLogger(log.New(ioutil.Discard, "", 0))
+}
+
+type otlpCollector struct {
+	mu       sync.Mutex
+	requests []map[string]interface{}
+	headers  []http.Header
+}
+
+func (c *otlpCollector) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var body map[string]interface{}
+	json.NewDecoder(req.Body).Decode(&body)
+
+	c.mu.Lock()
+	c.requests = append(c.requests, body)
+	c.headers = append(c.headers, req.Header)
+	c.mu.Unlock()
+}
+
+func TestOTLPExporter(t *testing.T) {
+	collector := &otlpCollector{}
+	ts := httptest.NewServer(collector)
+	defer ts.Close()
+
+	exporter := fdapm.NewOTLPExporter(fdapm.OTLPConfig{
+		Endpoint:      ts.URL + "/v1/traces",
+		Headers:       map[string]string{"Authorization": "Bearer token"},
+		ServiceName:   "orders",
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	tracer := fdapm.NewTracer(exporter)
+
+	ctx, root := tracer.Start(context.Background(), "GET /v1/orders", fdapm.SpanKindServer)
+	root.SetAttribute("http.status_code", 200)
+	_, child := fdapm.StartSpan(ctx, "query")
+	child.End()
+	root.End()
+
+	// third span is sent only when flushing
+	_, other := tracer.Start(context.Background(), "other", fdapm.SpanKindInternal)
+	other.End()
+	assert.NoError(t, exporter.Flush(context.Background()))
+	assert.NoError(t, exporter.Shutdown(context.Background()))
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	if !assert.Len(t, collector.requests, 2) {
+		return
+	}
+	assert.Equal(t, "application/json", collector.headers[0].Get("Content-Type"))
+	assert.Equal(t, "Bearer token", collector.headers[0].Get("Authorization"))
+
+	resourceSpans := collector.requests[0]["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"attributes": []interface{}{
+			map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "orders"}},
+		},
+	}, resourceSpans["resource"])
+
+	spans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+
+	span := spans[1].(map[string]interface{})
+	assert.Equal(t, root.SpanContext().TraceID.String(), span["traceId"])
+	assert.Equal(t, root.SpanContext().SpanID.String(), span["spanId"])
+	assert.Equal(t, "GET /v1/orders", span["name"])
+	assert.Equal(t, float64(fdapm.SpanKindServer), span["kind"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"key": "http.status_code", "value": map[string]interface{}{"intValue": "200"}},
+	}, span["attributes"])
+	assert.Equal(t, root.SpanContext().SpanID.String(), spans[0].(map[string]interface{})["parentSpanId"])
+}
+
+func TestOTLPExporter_CollectorError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	exporter := fdapm.NewOTLPExporter(fdapm.OTLPConfig{Endpoint: ts.URL})
+	defer exporter.Shutdown(context.Background())
+
+	_, span := fdapm.NewTracer(exporter).Start(context.Background(), "span", fdapm.SpanKindInternal)
+	span.End()
+
+	err := exporter.Flush(context.Background())
+	assert.EqualError(t, err, "fdapm: otlp collector returned 400: invalid request")
+}
@@ -0,0 +1,137 @@
+package fdapm
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Headers used to propagate traces, defined by W3C Trace Context.
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+const traceParentVersion = "00"
+
+// FormatTraceParent return the traceparent value of sc, like
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func FormatTraceParent(sc SpanContext) string {
+	return traceParentVersion + "-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.TraceFlags})
+}
+
+// ParseTraceParent parse a traceparent value, ok is false when it's invalid.
+func ParseTraceParent(v string) (sc SpanContext, ok bool) {
+	v = strings.TrimSpace(v)
+
+	// version 00 has exactly 55 chars, future versions may append fields
+	if len(v) < 55 || (len(v) > 55 && (v[:2] == traceParentVersion || v[55] != '-')) {
+		return SpanContext{}, false
+	}
+	if v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return SpanContext{}, false
+	}
+
+	version, ok := decodeLowerHex(v[:2])
+	if !ok || version[0] == 0xff {
+		return SpanContext{}, false
+	}
+
+	traceID, ok := decodeLowerHex(v[3:35])
+	if !ok {
+		return SpanContext{}, false
+	}
+	spanID, ok := decodeLowerHex(v[36:52])
+	if !ok {
+		return SpanContext{}, false
+	}
+	flags, ok := decodeLowerHex(v[53:55])
+	if !ok {
+		return SpanContext{}, false
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.TraceFlags = flags[0] & FlagsSampled
+
+	return sc, sc.IsValid()
+}
+
+func decodeLowerHex(s string) ([]byte, bool) {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return nil, false
+		}
+	}
+
+	b, err := hex.DecodeString(s)
+	return b, err == nil
+}
+
+// Inject add the trace context of the current span in ctx into headers, that way
+// the next service continues the same trace.
+func Inject(ctx context.Context, header http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	header.Set(TraceParentHeader, FormatTraceParent(sc))
+	if sc.TraceState != "" {
+		header.Set(TraceStateHeader, sc.TraceState)
+	} else {
+		header.Del(TraceStateHeader)
+	}
+}
+
+// Extract read the trace context sent by another service, the next span started
+// with the returned context will be its child. Invalid headers are ignored.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, ok := ParseTraceParent(header.Get(TraceParentHeader))
+	if !ok {
+		return ctx
+	}
+
+	// multiple tracestate headers are combined as a list
+	sc.TraceState = strings.Join(header[http.CanonicalHeaderKey(TraceStateHeader)], ",")
+	return SetRemoteSpanContext(ctx, sc)
+}
+
+// TracePropagator send the trace context through message attributes, using the
+// same format as http headers. It implements pubsub.Propagator:
+//
+//  pubsub.DefaultPropagator = pubsub.Propagators{
+//  	pubsub.CorrelationIDPropagator{},
+//  	fdapm.TracePropagator{},
+//  }
+//
+//  // consumers continue the trace started by the publisher
+//  ctx, span := tracer.Start(msg.Context(), "process-order", fdapm.SpanKindConsumer)
+//  defer span.End()
+type TracePropagator struct{}
+
+// Inject add traceparent and tracestate attributes of the current span in ctx.
+func (TracePropagator) Inject(ctx context.Context, attrs map[string]string) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	attrs[TraceParentHeader] = FormatTraceParent(sc)
+	if sc.TraceState != "" {
+		attrs[TraceStateHeader] = sc.TraceState
+	}
+}
+
+// Extract read the trace context sent by the publisher, invalid attributes are
+// ignored.
+func (TracePropagator) Extract(ctx context.Context, attrs map[string]string) context.Context {
+	sc, ok := ParseTraceParent(attrs[TraceParentHeader])
+	if !ok {
+		return ctx
+	}
+
+	sc.TraceState = attrs[TraceStateHeader]
+	return SetRemoteSpanContext(ctx, sc)
+}
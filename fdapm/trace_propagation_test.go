@@ -0,0 +1,93 @@
+package fdapm_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdapm"
+	"github.com/foodora/go-ranger/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	sc, ok := fdapm.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Sampled())
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", fdapm.FormatTraceParent(sc))
+
+	// future versions can have more fields
+	_, ok = fdapm.ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	assert.True(t, ok)
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0g",
+	}
+	for _, v := range invalid {
+		_, ok := fdapm.ParseTraceParent(v)
+		assert.False(t, ok, v)
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	header.Add("tracestate", "a=1")
+	header.Add("tracestate", "b=2")
+
+	ctx := fdapm.Extract(context.Background(), header)
+	sc := fdapm.SpanContextFromContext(ctx)
+	assert.True(t, sc.Remote)
+	assert.Equal(t, "a=1,b=2", sc.TraceState)
+
+	out := http.Header{}
+	fdapm.Inject(ctx, out)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", out.Get("traceparent"))
+	assert.Equal(t, "a=1,b=2", out.Get("tracestate"))
+
+	// nothing to propagate
+	out = http.Header{}
+	fdapm.Inject(context.Background(), out)
+	assert.Empty(t, out)
+
+	header.Set("traceparent", "invalid")
+	ctx = fdapm.Extract(context.Background(), header)
+	assert.False(t, fdapm.SpanContextFromContext(ctx).IsValid())
+}
+
+func TestTracePropagator(t *testing.T) {
+	var _ pubsub.Propagator = fdapm.TracePropagator{}
+
+	attrs := map[string]string{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"tracestate":  "a=1",
+	}
+
+	ctx := fdapm.TracePropagator{}.Extract(context.Background(), attrs)
+	sc := fdapm.SpanContextFromContext(ctx)
+	assert.True(t, sc.Remote)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "a=1", sc.TraceState)
+
+	out := map[string]string{}
+	fdapm.TracePropagator{}.Inject(ctx, out)
+	assert.Equal(t, attrs, out)
+
+	// nothing to propagate
+	out = map[string]string{}
+	fdapm.TracePropagator{}.Inject(context.Background(), out)
+	assert.Empty(t, out)
+
+	attrs["traceparent"] = "invalid"
+	ctx = fdapm.TracePropagator{}.Extract(context.Background(), attrs)
+	assert.False(t, fdapm.SpanContextFromContext(ctx).IsValid())
+}
@@ -0,0 +1,101 @@
+package fdapm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdapm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracer_Start(t *testing.T) {
+	exporter := fdapm.NewInMemoryExporter()
+	tracer := fdapm.NewTracer(exporter)
+
+	ctx, root := tracer.Start(context.Background(), "root", fdapm.SpanKindServer)
+	assert.True(t, root.IsRecording())
+	assert.Equal(t, root, fdapm.CurrentSpan(ctx))
+
+	childCtx, child := fdapm.StartSpan(ctx, "child")
+	child.SetAttribute("db.rows", 10)
+	child.SetAttribute("db.tables", []string{"orders"})
+	child.RecordError(errors.New("deadlock"))
+	child.End()
+
+	// changes after end are ignored
+	child.SetAttribute("late", true)
+	child.End()
+	root.End()
+
+	assert.Equal(t, child, fdapm.CurrentSpan(childCtx))
+
+	spans := exporter.Spans()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, fdapm.SpanKindInternal, spans[0].Kind)
+	assert.Equal(t, root.SpanContext().TraceID, spans[0].SpanContext.TraceID)
+	assert.Equal(t, root.SpanContext().SpanID, spans[0].Parent.SpanID)
+	assert.Equal(t, map[string]interface{}{"db.rows": int64(10), "db.tables": []string{"orders"}}, spans[0].Attributes)
+	assert.Equal(t, fdapm.StatusError, spans[0].Status)
+	assert.Equal(t, "deadlock", spans[0].StatusMessage)
+	assert.Equal(t, "exception", spans[0].Events[0].Name)
+	assert.False(t, spans[0].EndTime.Before(spans[0].StartTime))
+
+	assert.Equal(t, "root", spans[1].Name)
+	assert.False(t, spans[1].Parent.IsValid())
+}
+
+func TestTracer_RemoteParent(t *testing.T) {
+	exporter := fdapm.NewInMemoryExporter()
+	tracer := fdapm.NewTracer(exporter)
+	tracer.SampleRate = 0
+
+	remote, _ := fdapm.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	remote.TraceState = "vendor=1"
+	ctx := fdapm.SetRemoteSpanContext(context.Background(), remote)
+
+	// sampled by the caller, even if this service doesn't sample
+	_, span := tracer.Start(ctx, "server", fdapm.SpanKindServer)
+	span.End()
+
+	spans := exporter.Spans()
+	if !assert.Len(t, spans, 1) {
+		return
+	}
+	assert.Equal(t, remote.TraceID, spans[0].SpanContext.TraceID)
+	assert.Equal(t, remote.SpanID, spans[0].Parent.SpanID)
+	assert.True(t, spans[0].Parent.Remote)
+	assert.Equal(t, "vendor=1", spans[0].SpanContext.TraceState)
+}
+
+func TestTracer_NotSampled(t *testing.T) {
+	exporter := fdapm.NewInMemoryExporter()
+	tracer := fdapm.NewTracer(exporter)
+	tracer.SampleRate = 0
+
+	ctx, span := tracer.Start(context.Background(), "root", fdapm.SpanKindServer)
+	assert.False(t, span.IsRecording())
+	assert.True(t, span.SpanContext().IsValid())
+
+	_, child := fdapm.StartSpan(ctx, "child")
+	child.End()
+	span.End()
+
+	assert.Empty(t, exporter.Spans())
+}
+
+func TestStartSpan_WithoutTracer(t *testing.T) {
+	ctx, span := fdapm.StartSpan(context.Background(), "child")
+	assert.Nil(t, span)
+	assert.Nil(t, fdapm.CurrentSpan(ctx))
+
+	// nil spans can be used safely
+	span.SetAttribute("key", "value")
+	span.RecordError(errors.New("error"))
+	span.End()
+	assert.False(t, span.IsRecording())
+}
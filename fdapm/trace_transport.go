@@ -0,0 +1,51 @@
+package fdapm
+
+import (
+	"net/http"
+
+	"github.com/foodora/go-ranger/fdhttp/fdmiddleware"
+)
+
+// TraceTransport return a fdmiddleware.ClientMiddleware that create a client span
+// for each http call and send the trace context to the other service. The parent
+// span is taken from the request context, so unlike NewRelicTransport the same
+// client can be shared by all requests:
+//
+//  client := fdhttp.NewClient()
+//  client.Use(fdapm.TraceTransport(tracer))
+//
+//  req, _ := http.NewRequest(http.MethodGet, "http://www.foodora.de", nil)
+//  resp, err := client.Do(req.WithContext(ctx))
+func TraceTransport(tracer *Tracer) fdmiddleware.ClientMiddleware {
+	return fdmiddleware.ClientMiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
+		return fdmiddleware.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx, span := tracer.Start(req.Context(), req.Method, SpanKindClient)
+			defer span.End()
+
+			span.SetAttribute("http.method", req.Method)
+			span.SetAttribute("http.url", req.URL.String())
+			span.SetAttribute("net.peer.name", req.URL.Hostname())
+
+			// RoundTripper should not modify the request
+			outReq := req.WithContext(ctx)
+			outReq.Header = make(http.Header, len(req.Header)+2)
+			for k, v := range req.Header {
+				outReq.Header[k] = v
+			}
+			Inject(ctx, outReq.Header)
+
+			resp, err := next.RoundTrip(outReq)
+			if err != nil {
+				span.RecordError(err)
+				return resp, err
+			}
+
+			span.SetAttribute("http.status_code", resp.StatusCode)
+			if resp.StatusCode >= 500 {
+				span.SetStatus(StatusError, http.StatusText(resp.StatusCode))
+			}
+
+			return resp, err
+		})
+	})
+}
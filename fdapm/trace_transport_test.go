@@ -0,0 +1,65 @@
+package fdapm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdapm"
+	"github.com/foodora/go-ranger/fdhttp/fdmiddleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceTransport(t *testing.T) {
+	exporter := fdapm.NewInMemoryExporter()
+	tracer := fdapm.NewTracer(exporter)
+
+	var received http.Header
+	transport := fdapm.TraceTransport(tracer).Wrap(fdmiddleware.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		received = req.Header
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}))
+
+	ctx, parent := tracer.Start(context.Background(), "handler", fdapm.SpanKindServer)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://www.foodora.de/v1/vendors", nil)
+	req = req.WithContext(ctx)
+	_, err := transport.RoundTrip(req)
+	assert.NoError(t, err)
+	parent.End()
+
+	// original request is not modified
+	assert.Empty(t, req.Header.Get("traceparent"))
+
+	spans := exporter.Spans()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+
+	client := spans[0]
+	assert.Equal(t, fdapm.SpanKindClient, client.Kind)
+	assert.Equal(t, parent.SpanContext().SpanID, client.Parent.SpanID)
+	assert.Equal(t, "www.foodora.de", client.Attributes["net.peer.name"])
+	assert.Equal(t, int64(http.StatusOK), client.Attributes["http.status_code"])
+	assert.Equal(t, fdapm.FormatTraceParent(client.SpanContext), received.Get("traceparent"))
+}
+
+func TestTraceTransport_Error(t *testing.T) {
+	exporter := fdapm.NewInMemoryExporter()
+
+	expectedErr := errors.New("connection refused")
+	transport := fdapm.TraceTransport(fdapm.NewTracer(exporter)).Wrap(fdmiddleware.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, expectedErr
+	}))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://www.foodora.de", nil)
+	_, err := transport.RoundTrip(req)
+	assert.Equal(t, expectedErr, err)
+
+	spans := exporter.Spans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, fdapm.StatusError, spans[0].Status)
+		assert.Equal(t, "connection refused", spans[0].StatusMessage)
+	}
+}
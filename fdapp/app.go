@@ -0,0 +1,232 @@
+package fdapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// DefaultGracePeriod is the time that App waits after draining before stop
+	// components, it gives time to load balancers notice the health check failing.
+	DefaultGracePeriod = 5 * time.Second
+
+	// DefaultStopTimeout is the max duration that each component has to stop.
+	DefaultStopTimeout = 15 * time.Second
+
+	// DefaultSignals are the signals that start the shutdown.
+	DefaultSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+)
+
+// ErrAppAlreadyRunning is returned when Run() is called twice.
+var ErrAppAlreadyRunning = errors.New("fdapp: app already running")
+
+// Errors is returned by Run when one or more components fail.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ComponentError is the error returned by a component.
+type ComponentError struct {
+	Name string
+	Op   string
+	Err  error
+}
+
+func (e *ComponentError) Error() string {
+	return fmt.Sprintf("fdapp: %s %s: %s", e.Name, e.Op, e.Err)
+}
+
+// App own all components of your service, like:
+//
+//  app := fdapp.New()
+//  app.AddDrainer(healthCheck)
+//  app.Add("db", fdapp.Closer(db))
+//  app.Add("orders", fdapp.Subscriber(sub, handleOrder))
+//  app.Add("http", fdapp.Server(fdhttp.NewServer("8080"), router))
+//
+//  if err := app.Run(context.Background()); err != nil {
+//      log.Fatal(err)
+//  }
+type App struct {
+	// GracePeriod is the time waiting between drain and stop.
+	GracePeriod time.Duration
+	// StopTimeout is the max duration that each component has to stop.
+	StopTimeout time.Duration
+	// Signals that start the shutdown, a second signal skip the grace period.
+	Signals []os.Signal
+	// Logger will be setted with DefaultLogger when New is called
+	// but you can overwrite later only in this instance.
+	Logger Logger
+
+	mu         sync.Mutex
+	running    bool
+	components []*namedComponent
+	drainers   []Drainer
+	shutdown   chan struct{}
+	once       sync.Once
+}
+
+type namedComponent struct {
+	name string
+	Component
+}
+
+// New create an app with default values.
+func New() *App {
+	return &App{
+		GracePeriod: DefaultGracePeriod,
+		StopTimeout: DefaultStopTimeout,
+		Signals:     DefaultSignals,
+		Logger:      defaultLogger,
+		shutdown:    make(chan struct{}),
+	}
+}
+
+// Add a component to the app. Components are started at the same time and
+// stopped in reverse order, so add first what others depend on.
+func (a *App) Add(name string, c Component) *App {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.components = append(a.components, &namedComponent{name: name, Component: c})
+	return a
+}
+
+// AddDrainer register a drainer that is notified when shutdown starts.
+func (a *App) AddDrainer(d Drainer) *App {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.drainers = append(a.drainers, d)
+	return a
+}
+
+// Shutdown start the shutdown, like receiving a signal.
+func (a *App) Shutdown() {
+	a.once.Do(func() {
+		close(a.shutdown)
+	})
+}
+
+// Run start all components and block until a signal is received, ctx is done,
+// Shutdown() is called or any component stops. After that drainers are notified,
+// it waits the grace period and stop components in reverse order.
+// All errors returned by components are aggregated in fdapp.Errors.
+func (a *App) Run(ctx context.Context) error {
+	a.mu.Lock()
+	if a.running {
+		a.mu.Unlock()
+		return ErrAppAlreadyRunning
+	}
+	a.running = true
+	components := make([]*namedComponent, len(a.components))
+	copy(components, a.components)
+	drainers := make([]Drainer, len(a.drainers))
+	copy(drainers, a.drainers)
+	a.mu.Unlock()
+
+	signals := make(chan os.Signal, 2)
+	if len(a.Signals) > 0 {
+		signal.Notify(signals, a.Signals...)
+		defer signal.Stop(signals)
+	}
+
+	var (
+		errsMu sync.Mutex
+		errs   Errors
+	)
+	addErr := func(c *namedComponent, op string, err error) {
+		errsMu.Lock()
+		errs = append(errs, &ComponentError{Name: c.name, Op: op, Err: err})
+		errsMu.Unlock()
+	}
+
+	stopped := make(chan *namedComponent, len(components))
+	var wg sync.WaitGroup
+
+	for _, c := range components {
+		wg.Add(1)
+		go func(c *namedComponent) {
+			defer wg.Done()
+
+			if err := c.Start(); err != nil {
+				addErr(c, "start", err)
+			}
+			stopped <- c
+		}(c)
+	}
+
+	skipGracePeriod := false
+
+	select {
+	case sig := <-signals:
+		a.Logger.Printf("Received %s, shutting down...", sig)
+	case <-ctx.Done():
+		a.Logger.Printf("Context is done, shutting down...")
+	case <-a.shutdown:
+		a.Logger.Printf("Shutting down...")
+	case c := <-stopped:
+		a.Logger.Printf("Component %s stopped, shutting down...", c.name)
+		// there is no reason to wait if the app is not working anymore
+		skipGracePeriod = true
+	}
+
+	for _, d := range drainers {
+		d.SetDraining(true)
+	}
+
+	if !skipGracePeriod && a.GracePeriod > 0 {
+		a.Logger.Printf("Draining for %s...", a.GracePeriod)
+
+		select {
+		case <-time.After(a.GracePeriod):
+		case sig := <-signals:
+			a.Logger.Printf("Received %s again, skipping grace period", sig)
+		}
+	}
+
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+
+		stopCtx, cancel := context.WithTimeout(context.Background(), a.StopTimeout)
+		if err := c.Stop(stopCtx); err != nil {
+			addErr(c, "stop", err)
+		}
+		cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(a.StopTimeout):
+		errsMu.Lock()
+		errs = append(errs, errors.New("fdapp: timeout waiting components to stop"))
+		errsMu.Unlock()
+	}
+
+	errsMu.Lock()
+	defer errsMu.Unlock()
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package fdapp_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/fdapp"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+}
+
+func (r *recorder) SetDraining(draining bool) {
+	r.add("drain")
+}
+
+func (r *recorder) component(name string, stopErr error) fdapp.Component {
+	done := make(chan struct{})
+	return fdapp.ComponentFunc(func() error {
+		<-done
+		return nil
+	}, func(context.Context) error {
+		r.add("stop " + name)
+		close(done)
+		return stopErr
+	})
+}
+
+func newApp() *fdapp.App {
+	app := fdapp.New()
+	app.GracePeriod = 0
+	app.StopTimeout = time.Second
+	app.Logger = log.New(ioutil.Discard, "", 0)
+	return app
+}
+
+func TestApp_StopInReverseOrder(t *testing.T) {
+	r := &recorder{}
+
+	app := newApp()
+	app.AddDrainer(r)
+	app.Add("db", r.component("db", nil))
+	app.Add("http", r.component("http", nil))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		app.Shutdown()
+	}()
+
+	err := app.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"drain", "stop http", "stop db"}, r.events)
+}
+
+func TestApp_AggregateErrors(t *testing.T) {
+	r := &recorder{}
+	errStart := errors.New("unable to connect")
+	errStop := errors.New("unable to close")
+
+	app := newApp()
+	app.Add("db", r.component("db", errStop))
+	app.Add("queue", fdapp.ComponentFunc(func() error {
+		return errStart
+	}, nil))
+
+	// queue failing stop everything
+	err := app.Run(context.Background())
+	if !assert.Error(t, err) {
+		return
+	}
+
+	errs, ok := err.(fdapp.Errors)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "fdapp: queue start: unable to connect; fdapp: db stop: unable to close", err.Error())
+}
+
+func TestApp_WaitGracePeriod(t *testing.T) {
+	r := &recorder{}
+
+	app := newApp()
+	app.GracePeriod = 50 * time.Millisecond
+	app.Add("http", r.component("http", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	started := time.Now()
+	err := app.Run(ctx)
+	assert.NoError(t, err)
+	assert.True(t, time.Since(started) >= app.GracePeriod)
+}
+
+func TestApp_StopOnSignal(t *testing.T) {
+	r := &recorder{}
+
+	app := newApp()
+	app.Add("http", r.component("http", nil))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p, _ := os.FindProcess(os.Getpid())
+		p.Signal(syscall.SIGTERM)
+	}()
+
+	err := app.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"stop http"}, r.events)
+}
+
+func TestApp_RunTwice(t *testing.T) {
+	app := newApp()
+	app.Shutdown()
+
+	assert.NoError(t, app.Run(context.Background()))
+	assert.Equal(t, fdapp.ErrAppAlreadyRunning, app.Run(context.Background()))
+}
+
+type dummyCloser struct {
+	closed bool
+}
+
+func (c *dummyCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestCloser(t *testing.T) {
+	closer := &dummyCloser{}
+
+	app := newApp()
+	app.Add("db", fdapp.Closer(closer))
+	app.Shutdown()
+
+	err := app.Run(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, closer.closed)
+}
@@ -0,0 +1,141 @@
+package fdapp
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/pubsub"
+)
+
+// Component is anything that can be started and stopped by App.
+type Component interface {
+	// Start block until the component stops, returning an error in case
+	// it was not able to start or it failed while running.
+	Start() error
+	// Stop the component, after that Start should return. The context
+	// has the deadline to stop, configured by App.StopTimeout.
+	Stop(ctx context.Context) error
+}
+
+// Drainer is notified before components are stopped, like fdhandler.HealthCheck
+// that fails when draining, so load balancers stop sending traffic.
+type Drainer interface {
+	SetDraining(bool)
+}
+
+// ComponentFunc create a component from functions, stop can be nil.
+func ComponentFunc(start func() error, stop func(context.Context) error) Component {
+	return &funcComponent{
+		start: start,
+		stop:  stop,
+	}
+}
+
+type funcComponent struct {
+	start func() error
+	stop  func(context.Context) error
+}
+
+func (c *funcComponent) Start() error {
+	return c.start()
+}
+
+func (c *funcComponent) Stop(ctx context.Context) error {
+	if c.stop == nil {
+		return nil
+	}
+	return c.stop(ctx)
+}
+
+// Server create a component that run a fdhttp.Server with the router.
+func Server(srv *fdhttp.Server, r *fdhttp.Router) Component {
+	return &serverComponent{
+		srv:    srv,
+		router: r,
+	}
+}
+
+type serverComponent struct {
+	srv    *fdhttp.Server
+	router *fdhttp.Router
+}
+
+func (c *serverComponent) Start() error {
+	err := c.srv.Start(c.router)
+	if err == fdhttp.ErrServerStopped {
+		return nil
+	}
+	return err
+}
+
+func (c *serverComponent) Stop(ctx context.Context) error {
+	err := c.srv.Stop(ctx)
+	if err == fdhttp.ErrServerNotRunning {
+		return nil
+	}
+	return err
+}
+
+// Subscriber create a component that consume all messages received by sub
+// calling fn, one by one. Stop wait until the message being processed is done.
+func Subscriber(sub pubsub.Subscriber, fn func(pubsub.Message)) Component {
+	return &subscriberComponent{
+		sub: sub,
+		fn:  fn,
+	}
+}
+
+type subscriberComponent struct {
+	sub pubsub.Subscriber
+	fn  func(pubsub.Message)
+}
+
+func (c *subscriberComponent) Start() error {
+	for msg := range c.sub.Start() {
+		c.fn(msg)
+	}
+	return c.sub.Err()
+}
+
+func (c *subscriberComponent) Stop(ctx context.Context) error {
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- c.sub.Stop()
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Closer create a component that doesn't need to start, but it's closed
+// together with the others, like a database connection.
+func Closer(closer io.Closer) Component {
+	return &closerComponent{
+		closer: closer,
+		done:   make(chan struct{}),
+	}
+}
+
+type closerComponent struct {
+	closer io.Closer
+	once   sync.Once
+	done   chan struct{}
+}
+
+func (c *closerComponent) Start() error {
+	<-c.done
+	return nil
+}
+
+func (c *closerComponent) Stop(ctx context.Context) error {
+	defer c.once.Do(func() {
+		close(c.done)
+	})
+	return c.closer.Close()
+}
@@ -0,0 +1,3 @@
+// Package fdapp run servers, subscribers and any other component of your app,
+// stopping them gracefully when SIGINT or SIGTERM is received.
+package fdapp
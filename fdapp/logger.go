@@ -0,0 +1,29 @@
+package fdapp
+
+import (
+	"log"
+	"os"
+)
+
+// Logger is the interface used internally to log
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// defaultLogger will be used as logger when create a new app using New()
+var defaultLogger Logger
+
+func init() {
+	// Set default logger
+	SetLogger(nil)
+}
+
+// SetLogger will be used as logger when create a new app using New(), but
+// it's possible update individualy an app.Logger later.
+func SetLogger(logger Logger) {
+	if logger == nil {
+		defaultLogger = log.New(os.Stdout, "[fdapp] ", log.LstdFlags)
+	} else {
+		defaultLogger = logger
+	}
+}
@@ -0,0 +1,211 @@
+package fdhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tags used to bind values from the request into struct fields.
+const (
+	BindParamTag  = "param"
+	BindQueryTag  = "query"
+	BindFormTag   = "form"
+	BindHeaderTag = "header"
+)
+
+var bindSources = []string{BindParamTag, BindQueryTag, BindFormTag, BindHeaderTag}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// Bind decode the request into v, which must be a pointer to struct. Values
+// are read from the JSON body and fields tagged with param, query, form or
+// header are filled with route params, query string, form values or headers:
+//
+//  type UpdatePerson struct {
+//      ID     int64    `param:"id"`
+//      DryRun bool     `query:"dry_run"`
+//      Locale string   `header:"Accept-Language" validate:"enum=en|de"`
+//      Name   string   `json:"name" validate:"required,max=50"`
+//      Tags   []string `json:"tags"`
+//  }
+//
+// After decode the request fdhttp.Validate is called. Any failure is
+// returned as *fdhttp.Error that can be sent to clients with status 400.
+//
+// Note that body is consumed, so fdhttp.RequestBody will be empty after that.
+// If you want to bind the request before your handler is called
+// check Endpoint.Bind().
+func Bind(ctx context.Context, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("fdhttp: bind expects a non-nil pointer to struct")
+	}
+
+	if err := bindBody(ctx, v); err != nil {
+		return &Error{
+			Code:    "invalid_body",
+			Message: err.Error(),
+		}
+	}
+
+	if errs := bindValues(ctx, rv.Elem()); len(errs) > 0 {
+		return &Error{
+			Code:    ValidationFailedCode,
+			Message: "Request is invalid, check detail for more information",
+			Detail:  errs,
+		}
+	}
+
+	return Validate(v)
+}
+
+func bindBody(ctx context.Context, v interface{}) error {
+	body := RequestBody(ctx)
+	if body == nil {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(RequestHeaderValue(ctx, "Content-Type"))
+	if mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data" {
+		// form values are parsed by Router.ServeHTTP
+		return nil
+	}
+
+	buf, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	if len(bytes.TrimSpace(buf)) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(buf, v)
+}
+
+func bindValues(ctx context.Context, v reflect.Value) []FieldError {
+	var errs []FieldError
+
+	for _, f := range structFields(v.Type()) {
+		if f.source == "" {
+			continue
+		}
+
+		values := bindSourceValues(ctx, f.source, f.key)
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setValue(v.FieldByIndex(f.index), values); err != nil {
+			errs = append(errs, FieldError{
+				Field:   f.name,
+				Rule:    "type",
+				Message: err.Error(),
+			})
+		}
+	}
+
+	return errs
+}
+
+func bindSourceValues(ctx context.Context, source, key string) []string {
+	switch source {
+	case BindParamTag:
+		if v, ok := RouteParams(ctx)[key]; ok {
+			return []string{v}
+		}
+	case BindQueryTag:
+		if req := Request(ctx); req != nil {
+			return req.URL.Query()[key]
+		}
+	case BindFormTag:
+		return RequestForm(ctx)[key]
+	case BindHeaderTag:
+		return RequestHeader(ctx)[http.CanonicalHeaderKey(key)]
+	}
+
+	return nil
+}
+
+// setValue convert the string values to the field type.
+func setValue(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), values)
+	}
+
+	if v.Kind() == reflect.Slice && !v.Addr().Type().Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, s := range values {
+			if err := setValue(slice.Index(i), []string{s}); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	s := values[0]
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration '%s'", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean '%s'", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer '%s'", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(s), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer '%s'", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number '%s'", s)
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package fdhttp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+type updatePerson struct {
+	ID      int64         `param:"id" validate:"min=1"`
+	DryRun  bool          `query:"dry_run"`
+	Fields  []string      `query:"fields"`
+	Timeout time.Duration `form:"timeout"`
+	Locale  *string       `header:"Accept-Language" validate:"enum=en|de"`
+	Name    string        `json:"name" validate:"required,max=10"`
+}
+
+func newBindContext(body string, header http.Header, params map[string]string) context.Context {
+	req := httptest.NewRequest(http.MethodPut, "/v1/people/1?dry_run=true&fields=name&fields=age&timeout=1s", strings.NewReader(body))
+	req.Header = header
+	req.ParseForm()
+
+	ctx := context.Background()
+	ctx = fdhttp.SetRequest(ctx, req)
+	ctx = fdhttp.SetRequestHeader(ctx, header)
+	ctx = fdhttp.SetRequestBody(ctx, req.Body)
+	ctx = fdhttp.SetRequestForm(ctx, req.Form)
+	ctx = fdhttp.SetRouteParams(ctx, params)
+
+	return ctx
+}
+
+func TestBind(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Accept-Language", "de")
+
+	ctx := newBindContext(`{"name": "John"}`, header, map[string]string{"id": "123"})
+
+	var v updatePerson
+	err := fdhttp.Bind(ctx, &v)
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(123), v.ID)
+	assert.True(t, v.DryRun)
+	assert.Equal(t, []string{"name", "age"}, v.Fields)
+	assert.Equal(t, time.Second, v.Timeout)
+	if assert.NotNil(t, v.Locale) {
+		assert.Equal(t, "de", *v.Locale)
+	}
+	assert.Equal(t, "John", v.Name)
+}
+
+func TestBind_InvalidValues(t *testing.T) {
+	header := http.Header{}
+	header.Set("Accept-Language", "pt")
+
+	ctx := newBindContext(`{"name": "John"}`, header, map[string]string{"id": "abc"})
+
+	var v updatePerson
+	err := fdhttp.Bind(ctx, &v)
+
+	respErr, ok := err.(*fdhttp.Error)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	assert.Equal(t, fdhttp.ValidationFailedCode, respErr.Code)
+	assert.Equal(t, []fdhttp.FieldError{
+		{Field: "id", Rule: "type", Message: "invalid integer 'abc'"},
+	}, respErr.Detail)
+
+	ctx = newBindContext(`{}`, header, map[string]string{"id": "0"})
+
+	err = fdhttp.Bind(ctx, &updatePerson{})
+	respErr, ok = err.(*fdhttp.Error)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	assert.Equal(t, fdhttp.ValidationFailedCode, respErr.Code)
+	assert.Equal(t, []fdhttp.FieldError{
+		{Field: "id", Rule: "min", Message: "must be at least 1"},
+		{Field: "Accept-Language", Rule: "enum", Message: "must be one of en, de"},
+		{Field: "name", Rule: "required", Message: "is required"},
+	}, respErr.Detail)
+}
+
+func TestBind_InvalidBody(t *testing.T) {
+	ctx := newBindContext(`{"name": `, http.Header{}, nil)
+
+	err := fdhttp.Bind(ctx, &updatePerson{})
+	respErr, ok := err.(*fdhttp.Error)
+	if assert.True(t, ok) {
+		assert.Equal(t, "invalid_body", respErr.Code)
+	}
+}
+
+func TestBind_OnlyPointerToStruct(t *testing.T) {
+	ctx := newBindContext("", http.Header{}, nil)
+
+	err := fdhttp.Bind(ctx, updatePerson{})
+	assert.EqualError(t, err, "fdhttp: bind expects a non-nil pointer to struct")
+}
+
+func TestRouter_BindRequest(t *testing.T) {
+	var handlerCalled bool
+
+	r := fdhttp.NewRouter()
+	r.PUT("/v1/people/:id", func(ctx context.Context) (int, interface{}) {
+		handlerCalled = true
+
+		req := fdhttp.RequestBinding(ctx).(*updatePerson)
+		return http.StatusOK, req
+	}).Bind(updatePerson{})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	// valid request
+	req, _ := http.NewRequest(http.MethodPut, ts.URL+"/v1/people/10", bytes.NewBufferString(`{"name": "John"}`))
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var v updatePerson
+	json.NewDecoder(resp.Body).Decode(&v)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, handlerCalled)
+	assert.Equal(t, int64(10), v.ID)
+	assert.Equal(t, "John", v.Name)
+
+	// invalid request
+	handlerCalled = false
+
+	req, _ = http.NewRequest(http.MethodPut, ts.URL+"/v1/people/10?"+url.Values{"fields": {"name"}}.Encode(), bytes.NewBufferString(`{"name": "John Doe Junior"}`))
+	resp, err = http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var respErr struct {
+		Code   string              `json:"code"`
+		Detail []fdhttp.FieldError `json:"detail"`
+	}
+	json.NewDecoder(resp.Body).Decode(&respErr)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.False(t, handlerCalled)
+	assert.Equal(t, fdhttp.ValidationFailedCode, respErr.Code)
+	assert.Equal(t, []fdhttp.FieldError{
+		{Field: "name", Rule: "max", Message: "length must be at most 10"},
+	}, respErr.Detail)
+}
+
+func TestEndpoint_BindOnlyStructs(t *testing.T) {
+	r := fdhttp.NewRouter()
+
+	assert.Panics(t, func() {
+		r.GET("/", func(ctx context.Context) (int, interface{}) {
+			return http.StatusOK, nil
+		}).Bind("string")
+	})
+}
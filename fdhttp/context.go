@@ -36,6 +36,9 @@ var (
 	// RequestPostFormContextKey is the key used to save the post data. Check RequestFormContextKey.
 	RequestPostFormContextKey = &contextKey{"request-post-form"}
 
+	// RequestBindingContextKey is the key used to save the value decoded by Endpoint.Bind().
+	RequestBindingContextKey = &contextKey{"request-binding"}
+
 	// ResponseContextKey is the key used to save original response.
 	ResponseContextKey = &contextKey{"response"}
 
@@ -43,6 +46,16 @@ var (
 	// and it'll be sent to clients before request ends.
 	ResponseHeaderContextKey = &contextKey{"response-header"}
 
+	// PeerIdentityContextKey is the key used to save the client identity verified using mTLS.
+	PeerIdentityContextKey = &contextKey{"peer-identity"}
+
+	// EndpointContextKey is the key used to save the endpoint that matched the request.
+	EndpointContextKey = &contextKey{"endpoint"}
+
+	// ServerShutdownContextKey is the key used to save the channel closed when
+	// Server.Stop() is called.
+	ServerShutdownContextKey = &contextKey{"server-shutdown"}
+
 	// ResponseErrorContextKey is the key used to save the response error.
 	// We save this information to sent to log middleware.
 	ResponseErrorContextKey = &contextKey{"response-error"}
@@ -153,6 +166,74 @@ func SetRequestPostForm(ctx context.Context, value url.Values) context.Context {
 	return context.WithValue(ctx, RequestPostFormContextKey, value)
 }
 
+// RequestBinding get the value decoded using Endpoint.Bind() from context.
+func RequestBinding(ctx context.Context) interface{} {
+	return ctx.Value(RequestBindingContextKey)
+}
+
+// SetRequestBinding set the value decoded from the request into context.
+func SetRequestBinding(ctx context.Context, v interface{}) context.Context {
+	return context.WithValue(ctx, RequestBindingContextKey, v)
+}
+
+// Peer get the identity of the client verified using mTLS from context,
+// it's nil when client didn't send a valid certificate.
+func Peer(ctx context.Context) *PeerIdentity {
+	v, _ := ctx.Value(PeerIdentityContextKey).(*PeerIdentity)
+	return v
+}
+
+// SetPeer set the identity of the client into context.
+func SetPeer(ctx context.Context, peer *PeerIdentity) context.Context {
+	return context.WithValue(ctx, PeerIdentityContextKey, peer)
+}
+
+// endpointMatch is saved in the context before routing, that way middlewares
+// of the main router can access the endpoint after the route matched.
+type endpointMatch struct {
+	endpoint *Endpoint
+	hooks    []func(*Endpoint)
+}
+
+// MatchedEndpoint get the endpoint that matched the request from context, it's nil
+// when no route matched. Middlewares of the main router are called before routing,
+// so there it's only available after calling the next handler.
+func MatchedEndpoint(ctx context.Context) *Endpoint {
+	m, _ := ctx.Value(EndpointContextKey).(*endpointMatch)
+	if m == nil {
+		return nil
+	}
+	return m.endpoint
+}
+
+// OnEndpointMatch register fn to be called when a route matches the request, before
+// the endpoint handler. Useful for middlewares that need the endpoint earlier.
+func OnEndpointMatch(ctx context.Context, fn func(*Endpoint)) {
+	m, _ := ctx.Value(EndpointContextKey).(*endpointMatch)
+	if m == nil {
+		return
+	}
+	if m.endpoint != nil {
+		fn(m.endpoint)
+		return
+	}
+	m.hooks = append(m.hooks, fn)
+}
+
+// setMatchedEndpoint save the endpoint and call hooks registered by OnEndpointMatch.
+func setMatchedEndpoint(ctx context.Context, e *Endpoint) {
+	m, _ := ctx.Value(EndpointContextKey).(*endpointMatch)
+	if m == nil || m.endpoint != nil {
+		return
+	}
+
+	m.endpoint = e
+	for _, fn := range m.hooks {
+		fn(e)
+	}
+	m.hooks = nil
+}
+
 // Response set http response from context.
 func Response(ctx context.Context) http.ResponseWriter {
 	v, _ := ctx.Value(ResponseContextKey).(http.ResponseWriter)
@@ -197,3 +278,17 @@ func AddResponseHeaderValue(ctx context.Context, key, value string) {
 	header := ResponseHeader(ctx)
 	header.Add(key, value)
 }
+
+// ServerShutdown get a channel that is closed when Server.Stop() is called. Long-lived
+// requests (like event streams) should finish when it happens, otherwise server will
+// wait for them until the context passed to Stop() is done.
+// It returns nil (a channel that is never closed) when the request is not handled by fdhttp.Server.
+func ServerShutdown(ctx context.Context) <-chan struct{} {
+	v, _ := ctx.Value(ServerShutdownContextKey).(<-chan struct{})
+	return v
+}
+
+// SetServerShutdown set into context the channel closed when server stops.
+func SetServerShutdown(ctx context.Context, shutdown <-chan struct{}) context.Context {
+	return context.WithValue(ctx, ServerShutdownContextKey, shutdown)
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdhttp/fdmiddleware"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -216,3 +217,43 @@ func TestAddResponseHeaderValue(t *testing.T) {
 	assert.Equal(t, "2", header["X-Personal"][1])
 	assert.Equal(t, "3", header["X-Personal"][2])
 }
+
+func TestMatchedEndpoint(t *testing.T) {
+	var (
+		matched   string
+		afterNext string
+	)
+
+	router := fdhttp.NewRouter()
+	router.Use(fdmiddleware.MiddlewareFunc(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			assert.Nil(t, fdhttp.MatchedEndpoint(req.Context()))
+			fdhttp.OnEndpointMatch(req.Context(), func(e *fdhttp.Endpoint) {
+				matched = e.Name
+			})
+
+			// a copy of the request must not hide the endpoint
+			next.ServeHTTP(w, req.WithContext(req.Context()))
+
+			if e := fdhttp.MatchedEndpoint(req.Context()); e != nil {
+				afterNext = e.Name
+			}
+		})
+	}))
+	router.GET("/people/:id", func(ctx context.Context) (int, interface{}) {
+		assert.Equal(t, "get_person", matched)
+		assert.Equal(t, "/people/:id", fdhttp.MatchedEndpoint(ctx).Path)
+		return http.StatusOK, nil
+	}).SetName("get_person")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/people/1", nil))
+	assert.Equal(t, "get_person", afterNext)
+
+	matched, afterNext = "", ""
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/not-found", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, matched)
+	assert.Empty(t, afterNext)
+}
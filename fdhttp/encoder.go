@@ -0,0 +1,285 @@
+package fdhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Media types supported by the encoders implemented in this package.
+const (
+	MediaTypeJSON    = "application/json"
+	MediaTypeXML     = "application/xml"
+	MediaTypeMsgPack = "application/msgpack"
+	MediaTypeCSV     = "text/csv"
+)
+
+// Encoder write a response in a specific format.
+type Encoder interface {
+	// ContentType is sent to clients as Content-Type header.
+	ContentType() string
+	// Encode write v into w.
+	Encode(w io.Writer, v interface{}) error
+}
+
+// Encoders is a registry of encoders by media type, it's used by Router.Handler
+// to choose how a response is sent to clients based on Accept header.
+type Encoders struct {
+	mu         sync.RWMutex
+	mediaTypes []string
+	encoders   map[string]Encoder
+}
+
+// DefaultEncoders is used by fdhttp.Router when Router.Encoders is nil,
+// by default only JSON is registered.
+var DefaultEncoders = NewEncoders()
+
+// NewEncoders create a registry with JSON encoder, that is also the default format
+// when clients doesn't send Accept header.
+func NewEncoders() *Encoders {
+	e := &Encoders{
+		encoders: make(map[string]Encoder),
+	}
+	e.Register(MediaTypeJSON, JSONEncoder{})
+
+	return e
+}
+
+// Register an encoder to a media type, like "application/xml". If the media type
+// was already registered the encoder is replaced.
+func (e *Encoders) Register(mediaType string, enc Encoder) {
+	mediaType = strings.ToLower(mediaType)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.encoders[mediaType]; !ok {
+		e.mediaTypes = append(e.mediaTypes, mediaType)
+	}
+	e.encoders[mediaType] = enc
+}
+
+// Len return the number of encoders registered.
+func (e *Encoders) Len() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.mediaTypes)
+}
+
+// Default return the first encoder registered.
+func (e *Encoders) Default() Encoder {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if len(e.mediaTypes) == 0 {
+		return JSONEncoder{}
+	}
+	return e.encoders[e.mediaTypes[0]]
+}
+
+// Negotiate choose the best encoder based on the Accept header. Empty header
+// accepts any format and return the default encoder. In case nothing match
+// it returns false.
+func (e *Encoders) Negotiate(accept string) (Encoder, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return e.Default(), true
+	}
+
+	ranges := parseAccept(accept)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var (
+		best         Encoder
+		bestQ        float64
+		bestSpecific = -1
+	)
+
+	for _, mediaType := range e.mediaTypes {
+		r, ok := matchAccept(ranges, mediaType)
+		if !ok || r.q <= 0 {
+			continue
+		}
+
+		// in case of tie, more specific ranges and encoders registered first win
+		if r.q > bestQ || (r.q == bestQ && r.specificity() > bestSpecific) {
+			best, bestQ, bestSpecific = e.encoders[mediaType], r.q, r.specificity()
+		}
+	}
+
+	return best, best != nil
+}
+
+// Response send resp to clients using the format that they accept.
+// If clients doesn't accept any format registered they receive
+// 406 Not Acceptable using default encoder.
+func (e *Encoders) Response(w http.ResponseWriter, req *http.Request, statusCode int, resp interface{}) {
+	enc, ok := e.Negotiate(req.Header.Get("Accept"))
+	if !ok {
+		statusCode, resp = http.StatusNotAcceptable, e.notAcceptableError()
+		enc = e.Default()
+	}
+
+	ResponseEncoded(w, enc, statusCode, resp)
+}
+
+func (e *Encoders) notAcceptableError() *Error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return &Error{
+		Code:    "not_acceptable",
+		Message: fmt.Sprintf("Accept header must include one of: %s", strings.Join(e.mediaTypes, ", ")),
+	}
+}
+
+// ResponseEncoded respond using the encoder informed. If resp implements
+// fdhttp.JSONer the result of JSON() is encoded, that way all formats
+// send the same content.
+//
+// The response is encoded before sending the status code, if it fails
+// clients receive 500 with an fdhttp.Error instead.
+func ResponseEncoded(w http.ResponseWriter, enc Encoder, statusCode int, resp interface{}) {
+	w.Header().Set("Content-Type", enc.ContentType())
+
+	if resp == nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	if j, ok := resp.(JSONer); ok {
+		resp = j.JSON()
+	}
+
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, resp); err != nil {
+		defaultLogger.Printf("Unable to encode response: %v", err)
+
+		buf.Reset()
+		statusCode = http.StatusInternalServerError
+		err = enc.Encode(&buf, &Error{
+			Code:    "encode_error",
+			Message: "Unable to encode response",
+		})
+		if err != nil {
+			buf.Reset()
+		}
+	}
+
+	w.WriteHeader(statusCode)
+	if _, err := buf.WriteTo(w); err != nil {
+		defaultLogger.Printf("Unable to send response to client: %v", err)
+	}
+}
+
+// acceptRange is a media range parsed from Accept header.
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+func (r acceptRange) specificity() int {
+	switch {
+	case r.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(r.mediaType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+func (r acceptRange) match(mediaType string) bool {
+	switch {
+	case r.mediaType == "*/*":
+		return true
+	case strings.HasSuffix(r.mediaType, "/*"):
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(r.mediaType, "*"))
+	}
+	return r.mediaType == mediaType
+}
+
+// parseAccept return all media ranges from Accept header.
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		ranges = append(ranges, acceptRange{
+			mediaType: mediaType,
+			q:         q,
+		})
+	}
+
+	return ranges
+}
+
+// matchAccept return the most specific range that match the media type,
+// its quality is the quality of the media type. A quality of 0 means
+// "not acceptable".
+func matchAccept(ranges []acceptRange, mediaType string) (acceptRange, bool) {
+	var (
+		match acceptRange
+		found bool
+	)
+
+	for _, r := range ranges {
+		if !r.match(mediaType) {
+			continue
+		}
+		if !found || r.specificity() > match.specificity() {
+			match, found = r, true
+		}
+	}
+
+	return match, found
+}
+
+// JSONEncoder send responses as JSON.
+type JSONEncoder struct{}
+
+// ContentType implements fdhttp.Encoder.
+func (JSONEncoder) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+// Encode implements fdhttp.Encoder.
+func (JSONEncoder) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// XMLEncoder send responses as XML using encoding/xml, so you can use xml tags
+// to control how your structs are encoded. Note that encoding/xml doesn't support maps.
+type XMLEncoder struct{}
+
+// ContentType implements fdhttp.Encoder.
+func (XMLEncoder) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+// Encode implements fdhttp.Encoder.
+func (XMLEncoder) Encode(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,233 @@
+package fdhttp
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// CSVEncoder send responses as CSV, useful for exports. A slice of structs or
+// maps become one line per item and the first line has the column names,
+// taken from json tags. A single struct or map is sent as only one line.
+// Nested values are encoded as JSON inside of the cell.
+type CSVEncoder struct {
+	// Comma is the field delimiter, by default ','.
+	Comma rune
+}
+
+// ContentType implements fdhttp.Encoder.
+func (CSVEncoder) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+// Encode implements fdhttp.Encoder.
+func (e CSVEncoder) Encode(w io.Writer, v interface{}) error {
+	cw := csv.NewWriter(w)
+	if e.Comma != 0 {
+		cw.Comma = e.Comma
+	}
+
+	records, err := csvRecords(v)
+	if err != nil {
+		return err
+	}
+
+	if err := cw.WriteAll(records); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func csvRecords(v interface{}) ([][]string, error) {
+	if records, ok := v.([][]string); ok {
+		return records, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+
+	var rows []reflect.Value
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < rv.Len(); i++ {
+			rows = append(rows, indirect(rv.Index(i)))
+		}
+	} else {
+		rows = append(rows, rv)
+	}
+
+	columns := csvColumns(rows)
+
+	records := make([][]string, 0, len(rows)+1)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	records = append(records, header)
+
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, c := range columns {
+			cell, err := csvCell(c.value(row))
+			if err != nil {
+				return nil, err
+			}
+			record[i] = cell
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+type csvColumn struct {
+	name  string
+	value func(row reflect.Value) reflect.Value
+}
+
+// csvColumns find the columns based on the first row for structs
+// or all keys for maps.
+func csvColumns(rows []reflect.Value) []csvColumn {
+	var first reflect.Value
+	for _, row := range rows {
+		if row.IsValid() {
+			first = row
+			break
+		}
+	}
+
+	switch first.Kind() {
+	case reflect.Struct:
+		return csvStructColumns(first.Type(), nil)
+	case reflect.Map:
+		// keep the original key of each column, keys aren't always strings
+		keys := map[string]reflect.Value{}
+		for _, row := range rows {
+			if row.Kind() != reflect.Map {
+				continue
+			}
+			for _, k := range row.MapKeys() {
+				name := fmt.Sprint(k.Interface())
+				if _, ok := keys[name]; !ok {
+					keys[name] = k
+				}
+			}
+		}
+
+		names := make([]string, 0, len(keys))
+		for k := range keys {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		columns := make([]csvColumn, len(names))
+		for i, name := range names {
+			name, key := name, keys[name]
+			columns[i] = csvColumn{
+				name: name,
+				value: func(row reflect.Value) reflect.Value {
+					if row.Kind() != reflect.Map {
+						return reflect.Value{}
+					}
+					if key.Type() == row.Type().Key() {
+						return row.MapIndex(key)
+					}
+					// rows are maps with different key types
+					for _, k := range row.MapKeys() {
+						if fmt.Sprint(k.Interface()) == name {
+							return row.MapIndex(k)
+						}
+					}
+					return reflect.Value{}
+				},
+			}
+		}
+		return columns
+	}
+
+	return []csvColumn{{
+		name:  "value",
+		value: func(row reflect.Value) reflect.Value { return row },
+	}}
+}
+
+func csvStructColumns(t reflect.Type, index []int) []csvColumn {
+	var columns []csvColumn
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, _ := splitTag(f.Tag.Get("json"))
+		if name == "-" {
+			continue
+		}
+
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if f.Anonymous && f.PkgPath == "" && name == "" && f.Type.Kind() == reflect.Struct {
+			columns = append(columns, csvStructColumns(f.Type, fieldIndex)...)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		columns = append(columns, csvColumn{
+			name: name,
+			value: func(row reflect.Value) reflect.Value {
+				if row.Kind() != reflect.Struct {
+					return reflect.Value{}
+				}
+				return row.FieldByIndex(fieldIndex)
+			},
+		})
+	}
+
+	return columns
+}
+
+// csvCell encode the value as JSON, but strings are sent without quotes
+// and null values as empty cells.
+func csvCell(v reflect.Value) (string, error) {
+	if !v.IsValid() {
+		return "", nil
+	}
+
+	buf, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+
+	var s string
+	if json.Unmarshal(buf, &s) == nil {
+		return s, nil
+	}
+
+	if string(buf) == "null" {
+		return "", nil
+	}
+
+	return string(buf), nil
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
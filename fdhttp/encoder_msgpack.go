@@ -0,0 +1,164 @@
+package fdhttp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// MsgPackEncoder send responses as MessagePack (https://msgpack.org).
+// Values are converted to JSON first, that way json tags and json.Marshaler
+// are respected and clients receive exactly the same content of JSON responses.
+type MsgPackEncoder struct{}
+
+// ContentType implements fdhttp.Encoder.
+func (MsgPackEncoder) ContentType() string {
+	return MediaTypeMsgPack
+}
+
+// Encode implements fdhttp.Encoder.
+func (MsgPackEncoder) Encode(w io.Writer, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := writeMsgPack(bw, tree); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func writeMsgPack(w *bufio.Writer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		return w.WriteByte(0xc0)
+	case bool:
+		if v {
+			return w.WriteByte(0xc3)
+		}
+		return w.WriteByte(0xc2)
+	case json.Number:
+		return writeMsgPackNumber(w, v)
+	case string:
+		writeMsgPackHeader(w, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		_, err := w.WriteString(v)
+		return err
+	case []interface{}:
+		writeMsgPackHeader(w, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := writeMsgPack(w, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		writeMsgPackHeader(w, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, k := range keys {
+			if err := writeMsgPack(w, k); err != nil {
+				return err
+			}
+			if err := writeMsgPack(w, v[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("fdhttp: msgpack: unsupported type %T", v)
+}
+
+// writeMsgPackHeader write the type and length of strings, arrays and maps.
+// fixMax is the limit to use the fix format and code8 is zero when the
+// type doesn't have 8 bits format.
+func writeMsgPackHeader(w *bufio.Writer, n int, fix byte, fixMax int, code8, code16, code32 byte) {
+	switch {
+	case n < fixMax:
+		w.WriteByte(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		w.WriteByte(code8)
+		w.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		w.WriteByte(code16)
+		binary.Write(w, binary.BigEndian, uint16(n))
+	default:
+		w.WriteByte(code32)
+		binary.Write(w, binary.BigEndian, uint32(n))
+	}
+}
+
+func writeMsgPackNumber(w *bufio.Writer, n json.Number) error {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		writeMsgPackInt(w, i)
+		return nil
+	}
+
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		w.WriteByte(0xcf)
+		return binary.Write(w, binary.BigEndian, u)
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return err
+	}
+
+	w.WriteByte(0xcb)
+	return binary.Write(w, binary.BigEndian, math.Float64bits(f))
+}
+
+func writeMsgPackInt(w *bufio.Writer, i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		// positive fixint
+		w.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		// negative fixint
+		w.WriteByte(byte(int8(i)))
+	case i >= 0 && i <= math.MaxUint8:
+		w.WriteByte(0xcc)
+		w.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		w.WriteByte(0xcd)
+		binary.Write(w, binary.BigEndian, uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		w.WriteByte(0xce)
+		binary.Write(w, binary.BigEndian, uint32(i))
+	case i >= 0:
+		w.WriteByte(0xcf)
+		binary.Write(w, binary.BigEndian, uint64(i))
+	case i >= math.MinInt8:
+		w.WriteByte(0xd0)
+		w.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16:
+		w.WriteByte(0xd1)
+		binary.Write(w, binary.BigEndian, int16(i))
+	case i >= math.MinInt32:
+		w.WriteByte(0xd2)
+		binary.Write(w, binary.BigEndian, int32(i))
+	default:
+		w.WriteByte(0xd3)
+		binary.Write(w, binary.BigEndian, i)
+	}
+}
@@ -0,0 +1,204 @@
+package fdhttp_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+type encodedPerson struct {
+	Name string   `json:"name" xml:"name"`
+	Age  int      `json:"age" xml:"age"`
+	Tags []string `json:"tags,omitempty" xml:"tag"`
+}
+
+func newEncoders() *fdhttp.Encoders {
+	encoders := fdhttp.NewEncoders()
+	encoders.Register(fdhttp.MediaTypeXML, fdhttp.XMLEncoder{})
+	encoders.Register(fdhttp.MediaTypeMsgPack, fdhttp.MsgPackEncoder{})
+	encoders.Register(fdhttp.MediaTypeCSV, fdhttp.CSVEncoder{})
+	return encoders
+}
+
+func TestEncoders_Negotiate(t *testing.T) {
+	encoders := newEncoders()
+
+	tests := []struct {
+		accept      string
+		contentType string
+	}{
+		{"", "application/json; charset=utf-8"},
+		{"*/*", "application/json; charset=utf-8"},
+		{"application/xml", "application/xml; charset=utf-8"},
+		{"text/*", "text/csv; charset=utf-8"},
+		{"text/html, application/msgpack;q=0.9, */*;q=0.1", "application/msgpack"},
+		{"application/json;q=0.5, application/xml;q=0.8", "application/xml; charset=utf-8"},
+		{"application/*;q=0.5, application/xml;q=0.5", "application/xml; charset=utf-8"},
+		{"application/json;q=0, */*", "application/xml; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		enc, ok := encoders.Negotiate(tt.accept)
+		if assert.True(t, ok, tt.accept) {
+			assert.Equal(t, tt.contentType, enc.ContentType(), tt.accept)
+		}
+	}
+
+	_, ok := encoders.Negotiate("text/html, image/*")
+	assert.False(t, ok)
+
+	_, ok = encoders.Negotiate("application/json;q=0")
+	assert.False(t, ok)
+}
+
+func TestMsgPackEncoder(t *testing.T) {
+	var b bytes.Buffer
+	err := fdhttp.MsgPackEncoder{}.Encode(&b, map[string]interface{}{
+		"a": 1,
+		"b": []interface{}{true, nil, -1, 300, "x"},
+		"c": 1.5,
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []byte{
+		0x83,
+		0xa1, 'a', 0x01,
+		0xa1, 'b', 0x95, 0xc3, 0xc0, 0xff, 0xcd, 0x01, 0x2c, 0xa1, 'x',
+		0xa1, 'c', 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+	}, b.Bytes())
+}
+
+func TestCSVEncoder(t *testing.T) {
+	var b bytes.Buffer
+	err := fdhttp.CSVEncoder{}.Encode(&b, []*encodedPerson{
+		{Name: "John", Age: 30, Tags: []string{"a", "b"}},
+		{Name: "Mary, Jane", Age: 25},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "name,age,tags\nJohn,30,\"[\"\"a\"\",\"\"b\"\"]\"\n\"Mary, Jane\",25,\n", b.String())
+}
+
+func TestCSVEncoder_MapKeys(t *testing.T) {
+	var b bytes.Buffer
+	err := fdhttp.CSVEncoder{}.Encode(&b, []map[int]string{
+		{1: "a", 2: "b"},
+		{2: "c", 10: "d"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1,10,2\na,,b\n,d,c\n", b.String())
+
+	b.Reset()
+	err = fdhttp.CSVEncoder{}.Encode(&b, []interface{}{
+		map[interface{}]interface{}{"name": "John", 1: true},
+		map[string]int{"name": 1},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1,name\ntrue,John\n,1\n", b.String())
+}
+
+func newEncodersRouter() *fdhttp.Router {
+	r := fdhttp.NewRouter()
+	r.Encoders = newEncoders()
+	r.GET("/person", func(ctx context.Context) (int, interface{}) {
+		return http.StatusOK, &encodedPerson{Name: "John", Age: 30}
+	})
+	r.GET("/error", func(ctx context.Context) (int, interface{}) {
+		return http.StatusNotFound, &fdhttp.Error{Code: "not_found", Message: "person not found"}
+	})
+
+	return r
+}
+
+func requestWithAccept(t *testing.T, r http.Handler, path, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRouter_ContentNegotiation(t *testing.T) {
+	r := newEncodersRouter()
+
+	w := requestWithAccept(t, r, "/person", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	assert.Equal(t, `{"name":"John","age":30}`+"\n", w.Body.String())
+
+	w = requestWithAccept(t, r, "/person", "application/xml")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<encodedPerson><name>John</name><age>30</age></encodedPerson>`, w.Body.String())
+
+	w = requestWithAccept(t, r, "/person", "text/csv")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "name,age,tags\nJohn,30,\n", w.Body.String())
+
+	w = requestWithAccept(t, r, "/person", "application/msgpack")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/msgpack", w.Header().Get("Content-Type"))
+	assert.Equal(t, []byte{0x82, 0xa3, 'a', 'g', 'e', 0x1e, 0xa4, 'n', 'a', 'm', 'e', 0xa4, 'J', 'o', 'h', 'n'}, w.Body.Bytes())
+}
+
+func TestRouter_ErrorIsEncodedInAllFormats(t *testing.T) {
+	r := newEncodersRouter()
+
+	w := requestWithAccept(t, r, "/error", "application/xml")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<error><code>not_found</code><message>person not found</message></error>`, w.Body.String())
+
+	w = requestWithAccept(t, r, "/error", "text/csv")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "code,message,detail\nnot_found,person not found,\n", w.Body.String())
+}
+
+func TestRouter_EncodeErrorResponds500(t *testing.T) {
+	r := newEncodersRouter()
+	r.GET("/invalid", func(ctx context.Context) (int, interface{}) {
+		return http.StatusCreated, map[string]interface{}{"callback": func() {}}
+	})
+	r.GET("/invalid-detail", func(ctx context.Context) (int, interface{}) {
+		return http.StatusBadRequest, &fdhttp.Error{
+			Code:    "invalid_body",
+			Message: "body is invalid",
+			Detail:  map[string]string{"name": "required"},
+		}
+	})
+
+	w := requestWithAccept(t, r, "/invalid", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, `{"code":"encode_error","message":"Unable to encode response"}`+"\n", w.Body.String())
+
+	// maps can't be encoded as xml
+	w = requestWithAccept(t, r, "/invalid-detail", "application/xml")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<error><code>encode_error</code><message>Unable to encode response</message></error>`, w.Body.String())
+}
+
+func TestRouter_NotAcceptable(t *testing.T) {
+	var handlerCalled bool
+
+	r := fdhttp.NewRouter()
+	r.GET("/", func(ctx context.Context) (int, interface{}) {
+		handlerCalled = true
+		return http.StatusOK, nil
+	})
+
+	w := requestWithAccept(t, r, "/", "text/html")
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.False(t, handlerCalled)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	body, _ := ioutil.ReadAll(w.Body)
+	assert.Equal(t, `{"code":"not_acceptable","message":"Accept header must include one of: application/json"}`+"\n", string(body))
+}
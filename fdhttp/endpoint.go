@@ -2,6 +2,7 @@ package fdhttp
 
 import (
 	"fmt"
+	"reflect"
 )
 
 // Endpoint is returned when you create a router,
@@ -9,10 +10,26 @@ import (
 // endpoint. After that you can use this name to generate
 // URL. Check Router.Path() and Router.PathParam()
 type Endpoint struct {
-	router *Router
-	Name   string
-	Method string
-	Path   string
+	router  *Router
+	doc     *EndpointDoc
+	binding reflect.Type
+	Name    string
+	Method  string
+	Path    string
+}
+
+// EndpointDoc keeps the documentation attached to an endpoint. It's used
+// to generate API specifications (check fdopenapi package) and it doesn't
+// change how requests are handled.
+type EndpointDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	// Request is a value of the type expected as request body.
+	Request interface{}
+	// Responses map status code to a value of the type sent as response body.
+	Responses map[int]interface{}
 }
 
 // SetName give a better name to the endpoint, otherwise
@@ -22,6 +39,99 @@ func (e *Endpoint) SetName(name string) {
 	e.router.addEndpoint(e)
 }
 
+// Doc return the documentation attached to this endpoint.
+func (e Endpoint) Doc() EndpointDoc {
+	if e.doc == nil {
+		return EndpointDoc{}
+	}
+	return *e.doc
+}
+
+func (e *Endpoint) document() *EndpointDoc {
+	if e.doc == nil {
+		e.doc = &EndpointDoc{}
+	}
+	return e.doc
+}
+
+// SetSummary give a short summary of what the endpoint does.
+func (e *Endpoint) SetSummary(summary string) *Endpoint {
+	e.document().Summary = summary
+	return e
+}
+
+// SetDescription give a verbose explanation of the endpoint behaviour.
+func (e *Endpoint) SetDescription(description string) *Endpoint {
+	e.document().Description = description
+	return e
+}
+
+// SetTags group the endpoint with others that share the same tags.
+func (e *Endpoint) SetTags(tags ...string) *Endpoint {
+	e.document().Tags = tags
+	return e
+}
+
+// SetDeprecated mark the endpoint as deprecated.
+func (e *Endpoint) SetDeprecated() *Endpoint {
+	e.document().Deprecated = true
+	return e
+}
+
+// SetRequestSchema receive a value of the type that clients need to send
+// as request body, like:
+//  router.POST("/v1/people", h.Create).SetRequestSchema(Person{})
+func (e *Endpoint) SetRequestSchema(v interface{}) *Endpoint {
+	e.document().Request = v
+	return e
+}
+
+// SetResponseSchema receive a value of the type sent to clients when the
+// endpoint respond with statusCode, like:
+//  router.GET("/v1/people/:id", h.Get).
+//      SetResponseSchema(http.StatusOK, Person{}).
+//      SetResponseSchema(http.StatusNotFound, &fdhttp.Error{})
+func (e *Endpoint) SetResponseSchema(statusCode int, v interface{}) *Endpoint {
+	doc := e.document()
+	if doc.Responses == nil {
+		doc.Responses = make(map[int]interface{})
+	}
+	doc.Responses[statusCode] = v
+	return e
+}
+
+// Bind make the router decode each request into a new value with the same
+// type of v before call the handler, check fdhttp.Bind() to know how values
+// are decoded. Invalid requests are answered with status 400 and handler is
+// not called. The value can be accessed using fdhttp.RequestBinding():
+//
+//  router.PUT("/v1/people/:id", func(ctx context.Context) (int, interface{}) {
+//      req := fdhttp.RequestBinding(ctx).(*UpdatePerson)
+//      ...
+//  }).Bind(UpdatePerson{})
+//
+// It works only with endpoints registered with Router.Handler and v is
+// also used as documentation of the request, check Endpoint.SetRequestSchema().
+func (e *Endpoint) Bind(v interface{}) *Endpoint {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("fdhttp: endpoint %s %s can only bind structs", e.Method, e.Path))
+	}
+
+	// parse tags now to panic during startup in case of invalid rules
+	structFields(t)
+
+	e.binding = t
+	if e.document().Request == nil {
+		e.doc.Request = v
+	}
+
+	return e
+}
+
 // addEndpoint save endpoint to the list of available endpoints.
 // The name generate will be something like this:
 // 		GET /v:version/people/:id/metadata
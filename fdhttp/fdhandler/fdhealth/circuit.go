@@ -0,0 +1,34 @@
+package fdhealth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/foodora/go-ranger/fdhttp/fdhandler"
+	"github.com/foodora/go-ranger/fdhttp/fdmiddleware"
+)
+
+// CircuitStats is the detail returned by the circuit breaker health check.
+type CircuitStats struct {
+	Open      bool    `json:"open"`
+	ErrorRate float64 `json:"error_rate"`
+	Failures  int64   `json:"failures"`
+}
+
+// Circuit fail while the circuit breaker is open. Usually it's registered as
+// fdhandler.DegradedOnly or fdhandler.Informational, the circuit is open
+// because a dependency is failing, not the service itself.
+func Circuit(c *fdmiddleware.Circuit) fdhandler.HealthChecker {
+	return fdhandler.HealthCheckerFunc(func(ctx context.Context) (interface{}, error) {
+		stats := &CircuitStats{
+			Open:      c.Tripped(),
+			ErrorRate: c.ErrorRate(),
+			Failures:  c.Failures(),
+		}
+
+		if stats.Open {
+			return stats, errors.New("circuit is open")
+		}
+		return stats, nil
+	})
+}
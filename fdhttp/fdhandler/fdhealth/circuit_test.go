@@ -0,0 +1,34 @@
+package fdhealth_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/fdbackoff"
+	"github.com/foodora/go-ranger/fdhttp/fdhandler/fdhealth"
+	"github.com/foodora/go-ranger/fdhttp/fdmiddleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuit(t *testing.T) {
+	circuit := fdmiddleware.NewCircuitBreakerTransport(fdbackoff.Linear(time.Minute), 0.5, 2)
+	checker := fdhealth.Circuit(circuit)
+
+	detail, err := checker.HealthCheck(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &fdhealth.CircuitStats{}, detail)
+
+	transport := circuit.Wrap(fdmiddleware.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusInternalServerError}, nil
+	}))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost", nil)
+	transport.RoundTrip(req)
+	transport.RoundTrip(req)
+
+	detail, err = checker.HealthCheck(context.Background())
+	assert.EqualError(t, err, "circuit is open")
+	assert.Equal(t, &fdhealth.CircuitStats{Open: true, ErrorRate: 1, Failures: 2}, detail)
+}
@@ -0,0 +1,44 @@
+package fdhealth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/foodora/go-ranger/fdhttp/fdhandler"
+)
+
+// DiskStats is the detail returned by the disk health check.
+type DiskStats struct {
+	Path        string  `json:"path"`
+	TotalBytes  uint64  `json:"total_bytes"`
+	FreeBytes   uint64  `json:"free_bytes"`
+	FreePercent float64 `json:"free_percent"`
+}
+
+// Disk fail when the file system of path has less than minFreeBytes or
+// minFreePercent (0-100) available to the service, 0 disable the threshold.
+func Disk(path string, minFreeBytes uint64, minFreePercent float64) fdhandler.HealthChecker {
+	return fdhandler.HealthCheckerFunc(func(ctx context.Context) (interface{}, error) {
+		stats := &DiskStats{Path: path}
+
+		total, free, err := diskUsage(path)
+		if err != nil {
+			return stats, err
+		}
+
+		stats.TotalBytes = total
+		stats.FreeBytes = free
+		if total > 0 {
+			stats.FreePercent = float64(free) / float64(total) * 100
+		}
+
+		if free < minFreeBytes {
+			return stats, fmt.Errorf("%d bytes free, expected at least %d", free, minFreeBytes)
+		}
+		if stats.FreePercent < minFreePercent {
+			return stats, fmt.Errorf("%.2f%% free, expected at least %.2f%%", stats.FreePercent, minFreePercent)
+		}
+
+		return stats, nil
+	})
+}
@@ -0,0 +1,13 @@
+// +build !linux,!darwin,!freebsd
+
+package fdhealth
+
+import (
+	"errors"
+	"runtime"
+)
+
+// diskUsage is not implemented in this platform.
+func diskUsage(path string) (total, free uint64, err error) {
+	return 0, 0, errors.New("disk usage is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,29 @@
+package fdhealth_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdhttp/fdhandler/fdhealth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisk(t *testing.T) {
+	detail, err := fdhealth.Disk(".", 0, 0).HealthCheck(context.Background())
+	assert.NoError(t, err)
+
+	stats := detail.(*fdhealth.DiskStats)
+	assert.Equal(t, ".", stats.Path)
+	assert.True(t, stats.TotalBytes > 0)
+	assert.True(t, stats.FreeBytes <= stats.TotalBytes)
+
+	_, err = fdhealth.Disk(".", stats.TotalBytes+1, 0).HealthCheck(context.Background())
+	assert.Error(t, err)
+
+	_, err = fdhealth.Disk(".", 0, 100.1).HealthCheck(context.Background())
+	assert.Error(t, err)
+
+	detail, err = fdhealth.Disk("/does/not/exist", 0, 0).HealthCheck(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, &fdhealth.DiskStats{Path: "/does/not/exist"}, detail)
+}
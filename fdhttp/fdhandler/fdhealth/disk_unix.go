@@ -0,0 +1,16 @@
+// +build linux darwin freebsd
+
+package fdhealth
+
+import "syscall"
+
+// diskUsage return the size of the file system of path and the bytes
+// available to unprivileged users.
+func diskUsage(path string) (total, free uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+
+	return uint64(st.Blocks) * uint64(st.Bsize), uint64(st.Bavail) * uint64(st.Bsize), nil
+}
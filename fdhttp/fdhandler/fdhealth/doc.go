@@ -0,0 +1,12 @@
+// Package fdhealth provide fdhandler.HealthChecker implementations for the
+// dependencies that go-ranger already opens, that way services don't need to
+// write them again:
+//
+//  h := fdhandler.NewHealthCheck(tag, commit)
+//  h.Register("mysql", fdhealth.SQL(db))
+//  h.Register("orders-queue", fdhealth.Subscriber(sub))
+//  h.Register("events-topic", fdhealth.Publisher(pub))
+//  h.RegisterWithCriticality("disk", fdhealth.Disk("/var/lib/orders", 1<<30, 10), fdhandler.DegradedOnly)
+//  h.RegisterWithCriticality("payment", fdhealth.HTTP(client, "http://payment/health/live", http.StatusOK), fdhandler.DegradedOnly)
+//  h.RegisterWithCriticality("payment-circuit", fdhealth.Circuit(circuit), fdhandler.Informational)
+package fdhealth
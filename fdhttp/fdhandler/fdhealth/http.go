@@ -0,0 +1,51 @@
+package fdhealth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdhttp/fdhandler"
+)
+
+// HTTPStats is the detail returned by the HTTP health check.
+type HTTPStats struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code,omitempty"`
+}
+
+// HTTP send a GET request to url and fail when the response status is not
+// expectedStatus. When expectedStatus is 0 any 2xx status is accepted.
+func HTTP(client fdhttp.Client, url string, expectedStatus int) fdhandler.HealthChecker {
+	return fdhandler.HealthCheckerFunc(func(ctx context.Context) (interface{}, error) {
+		stats := &HTTPStats{URL: url}
+
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return stats, err
+		}
+
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return stats, err
+		}
+		// drain body, that way the connection can be reused
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		stats.StatusCode = resp.StatusCode
+
+		if expectedStatus == 0 {
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return stats, fmt.Errorf("unexpected status %d", resp.StatusCode)
+			}
+		} else if resp.StatusCode != expectedStatus {
+			return stats, fmt.Errorf("unexpected status %d, expected %d", resp.StatusCode, expectedStatus)
+		}
+
+		return stats, nil
+	})
+}
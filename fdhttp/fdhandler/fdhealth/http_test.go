@@ -0,0 +1,44 @@
+package fdhealth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdhttp/fdhandler/fdhealth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client := fdhttp.NewClient()
+
+	detail, err := fdhealth.HTTP(client, ts.URL+"/up", 0).HealthCheck(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &fdhealth.HTTPStats{URL: ts.URL + "/up", StatusCode: http.StatusNoContent}, detail)
+
+	_, err = fdhealth.HTTP(client, ts.URL+"/up", http.StatusOK).HealthCheck(context.Background())
+	assert.EqualError(t, err, "unexpected status 204, expected 200")
+
+	_, err = fdhealth.HTTP(client, ts.URL+"/down", 0).HealthCheck(context.Background())
+	assert.EqualError(t, err, "unexpected status 503")
+}
+
+func TestHTTP_Unreachable(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+
+	detail, err := fdhealth.HTTP(fdhttp.NewClient(), ts.URL, 0).HealthCheck(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, &fdhealth.HTTPStats{URL: ts.URL}, detail)
+}
@@ -0,0 +1,29 @@
+package fdhealth
+
+import (
+	"github.com/foodora/go-ranger/fdhttp/fdhandler"
+	"github.com/foodora/go-ranger/pubsub"
+)
+
+// Subscriber check if the queue of the subscriber is reachable, like the one
+// returned by awssub.NewSubscriber. It panics when the subscriber doesn't
+// support health checks, that way it's noticed when the service starts.
+func Subscriber(sub pubsub.Subscriber) fdhandler.HealthChecker {
+	checker, ok := sub.(fdhandler.HealthChecker)
+	if !ok {
+		panic("fdhealth: subscriber doesn't support health check")
+	}
+	return checker
+}
+
+// Publisher check if the default topic or queue of the publisher is reachable,
+// like the ones returned by awspub.NewPublisher and awspub.NewSQSPublisher. It
+// panics when the publisher doesn't
+// support health checks.
+func Publisher(pub pubsub.Publisher) fdhandler.HealthChecker {
+	checker, ok := pub.(fdhandler.HealthChecker)
+	if !ok {
+		panic("fdhealth: publisher doesn't support health check")
+	}
+	return checker
+}
@@ -0,0 +1,42 @@
+package fdhealth_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdhttp/fdhandler/fdhealth"
+	"github.com/foodora/go-ranger/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSubscriber struct {
+	pubsub.Subscriber
+}
+
+type checkedSubscriber struct {
+	pubsub.Subscriber
+}
+
+func (s *checkedSubscriber) HealthCheck(context.Context) (interface{}, error) {
+	return "queue is reachable", nil
+}
+
+type testPublisher struct {
+	pubsub.Publisher
+}
+
+func TestSubscriber(t *testing.T) {
+	detail, err := fdhealth.Subscriber(&checkedSubscriber{}).HealthCheck(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "queue is reachable", detail)
+
+	assert.Panics(t, func() {
+		fdhealth.Subscriber(&testSubscriber{})
+	})
+}
+
+func TestPublisher_NotSupported(t *testing.T) {
+	assert.Panics(t, func() {
+		fdhealth.Publisher(&testPublisher{})
+	})
+}
@@ -0,0 +1,42 @@
+package fdhealth
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/foodora/go-ranger/fdhttp/fdhandler"
+)
+
+// SQLStats is the detail returned by the SQL health check.
+type SQLStats struct {
+	MaxOpenConnections int `json:"max_open_connections"`
+	OpenConnections    int `json:"open_connections"`
+	InUse              int `json:"in_use"`
+	Idle               int `json:"idle"`
+
+	WaitCount         int64         `json:"wait_count"`
+	WaitDuration      time.Duration `json:"wait_duration"`
+	MaxIdleClosed     int64         `json:"max_idle_closed"`
+	MaxLifetimeClosed int64         `json:"max_lifetime_closed"`
+}
+
+// SQL ping the database, like the one returned by fddb.OpenSQL, and return
+// the connection pool stats as detail.
+func SQL(db *sql.DB) fdhandler.HealthChecker {
+	return fdhandler.HealthCheckerFunc(func(ctx context.Context) (interface{}, error) {
+		err := db.PingContext(ctx)
+
+		stats := db.Stats()
+		return &SQLStats{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDuration:       stats.WaitDuration / time.Millisecond,
+			MaxIdleClosed:      stats.MaxIdleClosed,
+			MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+		}, err
+	})
+}
@@ -0,0 +1,64 @@
+package fdhealth_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdhttp/fdhandler/fdhealth"
+	"github.com/stretchr/testify/assert"
+)
+
+var errPing = errors.New("connection refused")
+
+// pingDriver open connections that only answer pings, failing when
+// the dsn is "down".
+type pingDriver struct{}
+
+func (pingDriver) Open(dsn string) (driver.Conn, error) {
+	return &pingConn{down: dsn == "down"}, nil
+}
+
+type pingConn struct {
+	down bool
+}
+
+func (c *pingConn) Ping(context.Context) error {
+	if c.down {
+		return errPing
+	}
+	return nil
+}
+
+func (c *pingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (c *pingConn) Close() error                        { return nil }
+func (c *pingConn) Begin() (driver.Tx, error)           { return nil, errors.New("not implemented") }
+
+func init() {
+	sql.Register("fdhealth-ping", pingDriver{})
+}
+
+func TestSQL(t *testing.T) {
+	db, _ := sql.Open("fdhealth-ping", "up")
+	defer db.Close()
+	db.SetMaxOpenConns(5)
+
+	detail, err := fdhealth.SQL(db).HealthCheck(context.Background())
+	assert.NoError(t, err)
+
+	stats := detail.(*fdhealth.SQLStats)
+	assert.Equal(t, 5, stats.MaxOpenConnections)
+	assert.Equal(t, 1, stats.OpenConnections)
+	assert.Equal(t, 1, stats.Idle)
+}
+
+func TestSQL_Down(t *testing.T) {
+	db, _ := sql.Open("fdhealth-ping", "down")
+	defer db.Close()
+
+	detail, err := fdhealth.SQL(db).HealthCheck(context.Background())
+	assert.Equal(t, errPing, err)
+	assert.IsType(t, &fdhealth.SQLStats{}, detail)
+}
@@ -2,6 +2,7 @@ package fdhandler
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"runtime"
@@ -18,13 +19,73 @@ type HealthChecker interface {
 	HealthCheck(context.Context) (interface{}, error)
 }
 
+// HealthCheckerFunc is a function that implements HealthChecker.
+type HealthCheckerFunc func(context.Context) (interface{}, error)
+
+// HealthCheck implements HealthChecker.
+func (fn HealthCheckerFunc) HealthCheck(ctx context.Context) (interface{}, error) {
+	return fn(ctx)
+}
+
+// Criticality define how a failing check affects the service status.
+type Criticality int
+
+const (
+	// Critical checks make the service down when they fail, it's the default.
+	Critical Criticality = iota
+	// DegradedOnly checks make the service degraded when they fail, but it's
+	// still ready to receive traffic.
+	DegradedOnly
+	// Informational checks are only reported, they never change the status.
+	Informational
+)
+
+var criticalityNames = map[Criticality]string{
+	Critical:      "critical",
+	DegradedOnly:  "degraded",
+	Informational: "informational",
+}
+
+func (c Criticality) String() string {
+	return criticalityNames[c]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Criticality) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Criticality) UnmarshalText(text []byte) error {
+	for k, v := range criticalityNames {
+		if v == string(text) {
+			*c = k
+			return nil
+		}
+	}
+	return fmt.Errorf("fdhandler: invalid criticality %s", text)
+}
+
+// HealthState is the overall status of the service.
+type HealthState string
+
+// States returned by the health check.
+const (
+	HealthOK       HealthState = "ok"
+	HealthDegraded HealthState = "degraded"
+	HealthDown     HealthState = "down"
+)
+
 // HealthCheckResponse is the main json response from healthcheck endpoint
 type HealthCheckResponse struct {
 	Version struct {
 		Tag    string `json:"tag"`
 		Commit string `json:"commit"`
 	} `json:"version"`
+	// Status is false when service is down or draining.
 	Status   bool                                   `json:"status"`
+	State    HealthState                            `json:"state"`
+	Draining bool                                   `json:"draining,omitempty"`
 	Elapsed  time.Duration                          `json:"elapsed"`
 	Hostname string                                 `json:"hostname"`
 	Checks   map[string]*HealthCheckServiceResponse `json:"checks,omitempty"`
@@ -44,10 +105,16 @@ type HealthCheckResponse struct {
 // details
 type HealthCheckServiceResponse struct {
 	// True status indicates full availability of the service under check.
-	Status  bool          `json:"status"`
-	Elapsed time.Duration `json:"elapsed"`
-	Detail  interface{}   `json:"detail,omitempty"`
-	Error   interface{}   `json:"error,omitempty"`
+	Status      bool          `json:"status"`
+	Criticality Criticality   `json:"criticality"`
+	Elapsed     time.Duration `json:"elapsed"`
+	Detail      interface{}   `json:"detail,omitempty"`
+	Error       interface{}   `json:"error,omitempty"`
+	// CheckedAt is when the result was cached, only when polling is enabled.
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	// Stale is true when the cached result is older than expected.
+	Stale   bool `json:"stale,omitempty"`
+	timeout bool
 	sync.Mutex
 }
 
@@ -58,6 +125,14 @@ var _ fdhttp.Handler = &HealthCheck{}
 // and you also can check a specific service using: Prefix + HealthCheckURL + "/<service-name>"
 var HealthCheckURL = "/health/check"
 
+// HealthCheckLivenessURL is the url to check if the process is alive, no
+// services are checked, so it's safe to restart the service when it fails.
+var HealthCheckLivenessURL = "/health/live"
+
+// HealthCheckReadinessURL is the url to check if the service is ready to
+// receive traffic, it fails when a critical service is down or it's draining.
+var HealthCheckReadinessURL = "/health/ready"
+
 // HealthCheckServiceTimeout is the time that your service need to return otherwise
 var HealthCheckServiceTimeout = 1 * time.Second
 
@@ -74,9 +149,12 @@ type HealthCheck struct {
 	commit               string
 	hostname             string
 	servicesGuard        sync.RWMutex
-	services             map[string]HealthChecker
+	services             map[string]*healthService
 	extraParams          map[string]string
 	disableSystemVersion bool
+	draining             uint32
+	polling              *healthPolling
+	onStatusChange       HealthCheckStatusChangeFunc
 }
 
 // NewHealthCheck create a new healthcheck handler
@@ -87,7 +165,7 @@ func NewHealthCheck(tag, commit string) *HealthCheck {
 		tag:                  tag,
 		commit:               commit,
 		hostname:             hostname,
-		services:             make(map[string]HealthChecker),
+		services:             make(map[string]*healthService),
 		ServiceTimeout:       HealthCheckServiceTimeout,
 		disableSystemVersion: false,
 	}
@@ -104,18 +182,82 @@ func (h *HealthCheck) DisableSystemVersion() *HealthCheck {
 	return h
 }
 
+// SetDraining make the health check and readiness fail while liveness keeps
+// green, that way load balancers stop sending traffic before the service goes
+// away or while you investigate an instance. It implements fdapp.Drainer.
+func (h *HealthCheck) SetDraining(draining bool) {
+	var v uint32
+	if draining {
+		v = 1
+	}
+	atomic.StoreUint32(&h.draining, v)
+}
+
+// Draining return true when SetDraining(true) was called.
+func (h *HealthCheck) Draining() bool {
+	return atomic.LoadUint32(&h.draining) == 1
+}
+
 // Init will be called by fdhttp.Router to register fdhandler.HealthCheckURL
 // into it.
 func (h *HealthCheck) Init(r *fdhttp.Router) {
 	r.GET(h.Prefix+HealthCheckURL, h.Get)
 	r.GET(h.Prefix+HealthCheckURL+"/:service", h.Get)
+	r.GET(h.Prefix+HealthCheckLivenessURL, h.Live)
+	r.GET(h.Prefix+HealthCheckReadinessURL, h.Ready)
+}
+
+// HealthCheckOptions configure how a service is checked.
+type HealthCheckOptions struct {
+	// Criticality define how a failure affects the service status, by default Critical.
+	Criticality Criticality
+	// Interval is used when polling is enabled, by default HealthCheckPollInterval.
+	Interval time.Duration
+}
+
+type healthService struct {
+	name        string
+	checker     HealthChecker
+	criticality Criticality
+	interval    time.Duration
+
+	mu        sync.Mutex
+	last      *HealthCheckServiceResponse
+	checkedAt time.Time
 }
 
-// Register a new healthcheck Service.
+// Register a new healthcheck Service, it's critical.
 func (h *HealthCheck) Register(name string, s HealthChecker) {
+	h.RegisterWithOptions(name, s, HealthCheckOptions{})
+}
+
+// RegisterWithCriticality register a new healthcheck Service, failures of
+// non-critical services don't take the service down.
+func (h *HealthCheck) RegisterWithCriticality(name string, s HealthChecker, c Criticality) {
+	h.RegisterWithOptions(name, s, HealthCheckOptions{Criticality: c})
+}
+
+// RegisterWithOptions register a new healthcheck Service.
+func (h *HealthCheck) RegisterWithOptions(name string, s HealthChecker, opts HealthCheckOptions) {
+	if opts.Interval <= 0 {
+		opts.Interval = HealthCheckPollInterval
+	}
+
+	svc := &healthService{
+		name:        name,
+		checker:     s,
+		criticality: opts.Criticality,
+		interval:    opts.Interval,
+	}
+
 	h.servicesGuard.Lock()
-	h.services[name] = s
+	h.services[name] = svc
+	polling := h.polling
 	h.servicesGuard.Unlock()
+
+	if polling != nil {
+		polling.start(h, svc)
+	}
 }
 
 // Handler will return an http.Handler that you can register in your
@@ -130,6 +272,8 @@ func (h *HealthCheck) Handler() http.Handler {
 func (h *HealthCheck) newResponse() *HealthCheckResponse {
 	resp := &HealthCheckResponse{
 		Status:   true,
+		State:    HealthOK,
+		Draining: h.Draining(),
 		Hostname: h.hostname,
 		Checks:   make(map[string]*HealthCheckServiceResponse),
 	}
@@ -160,91 +304,161 @@ func (h *HealthCheck) newResponse() *HealthCheckResponse {
 // Get is a fdhttp.EndpointFunc that will be registred in the fdhttp.Router.
 // If you don't plan to use fdhttp.Router, check HealthCheck.Handler()
 func (h *HealthCheck) Get(ctx context.Context) (int, interface{}) {
-	started := time.Now()
-	svcParam := fdhttp.RouteParam(ctx, "service")
+	resp := h.check(ctx, fdhttp.RouteParam(ctx, "service"))
+
+	statusCode := http.StatusOK
+	if resp.State == HealthDown {
+		statusCode = http.StatusServiceUnavailable
+		if onlyTimeouts(resp) {
+			statusCode = http.StatusRequestTimeout
+		}
+	}
+
+	if resp.Draining {
+		statusCode = http.StatusServiceUnavailable
+		resp.Status = false
+	}
 
-	var statusCode int32 = http.StatusOK
+	fdhttp.AddResponseHeaderValue(ctx, "Cache-control", "private, no-cache")
+	return statusCode, resp
+}
+
+// Live is a fdhttp.EndpointFunc that return 200 while the process is running,
+// services are not checked and draining is only reported.
+func (h *HealthCheck) Live(ctx context.Context) (int, interface{}) {
+	resp := h.newResponse()
+
+	fdhttp.AddResponseHeaderValue(ctx, "Cache-control", "private, no-cache")
+	return http.StatusOK, resp
+}
+
+// Ready is a fdhttp.EndpointFunc that return 503 when a critical service is
+// down or it's draining. Degraded services are still ready.
+func (h *HealthCheck) Ready(ctx context.Context) (int, interface{}) {
+	resp := h.check(ctx, "")
+
+	statusCode := http.StatusOK
+	if resp.State == HealthDown || resp.Draining {
+		statusCode = http.StatusServiceUnavailable
+		resp.Status = false
+	}
+
+	fdhttp.AddResponseHeaderValue(ctx, "Cache-control", "private, no-cache")
+	return statusCode, resp
+}
+
+// check run all services (or only svcParam) at the same time.
+func (h *HealthCheck) check(ctx context.Context, svcParam string) *HealthCheckResponse {
+	started := time.Now()
 
 	resp := h.newResponse()
 
 	h.servicesGuard.RLock()
-	services := make(map[string]HealthChecker, len(h.services))
+	services := make(map[string]*healthService, len(h.services))
 	for k, v := range h.services {
+		if svcParam != "" && k != svcParam {
+			continue
+		}
 		services[k] = v
 	}
+	polling := h.polling != nil
 	h.servicesGuard.RUnlock()
+
 	var wg sync.WaitGroup
 	for name, svc := range services {
-		if svcParam != "" && name != svcParam {
+		if polling {
+			resp.Checks[name] = h.cachedCheck(svc)
 			continue
 		}
 
-		svcCheck := &HealthCheckServiceResponse{
-			Status: true,
-		}
-
-		resp.Checks[name] = svcCheck
-
 		wg.Add(1)
-		go func(svc HealthChecker, check *HealthCheckServiceResponse) {
+		go func(name string, svc *healthService) {
 			defer wg.Done()
-			started := time.Now()
-
-			timeoutCtx, cancel := context.WithTimeout(ctx, h.ServiceTimeout)
-			go func() {
-				// cancel the context
-				defer cancel()
-
-				detail, err := svc.HealthCheck(timeoutCtx)
-
-				if timeoutCtx.Err() != nil {
-					// context has timed out
-					return
-				}
-
-				if err != nil {
-					atomic.CompareAndSwapInt32(&statusCode, http.StatusOK, http.StatusServiceUnavailable)
-					resp.Lock()
-					resp.Status = false
-					resp.Unlock()
-
-					check.Lock()
-					check.Status = false
-					check.Error = err.Error()
-					check.Unlock()
-				}
-				check.Lock()
-				check.Detail = detail
-				check.Unlock()
-			}()
-
-			<-timeoutCtx.Done()
-
-			err := timeoutCtx.Err()
-			if err == context.DeadlineExceeded {
-				atomic.CompareAndSwapInt32(&statusCode, http.StatusOK, http.StatusRequestTimeout)
-				resp.Lock()
-				resp.Status = false
-				resp.Unlock()
-
-				check.Lock()
-				check.Status = false
-				check.Error = err.Error()
-				check.Unlock()
-			}
-
-			check.Lock()
-			check.Elapsed = time.Since(started) / time.Millisecond
-			check.Unlock()
-		}(svc, svcCheck)
+
+			check := h.runCheck(ctx, svc)
+
+			resp.Lock()
+			resp.Checks[name] = check
+			resp.Unlock()
+		}(name, svc)
 	}
 
 	wg.Wait()
 
-	resp.Lock()
+	resp.State = healthState(resp.Checks)
+	resp.Status = resp.State != HealthDown
 	resp.Elapsed = time.Since(started) / time.Millisecond
-	resp.Unlock()
 
-	fdhttp.AddResponseHeaderValue(ctx, "Cache-control", "private, no-cache")
-	return int(atomic.LoadInt32(&statusCode)), resp
+	return resp
+}
+
+// runCheck call the service waiting at most ServiceTimeout.
+func (h *HealthCheck) runCheck(ctx context.Context, svc *healthService) *HealthCheckServiceResponse {
+	started := time.Now()
+
+	check := &HealthCheckServiceResponse{
+		Status:      true,
+		Criticality: svc.criticality,
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, h.ServiceTimeout)
+	defer cancel()
+
+	type result struct {
+		detail interface{}
+		err    error
+	}
+	done := make(chan result, 1)
+
+	go func() {
+		detail, err := svc.checker.HealthCheck(timeoutCtx)
+		done <- result{detail, err}
+	}()
+
+	select {
+	case r := <-done:
+		check.Detail = r.detail
+		if r.err != nil {
+			check.Status = false
+			check.Error = r.err.Error()
+		}
+	case <-timeoutCtx.Done():
+		check.Status = false
+		check.Error = timeoutCtx.Err().Error()
+		check.timeout = true
+	}
+
+	check.Elapsed = time.Since(started) / time.Millisecond
+	return check
+}
+
+// healthState is down when a critical check fails and degraded when
+// a check that is degraded-only fails.
+func healthState(checks map[string]*HealthCheckServiceResponse) HealthState {
+	state := HealthOK
+
+	for _, check := range checks {
+		if check.Status {
+			continue
+		}
+
+		switch check.Criticality {
+		case Critical:
+			return HealthDown
+		case DegradedOnly:
+			state = HealthDegraded
+		}
+	}
+
+	return state
+}
+
+// onlyTimeouts return true when all critical checks failed due timeout.
+func onlyTimeouts(resp *HealthCheckResponse) bool {
+	for _, check := range resp.Checks {
+		if !check.Status && check.Criticality == Critical && !check.timeout {
+			return false
+		}
+	}
+	return true
 }
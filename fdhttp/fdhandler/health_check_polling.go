@@ -0,0 +1,181 @@
+package fdhandler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// HealthCheckPollInterval is the default interval that services are checked
+// when polling is enabled.
+var HealthCheckPollInterval = 10 * time.Second
+
+// HealthCheckStatusChangeFunc is called when a service status flips, check is the
+// new result. It's called from the polling goroutine, so it should not block.
+type HealthCheckStatusChangeFunc func(name string, check *HealthCheckServiceResponse)
+
+type healthPolling struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	services map[string]context.CancelFunc
+}
+
+// OnStatusChange set a function called when a service status flips while polling,
+// useful to log or alert. Services are considered healthy before the first check.
+func (h *HealthCheck) OnStatusChange(fn HealthCheckStatusChangeFunc) *HealthCheck {
+	h.servicesGuard.Lock()
+	h.onStatusChange = fn
+	h.servicesGuard.Unlock()
+	return h
+}
+
+// StartPolling check each service in background on its own interval, after that
+// requests are answered instantly using the last result. Until the first result
+// arrives the service is reported as failing. Results not refreshed within twice
+// the interval plus ServiceTimeout are stale and considered failing as well.
+//
+//  h := fdhandler.NewHealthCheck(tag, commit)
+//  h.RegisterWithOptions("db", db, fdhandler.HealthCheckOptions{Interval: 5 * time.Second})
+//  h.StartPolling()
+//  defer h.StopPolling()
+func (h *HealthCheck) StartPolling() {
+	h.servicesGuard.Lock()
+	if h.polling != nil {
+		h.servicesGuard.Unlock()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	polling := &healthPolling{
+		ctx:      ctx,
+		cancel:   cancel,
+		services: make(map[string]context.CancelFunc),
+	}
+	h.polling = polling
+
+	services := make([]*healthService, 0, len(h.services))
+	for _, svc := range h.services {
+		services = append(services, svc)
+	}
+	h.servicesGuard.Unlock()
+
+	for _, svc := range services {
+		polling.start(h, svc)
+	}
+}
+
+// StopPolling stop checking services in background and wait the running checks,
+// after that services are checked on each request again.
+func (h *HealthCheck) StopPolling() {
+	h.servicesGuard.Lock()
+	polling := h.polling
+	h.polling = nil
+	h.servicesGuard.Unlock()
+
+	if polling == nil {
+		return
+	}
+
+	polling.cancel()
+	polling.wg.Wait()
+}
+
+// start poll the service, replacing a service registered with the same name.
+func (p *healthPolling) start(h *HealthCheck, svc *healthService) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ctx.Err() != nil {
+		return
+	}
+
+	if cancel, ok := p.services[svc.name]; ok {
+		cancel()
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	p.services[svc.name] = cancel
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		h.poll(ctx, svc)
+	}()
+}
+
+func (h *HealthCheck) poll(ctx context.Context, svc *healthService) {
+	ticker := time.NewTicker(svc.interval)
+	defer ticker.Stop()
+
+	for {
+		check := h.runCheck(ctx, svc)
+		if ctx.Err() != nil {
+			// canceled during the check, the result is not valid
+			return
+		}
+
+		svc.mu.Lock()
+		changed := (svc.last == nil && !check.Status) || (svc.last != nil && svc.last.Status != check.Status)
+		svc.last = check
+		svc.checkedAt = time.Now()
+		svc.mu.Unlock()
+
+		h.servicesGuard.RLock()
+		onStatusChange := h.onStatusChange
+		h.servicesGuard.RUnlock()
+
+		if changed && onStatusChange != nil {
+			onStatusChange(svc.name, copyCheck(check))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// cachedCheck return the last result of the service.
+func (h *HealthCheck) cachedCheck(svc *healthService) *HealthCheckServiceResponse {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	if svc.last == nil {
+		return &HealthCheckServiceResponse{
+			Status:      false,
+			Criticality: svc.criticality,
+			Error:       "service was not checked yet",
+		}
+	}
+
+	check := copyCheck(svc.last)
+	checkedAt := svc.checkedAt
+	check.CheckedAt = &checkedAt
+
+	if time.Since(checkedAt) > 2*svc.interval+h.ServiceTimeout {
+		check.Stale = true
+		check.Status = false
+		if check.Error == nil {
+			check.Error = "result is stale"
+		}
+	}
+
+	return check
+}
+
+// copyCheck copy the result, that way responses don't share the cache.
+func copyCheck(c *HealthCheckServiceResponse) *HealthCheckServiceResponse {
+	return &HealthCheckServiceResponse{
+		Status:      c.Status,
+		Criticality: c.Criticality,
+		Elapsed:     c.Elapsed,
+		Detail:      c.Detail,
+		Error:       c.Error,
+		CheckedAt:   c.CheckedAt,
+		Stale:       c.Stale,
+		timeout:     c.timeout,
+	}
+}
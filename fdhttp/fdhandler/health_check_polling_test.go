@@ -0,0 +1,132 @@
+package fdhandler_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdhttp/fdhandler"
+	"github.com/stretchr/testify/assert"
+)
+
+func newHealthContext() context.Context {
+	return fdhttp.SetResponseHeader(context.Background(), http.Header{})
+}
+
+func TestHealthCheck_PollingServeCachedResults(t *testing.T) {
+	var calls int32
+	dummyCheck := &dummyHealthCheck{
+		HealthCheckFunc: func() (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return "pong", nil
+		},
+	}
+
+	h := fdhandler.NewHealthCheck("1.0.0", "c6053cf")
+	h.RegisterWithOptions("db", dummyCheck, fdhandler.HealthCheckOptions{Interval: time.Hour})
+	h.StartPolling()
+	defer h.StopPolling()
+
+	// wait the first check
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		statusCode, resp := h.Get(newHealthContext())
+		healthResp := resp.(*fdhandler.HealthCheckResponse)
+
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.True(t, healthResp.Checks["db"].Status)
+		assert.Equal(t, "pong", healthResp.Checks["db"].Detail)
+		assert.NotNil(t, healthResp.Checks["db"].CheckedAt)
+		assert.False(t, healthResp.Checks["db"].Stale)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestHealthCheck_PollingNotCheckedYet(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	dummyCheck := &dummyHealthCheck{
+		HealthCheckFunc: func() (interface{}, error) {
+			<-block
+			return nil, nil
+		},
+	}
+
+	h := fdhandler.NewHealthCheck("1.0.0", "c6053cf")
+	h.ServiceTimeout = time.Hour
+	h.Register("db", dummyCheck)
+	h.StartPolling()
+
+	statusCode, resp := h.Ready(newHealthContext())
+	healthResp := resp.(*fdhandler.HealthCheckResponse)
+
+	assert.Equal(t, http.StatusServiceUnavailable, statusCode)
+	assert.Equal(t, "service was not checked yet", healthResp.Checks["db"].Error)
+
+	// the check is canceled, block is closed only to release the goroutine
+	h.StopPolling()
+}
+
+func TestHealthCheck_PollingStatusChange(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		healthy = true
+		changes []bool
+	)
+
+	dummyCheck := &dummyHealthCheck{
+		HealthCheckFunc: func() (interface{}, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if !healthy {
+				return nil, errors.New("connection refused")
+			}
+			return nil, nil
+		},
+	}
+
+	changed := make(chan struct{}, 10)
+
+	h := fdhandler.NewHealthCheck("1.0.0", "c6053cf")
+	h.OnStatusChange(func(name string, check *fdhandler.HealthCheckServiceResponse) {
+		assert.Equal(t, "db", name)
+
+		mu.Lock()
+		changes = append(changes, check.Status)
+		mu.Unlock()
+
+		changed <- struct{}{}
+	})
+	h.RegisterWithOptions("db", dummyCheck, fdhandler.HealthCheckOptions{Interval: 5 * time.Millisecond})
+	h.StartPolling()
+	defer h.StopPolling()
+
+	// healthy at the first check doesn't flip
+	time.Sleep(20 * time.Millisecond)
+
+	mu.Lock()
+	healthy = false
+	mu.Unlock()
+	<-changed
+
+	mu.Lock()
+	healthy = true
+	mu.Unlock()
+	<-changed
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []bool{false, true}, changes)
+}
@@ -73,6 +73,28 @@ func TestHealthCheckNoSystemVersion(t *testing.T) {
 	assert.Empty(t, healthResp.System.Version)
 }
 
+func TestHealthCheck_Draining(t *testing.T) {
+	h := fdhandler.NewHealthCheck("1.0.0", "c6053cf")
+	h.SetDraining(true)
+
+	router := fdhttp.NewRouter()
+	router.Register(h)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/health/check")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	var healthResp fdhandler.HealthCheckResponse
+	json.NewDecoder(resp.Body).Decode(&healthResp)
+	defer resp.Body.Close()
+
+	assert.False(t, healthResp.Status)
+	assert.True(t, healthResp.Draining)
+}
+
 func TestHealthCheck_WithPrefixAndDifferentURL(t *testing.T) {
 	defaultHealthCheckURL := fdhandler.HealthCheckURL
 	defer func() {
@@ -341,3 +363,116 @@ func TestHealthCheck_WithExtra_ShowExtra(t *testing.T) {
 		assert.Equal(t, v, hcResponse.Extra[k], fmt.Sprintf("for %s expected %s, obtained %s", k, v, hcResponse.Extra[k]))
 	}
 }
+
+func TestHealthCheck_Criticality(t *testing.T) {
+	failing := &dummyHealthCheck{
+		HealthCheckFunc: func() (interface{}, error) {
+			return nil, errors.New("cannot access remote server")
+		},
+	}
+
+	tests := []struct {
+		criticality        fdhandler.Criticality
+		expectedStatusCode int
+		expectedState      fdhandler.HealthState
+	}{
+		{fdhandler.Critical, http.StatusServiceUnavailable, fdhandler.HealthDown},
+		{fdhandler.DegradedOnly, http.StatusOK, fdhandler.HealthDegraded},
+		{fdhandler.Informational, http.StatusOK, fdhandler.HealthOK},
+	}
+
+	for _, tt := range tests {
+		h := fdhandler.NewHealthCheck("1.0.0", "c6053cf")
+		h.RegisterWithCriticality("recommendations", failing, tt.criticality)
+
+		router := fdhttp.NewRouter()
+		router.Register(h)
+
+		ts := httptest.NewServer(router)
+
+		for _, url := range []string{"/health/check", "/health/ready"} {
+			resp, err := http.Get(ts.URL + url)
+			if !assert.NoError(t, err) {
+				continue
+			}
+
+			var healthResp fdhandler.HealthCheckResponse
+			json.NewDecoder(resp.Body).Decode(&healthResp)
+			resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode, "%s with %s check", url, tt.criticality)
+			assert.Equal(t, tt.expectedState, healthResp.State, "%s with %s check", url, tt.criticality)
+			assert.Equal(t, tt.criticality, healthResp.Checks["recommendations"].Criticality)
+			assert.False(t, healthResp.Checks["recommendations"].Status)
+		}
+
+		ts.Close()
+	}
+}
+
+func TestHealthCheck_LivenessAndReadiness(t *testing.T) {
+	calls := 0
+	dummyCheck := &dummyHealthCheck{
+		HealthCheckFunc: func() (interface{}, error) {
+			calls++
+			return nil, errors.New("cannot access remote server")
+		},
+	}
+
+	h := fdhandler.NewHealthCheck("1.0.0", "c6053cf")
+	h.Register("db", dummyCheck)
+
+	router := fdhttp.NewRouter()
+	router.Register(h)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// liveness doesn't check services
+	resp, err := http.Get(ts.URL + "/health/live")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 0, calls)
+
+	resp, err = http.Get(ts.URL + "/health/ready")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 1, calls)
+}
+
+func TestHealthCheck_DrainFailOnlyReadiness(t *testing.T) {
+	h := fdhandler.NewHealthCheck("1.0.0", "c6053cf")
+	h.SetDraining(true)
+
+	router := fdhttp.NewRouter()
+	router.Register(h)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/health/ready")
+	assert.NoError(t, err)
+
+	var healthResp fdhandler.HealthCheckResponse
+	json.NewDecoder(resp.Body).Decode(&healthResp)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.False(t, healthResp.Status)
+	assert.True(t, healthResp.Draining)
+	assert.Equal(t, fdhandler.HealthOK, healthResp.State)
+
+	resp, err = http.Get(ts.URL + "/health/live")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	h.SetDraining(false)
+
+	resp, err = http.Get(ts.URL + "/health/ready")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,63 @@
+package fdhandler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdhttp/fdopenapi"
+)
+
+var _ fdhttp.Handler = &OpenAPI{}
+
+// OpenAPIURL is the default path used to serve the OpenAPI document.
+var OpenAPIURL = "/openapi.json"
+
+// OpenAPI serve an OpenAPI 3 document describing all endpoints registered
+// in the router. Use Endpoint.SetRequestSchema() and Endpoint.SetResponseSchema()
+// to document request and response bodies.
+type OpenAPI struct {
+	router *fdhttp.Router
+	// Path is where the document will be available, by default fdhandler.OpenAPIURL.
+	Path string
+	// Generator can be used to add servers or a description to the document.
+	Generator *fdopenapi.Generator
+
+	once sync.Once
+	doc  *fdopenapi.Document
+}
+
+// NewOpenAPI create a handler that describe your API with title and version.
+func NewOpenAPI(title, version string) *OpenAPI {
+	return &OpenAPI{
+		Path:      OpenAPIURL,
+		Generator: fdopenapi.NewGenerator(title, version),
+	}
+}
+
+// Init will be called by fdhttp.Router to register OpenAPI.Path into it.
+func (h *OpenAPI) Init(router *fdhttp.Router) {
+	h.router = router
+	router.GET(h.Path, h.Get)
+}
+
+// Get is a fdhttp.EndpointFunc that return the OpenAPI document. It's
+// generated only once, because all endpoints are known after router is initialized.
+func (h *OpenAPI) Get(ctx context.Context) (int, interface{}) {
+	h.once.Do(func() {
+		endpoints := h.router.Endpoints()
+
+		filtered := make([]fdhttp.Endpoint, 0, len(endpoints))
+		for _, e := range endpoints {
+			if e.Method == http.MethodGet && e.Path == h.router.Prefix+h.Path {
+				continue
+			}
+			filtered = append(filtered, e)
+		}
+
+		h.doc = h.Generator.Generate(filtered)
+	})
+
+	return http.StatusOK, h.doc
+}
@@ -0,0 +1,44 @@
+package fdhandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdhttp/fdhandler"
+	"github.com/foodora/go-ranger/fdhttp/fdopenapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPI(t *testing.T) {
+	openAPIHandler := fdhandler.NewOpenAPI("My API", "1.0.0")
+
+	router := fdhttp.NewRouter()
+	router.Register(openAPIHandler)
+	router.GET("/v1/foo/:id", func(ctx context.Context) (int, interface{}) {
+		return http.StatusOK, nil
+	}).SetResponseSchema(http.StatusOK, map[string]string{}).SetName("get_foo")
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc fdopenapi.Document
+	err := json.NewDecoder(w.Body).Decode(&doc)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "My API", doc.Info.Title)
+	assert.Len(t, doc.Paths, 1)
+
+	item := doc.Paths["/v1/foo/{id}"]
+	if assert.NotNil(t, item) {
+		op := (*item)["get"]
+		assert.Equal(t, "get_foo", op.OperationID)
+		assert.Equal(t, "object", op.Responses["200"].Content["application/json"].Schema.Type)
+	}
+}
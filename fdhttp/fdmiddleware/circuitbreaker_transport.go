@@ -58,6 +58,21 @@ func (c *Circuit) Configure(rate float64, minSamples int64) {
 	c.mu.Unlock()
 }
 
+// Tripped return true when the circuit is open and requests are failing fast.
+func (c *Circuit) Tripped() bool {
+	return c.breaker.Tripped()
+}
+
+// ErrorRate return the error rate over the current window.
+func (c *Circuit) ErrorRate() float64 {
+	return c.breaker.ErrorRate()
+}
+
+// Failures return the number of failures over the current window.
+func (c *Circuit) Failures() int64 {
+	return c.breaker.Failures()
+}
+
 func (c *Circuit) Wrap(next http.RoundTripper) http.RoundTripper {
 	return RoundTripperFunc(func(req *http.Request) (resp *http.Response, err error) {
 		c.mu.RLock()
@@ -1,7 +1,9 @@
 package fdmiddleware
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"html/template"
 	"net"
 	"net/http"
@@ -96,6 +98,33 @@ func (lr *LogResponse) WriteHeader(code int) {
 	lr.ResponseWriter.WriteHeader(code)
 }
 
+// Flush implements http.Flusher, otherwise streams would not reach clients.
+func (lr *LogResponse) Flush() {
+	if f, ok := lr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, it's used by WebSockets. As the handler
+// is not able to write the status code anymore, we log it as 101 Switching Protocols.
+func (lr *LogResponse) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := lr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("fdmiddleware: response writer doesn't support hijack")
+	}
+
+	conn, brw, err := h.Hijack()
+	if err == nil {
+		lr.StatusCode = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+// Unwrap return the original http.ResponseWriter, it's used by http.ResponseController.
+func (lr *LogResponse) Unwrap() http.ResponseWriter {
+	return lr.ResponseWriter
+}
+
 func (lr *LogResponse) StatusText() string {
 	return http.StatusText(lr.StatusCode)
 }
@@ -147,3 +147,22 @@ func TestNewLogMiddleware_CanGetError(t *testing.T) {
 
 	assert.Equal(t, handlerErr.Error(), logger.PrintfMsg)
 }
+
+func TestNewLogMiddleware_ResponseCanBeFlushed(t *testing.T) {
+	logMiddleware := fdmiddleware.NewLogMiddleware()
+	logMiddleware.SetLoggerFunc(func(logReq *fdmiddleware.LogRequest) {})
+
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if assert.True(t, ok) {
+			w.Write([]byte("chunk"))
+			flusher.Flush()
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/foo", nil)
+	logMiddleware.Wrap(http.HandlerFunc(handler)).ServeHTTP(w, req)
+
+	assert.True(t, w.Flushed)
+}
@@ -0,0 +1,2 @@
+// Package fdopenapi generate OpenAPI 3 specifications from endpoints registered in fdhttp.Router.
+package fdopenapi
@@ -0,0 +1,212 @@
+package fdopenapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/foodora/go-ranger/fdhttp"
+)
+
+// ContentType is the media type used to describe request and response bodies.
+var ContentType = "application/json"
+
+// Generator build an OpenAPI document from a list of fdhttp.Endpoint.
+type Generator struct {
+	Info    Info
+	Servers []Server
+}
+
+// NewGenerator create a generator that describe your API with title and version.
+func NewGenerator(title, version string) *Generator {
+	return &Generator{
+		Info: Info{
+			Title:   title,
+			Version: version,
+		},
+	}
+}
+
+// Generate a new document describing all endpoints, usually the result of
+// fdhttp.Router.Endpoints().
+func (g *Generator) Generate(endpoints []fdhttp.Endpoint) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    g.Info,
+		Servers: g.Servers,
+		Paths:   make(map[string]*PathItem),
+	}
+
+	schemas := newSchemaRegistry()
+
+	for _, e := range uniqueEndpoints(endpoints) {
+		path, params := convertPath(e.Path)
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+
+		(*item)[strings.ToLower(e.Method)] = g.operation(schemas, e, params)
+	}
+
+	if len(schemas.components) > 0 {
+		doc.Components = &Components{
+			Schemas: schemas.components,
+		}
+	}
+
+	return doc
+}
+
+func (g *Generator) operation(schemas *schemaRegistry, e fdhttp.Endpoint, params []*Parameter) *Operation {
+	doc := e.Doc()
+
+	op := &Operation{
+		OperationID: e.Name,
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		Tags:        doc.Tags,
+		Deprecated:  doc.Deprecated,
+		Parameters:  params,
+		Responses:   make(map[string]*Response),
+	}
+
+	if doc.Request != nil {
+		t := reflect.TypeOf(doc.Request)
+
+		for _, p := range schemas.parameters(t) {
+			if p.In == "path" {
+				// path params are already in the list, but now we know their types
+				for k := range op.Parameters {
+					if op.Parameters[k].Name == p.Name {
+						if p.Description == "" {
+							p.Description = op.Parameters[k].Description
+						}
+						op.Parameters[k] = p
+					}
+				}
+				continue
+			}
+			op.Parameters = append(op.Parameters, p)
+		}
+
+		if hasBody(t) {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content: map[string]*MediaType{
+					ContentType: {Schema: schemas.schemaOf(doc.Request)},
+				},
+			}
+		}
+	}
+
+	for statusCode, v := range doc.Responses {
+		resp := &Response{
+			Description: http.StatusText(statusCode),
+		}
+		if v != nil {
+			resp.Content = map[string]*MediaType{
+				ContentType: {Schema: schemas.schemaOf(v)},
+			}
+		}
+		op.Responses[strconv.Itoa(statusCode)] = resp
+	}
+
+	if len(op.Responses) == 0 {
+		// OpenAPI requires at least one response per operation
+		op.Responses["default"] = &Response{Description: "Default response"}
+	}
+
+	return op
+}
+
+// uniqueEndpoints remove endpoints registered twice, it happens when
+// Endpoint.SetName() is called and router keeps the generated name as well.
+// The name given by the user has precedence.
+func uniqueEndpoints(endpoints []fdhttp.Endpoint) []fdhttp.Endpoint {
+	sorted := make([]fdhttp.Endpoint, len(endpoints))
+	copy(sorted, endpoints)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		if sorted[i].Method != sorted[j].Method {
+			return sorted[i].Method < sorted[j].Method
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	unique := make([]fdhttp.Endpoint, 0, len(sorted))
+	seen := make(map[string]int)
+
+	for _, e := range sorted {
+		key := e.Method + " " + e.Path
+		i, ok := seen[key]
+		if !ok {
+			seen[key] = len(unique)
+			unique = append(unique, e)
+			continue
+		}
+
+		if unique[i].Name == generatedName(e) {
+			unique[i] = e
+		}
+	}
+
+	return unique
+}
+
+// generatedName is the name that fdhttp.Router give to endpoints without name.
+func generatedName(e fdhttp.Endpoint) string {
+	var name strings.Builder
+	name.WriteString(e.Method)
+	name.WriteRune('_')
+
+	for _, r := range strings.Trim(e.Path, "/") {
+		switch r {
+		case ':', '*':
+		case '/':
+			name.WriteRune('_')
+		default:
+			name.WriteRune(r)
+		}
+	}
+
+	return name.String()
+}
+
+// convertPath convert httprouter params into OpenAPI templates, like:
+// 		/v:version/people/:id/*file
+// 		become
+// 		/v{version}/people/{id}/{file}
+func convertPath(path string) (string, []*Parameter) {
+	var params []*Parameter
+
+	parts := strings.Split(path, "/")
+	for k, part := range parts {
+		i := strings.IndexAny(part, ":*")
+		if i < 0 {
+			continue
+		}
+
+		name := part[i+1:]
+		param := &Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		}
+		if part[i] == '*' {
+			param.Description = "Catch-all parameter, it can contain slashes"
+		}
+
+		params = append(params, param)
+		parts[k] = part[:i] + "{" + name + "}"
+	}
+
+	return strings.Join(parts, "/"), params
+}
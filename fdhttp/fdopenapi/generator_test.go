@@ -0,0 +1,179 @@
+package fdopenapi_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdhttp/fdopenapi"
+	"github.com/stretchr/testify/assert"
+)
+
+type address struct {
+	Street string `json:"street"`
+	Number int    `json:"number,omitempty"`
+}
+
+type person struct {
+	ID        int64     `json:"id,string"`
+	Name      string    `json:"name" description:"Full name"`
+	Email     *string   `json:"email"`
+	Tags      []string  `json:"tags,omitempty"`
+	Address   address   `json:"address"`
+	Friends   []*person `json:"friends,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Ignored   string    `json:"-"`
+	internal  string
+}
+
+func noop(ctx context.Context) (int, interface{}) {
+	return http.StatusOK, nil
+}
+
+func TestGenerator_Paths(t *testing.T) {
+	router := fdhttp.NewRouter()
+	router.GET("/v:version/people/:id", noop)
+	router.GET("/download/*file", noop)
+	router.StdPOST("/v1/people", func(w http.ResponseWriter, req *http.Request) {}).SetName("create_person")
+
+	doc := fdopenapi.NewGenerator("People API", "1.0.0").Generate(router.Endpoints())
+
+	assert.Equal(t, fdopenapi.Version, doc.OpenAPI)
+	assert.Equal(t, "People API", doc.Info.Title)
+	assert.Equal(t, "1.0.0", doc.Info.Version)
+	assert.Len(t, doc.Paths, 3)
+
+	op := (*doc.Paths["/v{version}/people/{id}"])["get"]
+	if assert.NotNil(t, op) {
+		assert.Equal(t, "GET_vversion_people_id", op.OperationID)
+		assert.Len(t, op.Parameters, 2)
+		assert.Equal(t, "version", op.Parameters[0].Name)
+		assert.Equal(t, "path", op.Parameters[0].In)
+		assert.True(t, op.Parameters[0].Required)
+		assert.Equal(t, "id", op.Parameters[1].Name)
+		assert.Contains(t, op.Responses, "default")
+	}
+
+	op = (*doc.Paths["/download/{file}"])["get"]
+	if assert.NotNil(t, op) {
+		assert.Len(t, op.Parameters, 1)
+		assert.Equal(t, "file", op.Parameters[0].Name)
+	}
+
+	// named endpoints are registered twice in the router
+	item := doc.Paths["/v1/people"]
+	if assert.NotNil(t, item) {
+		assert.Len(t, *item, 1)
+		assert.Equal(t, "create_person", (*item)["post"].OperationID)
+	}
+}
+
+func TestGenerator_Schemas(t *testing.T) {
+	router := fdhttp.NewRouter()
+	router.POST("/v1/people", noop).
+		SetSummary("Create a person").
+		SetTags("people").
+		SetRequestSchema(person{}).
+		SetResponseSchema(http.StatusCreated, &person{}).
+		SetResponseSchema(http.StatusBadRequest, &fdhttp.Error{}).
+		SetResponseSchema(http.StatusNoContent, nil)
+
+	doc := fdopenapi.NewGenerator("People API", "1.0.0").Generate(router.Endpoints())
+
+	op := (*doc.Paths["/v1/people"])["post"]
+	if !assert.NotNil(t, op) {
+		return
+	}
+
+	assert.Equal(t, "Create a person", op.Summary)
+	assert.Equal(t, []string{"people"}, op.Tags)
+	assert.Equal(t, "#/components/schemas/person", op.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/person", op.Responses["201"].Content["application/json"].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/Error", op.Responses["400"].Content["application/json"].Schema.Ref)
+	assert.Equal(t, "No Content", op.Responses["204"].Description)
+	assert.Nil(t, op.Responses["204"].Content)
+
+	s := doc.Components.Schemas["person"]
+	if !assert.NotNil(t, s) {
+		return
+	}
+
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, []string{"id", "name", "address", "created_at"}, s.Required)
+	assert.Len(t, s.Properties, 7)
+	assert.Equal(t, "string", s.Properties["id"].Type)
+	assert.Equal(t, "Full name", s.Properties["name"].Description)
+	assert.True(t, s.Properties["email"].Nullable)
+	assert.Equal(t, "array", s.Properties["tags"].Type)
+	assert.Equal(t, "string", s.Properties["tags"].Items.Type)
+	assert.Equal(t, "#/components/schemas/address", s.Properties["address"].Ref)
+	assert.Equal(t, "#/components/schemas/person", s.Properties["friends"].Items.Ref)
+	assert.Equal(t, "date-time", s.Properties["created_at"].Format)
+
+	s = doc.Components.Schemas["address"]
+	if assert.NotNil(t, s) {
+		assert.Equal(t, []string{"street"}, s.Required)
+		assert.Equal(t, "integer", s.Properties["number"].Type)
+	}
+}
+
+type updatePerson struct {
+	ID     int64  `param:"id"`
+	DryRun bool   `query:"dry_run" description:"Validate without save"`
+	Locale string `header:"Accept-Language" validate:"required,enum=en|de"`
+	Name   string `json:"name" validate:"required,min=2,max=10"`
+	Age    *int   `json:"age,omitempty" validate:"min=18"`
+}
+
+func TestGenerator_Binding(t *testing.T) {
+	router := fdhttp.NewRouter()
+	router.PUT("/v1/people/:id", noop).Bind(updatePerson{})
+
+	doc := fdopenapi.NewGenerator("People API", "1.0.0").Generate(router.Endpoints())
+
+	op := (*doc.Paths["/v1/people/{id}"])["put"]
+	if !assert.NotNil(t, op) {
+		return
+	}
+
+	if assert.Len(t, op.Parameters, 3) {
+		assert.Equal(t, "id", op.Parameters[0].Name)
+		assert.Equal(t, "integer", op.Parameters[0].Schema.Type)
+
+		assert.Equal(t, "dry_run", op.Parameters[1].Name)
+		assert.Equal(t, "query", op.Parameters[1].In)
+		assert.Equal(t, "Validate without save", op.Parameters[1].Description)
+		assert.False(t, op.Parameters[1].Required)
+
+		assert.Equal(t, "Accept-Language", op.Parameters[2].Name)
+		assert.Equal(t, "header", op.Parameters[2].In)
+		assert.True(t, op.Parameters[2].Required)
+		assert.Equal(t, []interface{}{"en", "de"}, op.Parameters[2].Schema.Enum)
+	}
+
+	s := doc.Components.Schemas["updatePerson"]
+	if assert.NotNil(t, s) {
+		assert.Len(t, s.Properties, 2)
+		assert.Equal(t, []string{"name"}, s.Required)
+		assert.Equal(t, int64(2), *s.Properties["name"].MinLength)
+		assert.Equal(t, int64(10), *s.Properties["name"].MaxLength)
+		assert.Equal(t, float64(18), *s.Properties["age"].Minimum)
+	}
+}
+
+func TestGenerator_BindingWithoutBody(t *testing.T) {
+	router := fdhttp.NewRouter()
+	router.GET("/v1/people/:id", noop).Bind(struct {
+		ID int64 `param:"id"`
+	}{})
+
+	doc := fdopenapi.NewGenerator("People API", "1.0.0").Generate(router.Endpoints())
+
+	op := (*doc.Paths["/v1/people/{id}"])["get"]
+	if assert.NotNil(t, op) {
+		assert.Nil(t, op.RequestBody)
+		assert.Len(t, op.Parameters, 1)
+	}
+}
@@ -0,0 +1,373 @@
+package fdopenapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foodora/go-ranger/fdhttp"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaRegistry keeps named structs as components, that way they're
+// declared only once and types that reference themselves are supported.
+type schemaRegistry struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// schemaOf return the schema of the value's type.
+func (r *schemaRegistry) schemaOf(v interface{}) *Schema {
+	return r.schema(reflect.TypeOf(v))
+}
+
+func (r *schemaRegistry) schema(t reflect.Type) *Schema {
+	if t == nil {
+		// interface{} accept any value
+		return &Schema{}
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	if t.Kind() != reflect.Ptr && t.Implements(jsonMarshalerType) {
+		// we don't know what it looks like
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := r.schema(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json send []byte as base64
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		return r.ref(t)
+	}
+
+	// interface, func, chan...
+	return &Schema{}
+}
+
+// ref register the struct as component and return a reference to it.
+func (r *schemaRegistry) ref(t reflect.Type) *Schema {
+	name, ok := r.names[t]
+	if !ok {
+		name = r.componentName(t)
+		r.names[t] = name
+		// reserve the name before build the schema, that way recursive
+		// types will find it
+		r.components[name] = nil
+		r.components[name] = r.structSchema(t)
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (r *schemaRegistry) componentName(t reflect.Type) string {
+	name := t.Name()
+	if _, ok := r.components[name]; !ok {
+		return name
+	}
+
+	// same name in a different package
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	name = pkg + "." + name
+
+	base := name
+	for i := 2; ; i++ {
+		if _, ok := r.components[name]; !ok {
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+	r.addFields(s, t)
+	return s
+}
+
+func (r *schemaRegistry) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := parseTag(tag)
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				// fields of embedded structs are promoted
+				r.addFields(s, ft)
+				continue
+			}
+		}
+
+		if f.PkgPath != "" || isBound(f) {
+			// unexported or not sent in the body
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		var fs *Schema
+		if opts.contains("string") && isScalar(f.Type) {
+			fs = &Schema{Type: "string"}
+		} else {
+			fs = r.schema(f.Type)
+		}
+
+		if desc := f.Tag.Get("description"); desc != "" && fs.Ref == "" {
+			fs.Description = desc
+		}
+
+		required := applyRules(fs, f.Tag.Get("validate"))
+
+		s.Properties[name] = fs
+
+		if required || (!opts.contains("omitempty") && f.Type.Kind() != reflect.Ptr) {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// bindTags are the tags used by fdhttp.Bind() to read values outside of the body.
+// and where they're located in the request.
+var bindTags = []struct{ tag, in string }{
+	{fdhttp.BindParamTag, "path"},
+	{fdhttp.BindQueryTag, "query"},
+	{fdhttp.BindFormTag, "query"},
+	{fdhttp.BindHeaderTag, "header"},
+}
+
+func isBound(f reflect.StructField) bool {
+	for _, b := range bindTags {
+		if f.Tag.Get(b.tag) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasBody return true if any field is decoded from the body.
+func hasBody(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("json") == "-" || isBound(f) {
+			continue
+		}
+		if f.PkgPath == "" || f.Anonymous {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parameters return the parameters of a struct used with Endpoint.Bind().
+func (r *schemaRegistry) parameters(t reflect.Type) []*Parameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var params []*Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		for _, b := range bindTags {
+			name := f.Tag.Get(b.tag)
+			if name == "" {
+				continue
+			}
+
+			schema := r.schema(f.Type)
+			if schema.Type == "array" {
+				// we bind each value, it doesn't make sense to validate the slice
+				applyRules(schema.Items, f.Tag.Get("validate"))
+			}
+
+			param := &Parameter{
+				Name:        name,
+				In:          b.in,
+				Description: f.Tag.Get("description"),
+				Schema:      schema,
+			}
+			param.Required = applyRules(schema, f.Tag.Get("validate")) || b.in == "path"
+			params = append(params, param)
+			break
+		}
+	}
+
+	return params
+}
+
+// applyRules add validations from fdhttp.Validate() into the schema and
+// return if the field is required.
+func applyRules(s *Schema, tag string) bool {
+	var required bool
+
+	for tag != "" {
+		var def string
+		if strings.HasPrefix(tag, "regex=") {
+			def, tag = tag, ""
+		} else {
+			var opts tagOptions
+			def, opts = parseTag(tag)
+			tag = string(opts)
+		}
+
+		name, arg := parseRule(def)
+		switch name {
+		case "required":
+			required = true
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+
+			if s.Ref != "" {
+				// cannot add validations to references
+				continue
+			}
+
+			switch s.Type {
+			case "string":
+				l := int64(n)
+				if name == "min" {
+					s.MinLength = &l
+				} else {
+					s.MaxLength = &l
+				}
+			case "integer", "number":
+				if name == "min" {
+					s.Minimum = &n
+				} else {
+					s.Maximum = &n
+				}
+			}
+		case "regex":
+			s.Pattern = arg
+		case "enum":
+			for _, e := range strings.Split(arg, "|") {
+				if s.Type == "integer" || s.Type == "number" {
+					if n, err := strconv.ParseFloat(e, 64); err == nil {
+						s.Enum = append(s.Enum, n)
+					}
+					continue
+				}
+				s.Enum = append(s.Enum, e)
+			}
+		}
+	}
+
+	return required
+}
+
+func parseRule(def string) (string, string) {
+	if i := strings.Index(def, "="); i >= 0 {
+		return def[:i], def[i+1:]
+	}
+	return def, ""
+}
+
+func isScalar(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	}
+
+	return false
+}
+
+type tagOptions string
+
+func parseTag(tag string) (string, tagOptions) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tagOptions(tag[i+1:])
+	}
+	return tag, ""
+}
+
+func (o tagOptions) contains(name string) bool {
+	for _, opt := range strings.Split(string(o), ",") {
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
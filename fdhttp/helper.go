@@ -1,7 +1,7 @@
 package fdhttp
 
 import (
-	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"net/http"
 	"sync"
@@ -22,22 +22,31 @@ func (err *Error) Error() string {
 	return fmt.Sprintf("%s: %s", err.Code, err.Message)
 }
 
-// ResponseJSON respond as a json object.
-func ResponseJSON(w http.ResponseWriter, statusCode int, resp interface{}) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(statusCode)
-
-	if resp == nil {
-		return
+// MarshalXML encode the error with the same fields used in JSON.
+func (err *Error) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "error"}
+	if err := e.EncodeToken(start); err != nil {
+		return err
 	}
 
-	if j, ok := resp.(JSONer); ok {
-		resp = j.JSON()
+	if err := e.EncodeElement(err.Code, xml.StartElement{Name: xml.Name{Local: "code"}}); err != nil {
+		return err
 	}
-
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		defaultLogger.Printf("Unable to send response to client: %v", err)
+	if err := e.EncodeElement(err.Message, xml.StartElement{Name: xml.Name{Local: "message"}}); err != nil {
+		return err
+	}
+	if err.Detail != nil {
+		if err := e.EncodeElement(err.Detail, xml.StartElement{Name: xml.Name{Local: "detail"}}); err != nil {
+			return err
+		}
 	}
+
+	return e.EncodeToken(start.End())
+}
+
+// ResponseJSON respond as a json object.
+func ResponseJSON(w http.ResponseWriter, statusCode int, resp interface{}) {
+	ResponseEncoded(w, JSONEncoder{}, statusCode, resp)
 }
 
 // Un can be called with defer passing Lock() function as parameter.
@@ -0,0 +1,147 @@
+package fdhttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Prefixes of addresses that are not TCP, check fdhttp.Listen.
+const (
+	UnixAddrPrefix    = "unix:"
+	FDAddrPrefix      = "fd:"
+	SystemdAddrPrefix = "systemd"
+)
+
+// systemdListenFDsStart is the first file descriptor passed by systemd,
+// see sd_listen_fds(3).
+const systemdListenFDsStart = 3
+
+// ErrNoSystemdListener is returned when the process was not started by
+// systemd socket activation or the name doesn't exist.
+var ErrNoSystemdListener = errors.New("fdhttp: no systemd listener found")
+
+// Listen create a listener to the address informed, that can be:
+//
+//  "0.0.0.0:8080", ":8080" or just the port "8080" - TCP
+//  "unix:/var/run/app.sock" - Unix domain socket, old socket files are removed
+//  "fd:3" - a file descriptor inherited from the parent process
+//  "systemd" or "systemd:name" - socket activation, using LISTEN_FDS and LISTEN_FDNAMES
+func Listen(addr string) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, UnixAddrPrefix):
+		return listenUnix(strings.TrimPrefix(addr, UnixAddrPrefix))
+	case strings.HasPrefix(addr, FDAddrPrefix):
+		fd, err := strconv.Atoi(strings.TrimPrefix(addr, FDAddrPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("fdhttp: invalid file descriptor in %s", addr)
+		}
+		return listenFD(uintptr(fd), addr)
+	case addr == SystemdAddrPrefix || strings.HasPrefix(addr, SystemdAddrPrefix+":"):
+		return SystemdListener(strings.TrimPrefix(strings.TrimPrefix(addr, SystemdAddrPrefix), ":"))
+	}
+
+	addr, err := checkAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	return net.Listen("tcp", addr)
+}
+
+func listenUnix(path string) (net.Listener, error) {
+	// socket left by a process that didn't stop properly
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return net.Listen("unix", path)
+}
+
+func listenFD(fd uintptr, name string) (net.Listener, error) {
+	f := os.NewFile(fd, name)
+	if f == nil {
+		return nil, fmt.Errorf("fdhttp: invalid file descriptor %d", fd)
+	}
+	defer f.Close()
+
+	return net.FileListener(f)
+}
+
+type systemdListener struct {
+	name string
+	net.Listener
+}
+
+var systemd struct {
+	once      sync.Once
+	mu        sync.Mutex
+	listeners []systemdListener
+	err       error
+}
+
+// SystemdListener return a listener passed by systemd socket activation, name is
+// defined by FileDescriptorName= in the .socket unit. Empty name return the first one.
+// Each listener can be returned only once.
+func SystemdListener(name string) (net.Listener, error) {
+	systemd.once.Do(func() {
+		systemd.listeners, systemd.err = systemdListeners()
+	})
+	if systemd.err != nil {
+		return nil, systemd.err
+	}
+
+	systemd.mu.Lock()
+	defer systemd.mu.Unlock()
+
+	for i, l := range systemd.listeners {
+		if name == "" || l.name == name {
+			systemd.listeners = append(systemd.listeners[:i], systemd.listeners[i+1:]...)
+			return l.Listener, nil
+		}
+	}
+
+	return nil, ErrNoSystemdListener
+}
+
+func systemdListeners() ([]systemdListener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, ErrNoSystemdListener
+	}
+
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n == 0 {
+		return nil, ErrNoSystemdListener
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	// avoid child processes inherit them
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]systemdListener, 0, n)
+	for i := 0; i < n; i++ {
+		fd := systemdListenFDsStart + i
+
+		name := strconv.Itoa(fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		l, err := listenFD(uintptr(fd), name)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, systemdListener{name: name, Listener: l})
+	}
+
+	return listeners, nil
+}
@@ -0,0 +1,191 @@
+package fdhttp_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func newNamedRouter(name string) *fdhttp.Router {
+	r := fdhttp.NewRouter()
+	r.GET("/name", func(ctx context.Context) (int, interface{}) {
+		return http.StatusOK, name
+	})
+	return r
+}
+
+func getBody(t *testing.T, client *http.Client, url string) string {
+	resp, err := client.Get(url)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestServer_ServeMultipleListeners(t *testing.T) {
+	api, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	admin, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	srv, stopChan := startServer("")
+	srv.AddListener(admin, newNamedRouter("admin"))
+	go func() {
+		err := srv.Serve(api, newNamedRouter("api"))
+		assert.Equal(t, fdhttp.ErrServerStopped, err)
+		stopChan <- struct{}{}
+	}()
+
+	// listeners are already open, there is no need to wait
+	assert.Equal(t, "\"api\"\n", getBody(t, http.DefaultClient, "http://"+api.Addr().String()+"/name"))
+	assert.Equal(t, "\"admin\"\n", getBody(t, http.DefaultClient, "http://"+admin.Addr().String()+"/name"))
+	assert.Equal(t, api.Addr(), srv.Addr())
+
+	err = stopServer(t, srv, stopChan)
+	assert.NoError(t, err)
+}
+
+func TestServer_StartFailsWhenAddressIsInvalid(t *testing.T) {
+	srv := fdhttp.NewServer("127.0.0.1:0")
+	srv.AddAddr("invalid", newNamedRouter("admin"))
+
+	err := srv.Start(newNamedRouter("api"))
+	assert.EqualError(t, err, "address invalid: missing port in address")
+}
+
+func TestListen_UnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fdhttp-unix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.sock")
+
+	// stale socket from a previous run
+	old, err := net.Listen("unix", path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	old.(*net.UnixListener).SetUnlinkOnClose(false)
+	old.Close()
+
+	srv, stopChan := startServer("unix:" + path)
+	go func() {
+		srv.Start(newNamedRouter("unix"))
+		stopChan <- struct{}{}
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+
+	var body string
+	for i := 0; i < 100 && body == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if resp, err := client.Get("http://unix/name"); err == nil {
+			b, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			body = string(b)
+		}
+	}
+	assert.Equal(t, "\"unix\"\n", body)
+
+	err = stopServer(t, srv, stopChan)
+	assert.NoError(t, err)
+}
+
+func TestListen_FileDescriptor(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer l.Close()
+
+	f, err := l.(*net.TCPListener).File()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+
+	// Listen take the ownership of the file descriptor
+	fd, err := syscall.Dup(int(f.Fd()))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	inherited, err := fdhttp.Listen("fd:" + strconv.Itoa(fd))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer inherited.Close()
+
+	assert.Equal(t, l.Addr().String(), inherited.Addr().String())
+
+	_, err = fdhttp.Listen("fd:abc")
+	assert.Error(t, err)
+}
+
+func TestListen_WithoutSystemd(t *testing.T) {
+	_, err := fdhttp.Listen("systemd:http")
+	assert.Equal(t, fdhttp.ErrNoSystemdListener, err)
+}
+
+func TestServer_StopAllListenersWhenShutdownFails(t *testing.T) {
+	api, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	admin, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	r := fdhttp.NewRouter()
+	r.GET("/slow", func(ctx context.Context) (int, interface{}) {
+		<-release
+		return http.StatusOK, nil
+	})
+
+	srv, stopChan := startServer("")
+	srv.AddListener(admin, newNamedRouter("admin"))
+	go func() {
+		srv.Serve(api, r)
+		stopChan <- struct{}{}
+	}()
+
+	go http.Get("http://" + api.Addr().String() + "/slow")
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, srv.Stop(ctx))
+
+	// admin is not serving anymore, even though api failed to shutdown
+	_, err = net.Dial("tcp", admin.Addr().String())
+	assert.Error(t, err)
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"reflect"
 	"strings"
 
 	"github.com/foodora/go-ranger/fdhttp/fdmiddleware"
@@ -24,6 +25,9 @@ type Router struct {
 	PanicHandler func(http.ResponseWriter, *http.Request, interface{})
 	// Prefix will be added in all routes
 	Prefix string
+	// Encoders used to send responses of fdhttp.EndpointFunc based on Accept header,
+	// by default fdhttp.DefaultEncoders. It's only used in the main router.
+	Encoders *Encoders
 
 	httprouter *httprouter.Router
 	parent     *Router
@@ -102,6 +106,18 @@ func (r *Router) wrapMiddlewares(h http.Handler) http.Handler {
 	return h
 }
 
+// encoders return the encoders of the main router.
+func (r *Router) encoders() *Encoders {
+	for r.parent != nil {
+		r = r.parent
+	}
+
+	if r.Encoders == nil {
+		return DefaultEncoders
+	}
+	return r.Encoders
+}
+
 func (r *Router) SubRouter() *Router {
 	subrouter := &Router{
 		parent:     r,
@@ -152,8 +168,16 @@ func (r *Router) StdHandler(method, path string, handler http.HandlerFunc) *Endp
 		return r.parent.StdHandler(method, r.Prefix+path, r.wrapMiddlewares(handler).ServeHTTP)
 	}
 
+	e := &Endpoint{
+		router: r,
+		doc:    &EndpointDoc{},
+		Method: method,
+		Path:   r.Prefix + path,
+	}
+
 	r.httprouter.Handle(method, r.Prefix+path, func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 		ctx := req.Context()
+		setMatchedEndpoint(ctx, e)
 		ctx = SetRouteParams(ctx, convertParams(ps))
 
 		ctx, err := injectRequestBody(ctx, req)
@@ -169,11 +193,6 @@ func (r *Router) StdHandler(method, path string, handler http.HandlerFunc) *Endp
 		// Handler is responsible to send Header, StatusCode and Body
 	})
 
-	e := &Endpoint{
-		router: r,
-		Method: method,
-		Path:   r.Prefix + path,
-	}
 	r.addEndpoint(e)
 
 	return e
@@ -226,7 +245,16 @@ func (r *Router) Handler(method, path string, fn EndpointFunc) *Endpoint {
 	}
 	prefix = append(prefix, r.Prefix)
 
+	e := &Endpoint{
+		router: r,
+		doc:    &EndpointDoc{},
+		Method: method,
+		Path:   r.Prefix + path,
+	}
+
 	r.httprouter.Handle(method, strings.Join(prefix, "")+path, func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		setMatchedEndpoint(req.Context(), e)
+
 		var handler http.Handler
 
 		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -241,8 +269,30 @@ func (r *Router) Handler(method, path string, fn EndpointFunc) *Endpoint {
 				})
 			}
 
-			// call user handler
-			statusCode, resp := fn(ctx)
+			var (
+				statusCode int
+				resp       interface{}
+			)
+
+			encoders := r.encoders()
+			enc, acceptable := encoders.Negotiate(req.Header.Get("Accept"))
+			if !acceptable {
+				// handler is not called because we cannot send its response
+				enc = encoders.Default()
+				statusCode, resp = http.StatusNotAcceptable, encoders.notAcceptableError()
+			} else if e.binding != nil {
+				v := reflect.New(e.binding).Interface()
+				if err := Bind(ctx, v); err != nil {
+					statusCode, resp = http.StatusBadRequest, err
+				} else {
+					ctx = SetRequestBinding(ctx, v)
+				}
+			}
+
+			if resp == nil {
+				// call user handler
+				statusCode, resp = fn(ctx)
+			}
 			if respErr, ok := resp.(*Error); ok {
 				ctx = SetResponseError(ctx, respErr)
 			} else if _, ok := resp.(JSONer); ok {
@@ -266,7 +316,10 @@ func (r *Router) Handler(method, path string, fn EndpointFunc) *Endpoint {
 				w.WriteHeader(statusCode)
 				io.Copy(w, r)
 			} else {
-				ResponseJSON(w, statusCode, resp)
+				if encoders.Len() > 1 {
+					w.Header().Add("Vary", "Accept")
+				}
+				ResponseEncoded(w, enc, statusCode, resp)
 			}
 		})
 
@@ -280,11 +333,6 @@ func (r *Router) Handler(method, path string, fn EndpointFunc) *Endpoint {
 		handler.ServeHTTP(w, req)
 	})
 
-	e := &Endpoint{
-		router: r,
-		Method: method,
-		Path:   r.Prefix + path,
-	}
 	r.addEndpoint(e)
 
 	return e
@@ -335,8 +383,13 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	ctx = SetRequest(ctx, req)
 	ctx = SetRequestHeader(ctx, req.Header)
 
+	if peer := peerIdentity(req.TLS); peer != nil {
+		ctx = SetPeer(ctx, peer)
+	}
+
 	ctx = SetResponse(ctx, w)
 	ctx = SetResponseHeader(ctx, w.Header())
+	ctx = context.WithValue(ctx, EndpointContextKey, &endpointMatch{})
 
 	// Inject Form and PostForm
 	if req.Form == nil {
@@ -2,6 +2,7 @@ package fdhttp
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"net"
 	"net/http"
@@ -19,8 +20,14 @@ type (
 		runLock  sync.Mutex
 		stopLock sync.Mutex
 		router   *Router
+		listener net.Listener
+		bindings []*binding
+		handlers sync.WaitGroup
 		HTTPSrv  *http.Server
 
+		// TLS enable HTTPS in all addresses when it's not nil, check fdhttp.NewTLSConfig.
+		TLS *TLSConfig
+
 		// Logger will be setted with DefaultLogger when NewServer is called
 		// but you can overwrite later only in this instance.
 		Logger Logger
@@ -42,7 +49,8 @@ var (
 )
 
 // NewServer return a new server instance and will be run in the address informed.
-// Address can be "0.0.0.0:8080", ":8080" or just the port "8080".
+// Address can be "0.0.0.0:8080", ":8080" or just the port "8080", check fdhttp.Listen
+// to use unix sockets or socket activation.
 func NewServer(addr string) *Server {
 	// Default timeouts to  prevent unclosed requests leaking memory.
 	// https://blog.cloudflare.com/exposing-go-on-the-internet/#timeouts
@@ -70,9 +78,63 @@ func checkAddr(addr string) (string, error) {
 	return net.JoinHostPort(host, port), nil
 }
 
+// binding is a router served on a listener, a server can have many of them.
+type binding struct {
+	addr     string
+	listener net.Listener
+	router   *Router
+	srv      *http.Server
+}
+
+// AddAddr serve another router in a different address when server starts, like
+// an admin router in an internal port. Address can be anything accepted by fdhttp.Listen.
+func (s *Server) AddAddr(addr string, r *Router) *Server {
+	s.runLock.Lock()
+	defer s.runLock.Unlock()
+
+	s.bindings = append(s.bindings, &binding{addr: addr, router: r})
+	return s
+}
+
+// AddListener serve another router in a listener when server starts,
+// check Server.AddAddr.
+func (s *Server) AddListener(l net.Listener, r *Router) *Server {
+	s.runLock.Lock()
+	defer s.runLock.Unlock()
+
+	s.bindings = append(s.bindings, &binding{listener: l, router: r})
+	return s
+}
+
+// Addr return the address that server is listening, it's useful when
+// port 0 is used to choose any port available.
+func (s *Server) Addr() net.Addr {
+	s.runLock.Lock()
+	defer s.runLock.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
 // Start the server and block until another go rotine call Stop()
 // or return imediatily in case is not possible to start the server.
 func (s *Server) Start(r *Router) error {
+	return s.serve(&binding{addr: s.addr, router: r})
+}
+
+// Serve is like Start, but using a listener created by the caller, like:
+//
+//  l, _ := net.Listen("tcp", "127.0.0.1:0")
+//  go srv.Serve(l, router)
+//
+// Listeners are closed when server stops.
+func (s *Server) Serve(l net.Listener, r *Router) error {
+	return s.serve(&binding{listener: l, router: r})
+}
+
+func (s *Server) serve(primary *binding) error {
 	if atomic.LoadUint32(&s.running) == 1 {
 		return ErrServerAlreadyRunning
 	}
@@ -85,52 +147,132 @@ func (s *Server) Start(r *Router) error {
 		return ErrServerAlreadyRunning
 	}
 
-	var err error
+	bindings := append([]*binding{primary}, s.bindings...)
 
-	s.addr, err = checkAddr(s.addr)
+	err := s.listen(bindings)
 	if err != nil {
 		s.runLock.Unlock()
 		return err
 	}
 
-	s.Logger.Printf("Running http server on %s...", s.addr)
+	s.listener = primary.listener
+	s.router = primary.router
+	s.HTTPSrv = primary.srv
 
-	s.HTTPSrv = &http.Server{
-		Addr:         s.addr,
-		ReadTimeout:  s.ReadTimeout,
-		WriteTimeout: s.WriteTimeout,
-		IdleTimeout:  s.IdleTimeout,
-	}
+	errChan := make(chan error, len(bindings))
 
-	if r != nil {
-		if r.parent != nil {
-			panic("Unable to start server with a sub router")
-		}
-
-		s.router = r
-		s.router.Init()
-		s.HTTPSrv.Handler = s.router
+	for _, b := range bindings {
+		go func(b *binding) {
+			if b.srv.TLSConfig != nil {
+				// certificates are already in TLSConfig
+				errChan <- b.srv.ServeTLS(b.listener, "", "")
+				return
+			}
+			errChan <- b.srv.Serve(b.listener)
+		}(b)
 	}
 
-	errChan := make(chan error)
-
-	go func() {
-		errChan <- s.HTTPSrv.ListenAndServe()
-	}()
-
 	atomic.StoreUint32(&s.running, 1)
 	s.runLock.Unlock()
 
 	err = <-errChan
 	if err == http.ErrServerClosed {
-		err = ErrServerStopped
+		return ErrServerStopped
+	}
+
+	// one listener failed, the others cannot keep running
+	for _, b := range bindings {
+		b.srv.Close()
 	}
+	atomic.StoreUint32(&s.running, 0)
 
 	return err
 }
 
-// Stop the server. Return nil in case of success, but can fail due
-// take so long to shutdown. In case of success Start() will return
+// listen open listeners and create a http.Server for each binding. In case of
+// error all listeners are closed.
+func (s *Server) listen(bindings []*binding) (err error) {
+	defer func() {
+		if err != nil {
+			for _, b := range bindings {
+				if b.listener != nil {
+					b.listener.Close()
+				}
+			}
+		}
+	}()
+
+	var tlsConfig *tls.Config
+	if s.TLS != nil {
+		tlsConfig, err = s.TLS.Config()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, b := range bindings {
+		// listeners from addresses are opened every time that server starts
+		if b.addr != "" || b.listener == nil {
+			b.listener, err = Listen(b.addr)
+			if err != nil {
+				return err
+			}
+		}
+
+		b.srv = &http.Server{
+			Addr:         b.listener.Addr().String(),
+			ReadTimeout:  s.ReadTimeout,
+			WriteTimeout: s.WriteTimeout,
+			IdleTimeout:  s.IdleTimeout,
+			TLSConfig:    tlsConfig,
+		}
+
+		if b.router != nil {
+			if b.router.parent != nil {
+				panic("Unable to start server with a sub router")
+			}
+
+			b.router.Init()
+			b.srv.Handler = b.router
+		}
+
+		b.srv.Handler = s.withShutdown(b.srv, b.srv.Handler)
+
+		if tlsConfig != nil {
+			s.Logger.Printf("Running https server on %s...", b.srv.Addr)
+		} else {
+			s.Logger.Printf("Running http server on %s...", b.srv.Addr)
+		}
+	}
+
+	return nil
+}
+
+// withShutdown inject into request context a channel that is closed
+// when server is stopping, check fdhttp.ServerShutdown.
+func (s *Server) withShutdown(srv *http.Server, h http.Handler) http.Handler {
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+
+	shutdown := make(chan struct{})
+	srv.RegisterOnShutdown(func() {
+		close(shutdown)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// http.Server doesn't wait for hijacked connections, like websockets
+		s.handlers.Add(1)
+		defer s.handlers.Done()
+
+		ctx := SetServerShutdown(req.Context(), shutdown)
+		h.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// Stop the server and all its addresses. Return nil in case of success, but
+// can fail due take so long to shutdown, the first error is returned. Event streams and websockets are notified and
+// Stop waits until their handlers return. In case of success Start() will return
 // fdhttp.ErrServerStopped
 func (s *Server) Stop(ctx context.Context) error {
 	if atomic.LoadUint32(&s.running) == 0 {
@@ -147,5 +289,41 @@ func (s *Server) Stop(ctx context.Context) error {
 
 	s.Logger.Printf("Stopping http server...")
 
-	return s.HTTPSrv.Shutdown(ctx)
+	// every server is shut down, even if a previous one failed
+	var shutdownErr error
+	for _, srv := range s.httpServers() {
+		if err := srv.Shutdown(ctx); err != nil && shutdownErr == nil {
+			shutdownErr = err
+		}
+	}
+	if shutdownErr != nil {
+		return shutdownErr
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.handlers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) httpServers() []*http.Server {
+	s.runLock.Lock()
+	defer s.runLock.Unlock()
+
+	servers := []*http.Server{s.HTTPSrv}
+	for _, b := range s.bindings {
+		// bindings added after start are not running
+		if b.srv != nil {
+			servers = append(servers, b.srv)
+		}
+	}
+	return servers
 }
@@ -0,0 +1,223 @@
+package fdhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventStreamHeartbeat is the interval that a comment is sent to clients
+// to keep the connection open when no event was sent, 0 disable it.
+var EventStreamHeartbeat = 15 * time.Second
+
+// ErrStreamClosed is returned by EventSink.Send when client is gone or
+// server is stopping.
+var ErrStreamClosed = errors.New("fdhttp: event stream closed")
+
+// Event is a message sent to clients using Server-Sent Events.
+type Event struct {
+	// ID is sent back by clients as Last-Event-ID when they reconnect.
+	ID string
+	// Event is the name of the event, clients receive "message" when empty.
+	Event string
+	// Data is sent as it is when it's a string or []byte, otherwise it's encoded as JSON.
+	Data interface{}
+	// Retry tell clients how long they should wait before reconnect.
+	Retry time.Duration
+}
+
+// EventSink send events to a client connected to an event stream.
+type EventSink interface {
+	// Send write the event and flush it to the client.
+	Send(Event) error
+	// LastEventID is the last event received by client before reconnect,
+	// you should send only events that happened after that.
+	LastEventID() string
+}
+
+// StreamFunc is the method signature to stream events to clients. The context
+// is canceled when client disconnects or Server.Stop() is called and the stream
+// is closed when function returns.
+type StreamFunc func(ctx context.Context, sink EventSink) error
+
+// EventStream register a GET endpoint that send Server-Sent Events to clients.
+//
+//  router.EventStream("/v1/orders/:id/status", func(ctx context.Context, sink fdhttp.EventSink) error {
+//      for {
+//          select {
+//          case <-ctx.Done():
+//              return nil
+//          case status := <-updates:
+//              if err := sink.Send(fdhttp.Event{ID: status.ID, Data: status}); err != nil {
+//                  return err
+//              }
+//          }
+//      }
+//  })
+func (r *Router) EventStream(path string, fn StreamFunc) *Endpoint {
+	return r.StdGET(path, eventStreamHandler(fn))
+}
+
+func eventStreamHandler(fn StreamFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			ResponseJSON(w, http.StatusInternalServerError, &Error{
+				Code:    "streaming_unsupported",
+				Message: "Response writer doesn't support flush",
+			})
+			return
+		}
+
+		DisableWriteTimeout(w)
+
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-ServerShutdown(ctx):
+				cancel()
+			}
+		}()
+
+		lastEventID := req.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			// some polyfills cannot send headers
+			lastEventID = req.URL.Query().Get("lastEventId")
+		}
+
+		sink := &eventSink{
+			ctx:         ctx,
+			cancel:      cancel,
+			w:           w,
+			flusher:     flusher,
+			lastEventID: lastEventID,
+		}
+
+		header := w.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		// disable buffering in nginx
+		header.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		if EventStreamHeartbeat > 0 {
+			go sink.heartbeat(EventStreamHeartbeat)
+		}
+
+		err := fn(ctx, sink)
+		if err != nil && err != ErrStreamClosed && ctx.Err() == nil {
+			defaultLogger.Printf("Event stream %s finished with error: %s", req.URL.Path, err)
+		}
+
+		sink.close()
+	}
+}
+
+type eventSink struct {
+	mu          sync.Mutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+	w           io.Writer
+	flusher     http.Flusher
+	lastEventID string
+	closed      bool
+}
+
+func (s *eventSink) LastEventID() string {
+	return s.lastEventID
+}
+
+func (s *eventSink) Send(e Event) error {
+	var b strings.Builder
+
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", singleLine(e.ID))
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", singleLine(e.Event))
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", e.Retry/time.Millisecond)
+	}
+
+	var data string
+	switch d := e.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		buf, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		data = string(buf)
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", strings.TrimSuffix(line, "\r"))
+	}
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+func (s *eventSink) write(msg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || s.ctx.Err() != nil {
+		return ErrStreamClosed
+	}
+
+	if _, err := io.WriteString(s.w, msg); err != nil {
+		// client is gone
+		s.cancel()
+		return ErrStreamClosed
+	}
+	s.flusher.Flush()
+
+	return nil
+}
+
+// heartbeat send comments that are ignored by clients, but it keeps
+// proxies from closing idle connections and detect clients that are gone.
+func (s *eventSink) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.write(": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// close prevent writes after handler returns, http.ResponseWriter cannot
+// be used after that.
+func (s *eventSink) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,11 @@
+// +build !go1.20
+
+package fdhttp
+
+import (
+	"net/http"
+)
+
+// DisableWriteTimeout is not possible before go1.20, so Server.WriteTimeout
+// must be 0 to use long-lived streams.
+func DisableWriteTimeout(w http.ResponseWriter) {}
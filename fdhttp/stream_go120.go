@@ -0,0 +1,16 @@
+// +build go1.20
+
+package fdhttp
+
+import (
+	"net/http"
+	"time"
+)
+
+// DisableWriteTimeout remove the write deadline set by Server.WriteTimeout,
+// otherwise long-lived streams are closed after that.
+func DisableWriteTimeout(w http.ResponseWriter) {
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		defaultLogger.Printf("Unable to disable write timeout: %s", err)
+	}
+}
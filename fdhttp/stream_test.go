@@ -0,0 +1,160 @@
+package fdhttp_test
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdhttp/fdmiddleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func readEvent(r *bufio.Reader) (string, error) {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line == "\n" {
+			return strings.Join(lines, ""), nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestRouter_EventStream(t *testing.T) {
+	logMiddleware := fdmiddleware.NewLogMiddleware()
+	logMiddleware.SetLogger(log.New(ioutil.Discard, "", 0))
+
+	r := fdhttp.NewRouter()
+	r.Use(logMiddleware)
+	r.EventStream("/events", func(ctx context.Context, sink fdhttp.EventSink) error {
+		assert.Equal(t, "41", sink.LastEventID())
+
+		err := sink.Send(fdhttp.Event{ID: "42", Event: "status", Data: map[string]string{"status": "delivered"}})
+		assert.NoError(t, err)
+
+		err = sink.Send(fdhttp.Event{Data: "line 1\nline 2", Retry: time.Second})
+		assert.NoError(t, err)
+
+		<-ctx.Done()
+		return nil
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", "41")
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+	body := bufio.NewReader(resp.Body)
+
+	event, err := readEvent(body)
+	assert.NoError(t, err)
+	assert.Equal(t, "id: 42\nevent: status\ndata: {\"status\":\"delivered\"}\n", event)
+
+	event, err = readEvent(body)
+	assert.NoError(t, err)
+	assert.Equal(t, "retry: 1000\ndata: line 1\ndata: line 2\n", event)
+}
+
+func TestRouter_EventStreamHeartbeat(t *testing.T) {
+	defaultHeartbeat := fdhttp.EventStreamHeartbeat
+	defer func() {
+		fdhttp.EventStreamHeartbeat = defaultHeartbeat
+	}()
+	fdhttp.EventStreamHeartbeat = 10 * time.Millisecond
+
+	r := fdhttp.NewRouter()
+	r.EventStream("/events", func(ctx context.Context, sink fdhttp.EventSink) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	event, err := readEvent(bufio.NewReader(resp.Body))
+	assert.NoError(t, err)
+	assert.Equal(t, ": heartbeat\n", event)
+}
+
+func TestRouter_EventStreamClientDisconnect(t *testing.T) {
+	finished := make(chan struct{})
+
+	r := fdhttp.NewRouter()
+	r.EventStream("/events", func(ctx context.Context, sink fdhttp.EventSink) error {
+		<-ctx.Done()
+		close(finished)
+		return nil
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	resp.Body.Close()
+
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		t.Error("Stream was not closed after client disconnect")
+	}
+}
+
+func TestServer_StopClosesEventStreams(t *testing.T) {
+	r := fdhttp.NewRouter()
+	r.EventStream("/events", func(ctx context.Context, sink fdhttp.EventSink) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	srv, stopChan := startServer("")
+	go func() {
+		srv.Serve(l, r)
+		stopChan <- struct{}{}
+	}()
+
+	resp, err := http.Get("http://" + l.Addr().String() + "/events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	// server wait for the stream to finish
+	err = stopServer(t, srv, stopChan)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,222 @@
+package fdhttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSConfig configure HTTPS in fdhttp.Server. When ClientCAFile is informed
+// clients need to send a certificate signed by one of those CAs (mTLS) and
+// their identity is available using fdhttp.Peer(ctx).
+type TLSConfig struct {
+	// CertFile and KeyFile are PEM encoded files with the certificate
+	// and private key of the server.
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile is a PEM bundle with CAs used to verify client certificates.
+	ClientCAFile string
+	// ClientAuth is the policy to verify client certificates, by default
+	// tls.RequireAndVerifyClientCert when ClientCAFile is informed.
+	ClientAuth tls.ClientAuthType
+
+	// ReloadInterval is the interval to check if files changed, that way rotated
+	// certificates are used without restart the server. 0 disable it.
+	ReloadInterval time.Duration
+
+	// MinVersion is the minimum TLS version accepted, by default TLS 1.2.
+	MinVersion uint16
+}
+
+// DefaultTLSReloadInterval is used as TLSConfig.ReloadInterval by NewTLSConfig.
+var DefaultTLSReloadInterval = time.Minute
+
+// NewTLSConfig create a config with the certificate and key of the server.
+func NewTLSConfig(certFile, keyFile string) *TLSConfig {
+	return &TLSConfig{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ReloadInterval: DefaultTLSReloadInterval,
+	}
+}
+
+// Config load files and return a *tls.Config that can be used by http.Server,
+// certificates are reloaded during handshakes when files change.
+func (c *TLSConfig) Config() (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("fdhttp: tls cert and key files are required")
+	}
+
+	r := &tlsReloader{config: *c}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion:     c.MinVersion,
+		GetCertificate: r.getCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+
+	if c.ClientCAFile != "" {
+		cfg.ClientAuth = c.ClientAuth
+		if cfg.ClientAuth == tls.NoClientCert {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		cfg.ClientCAs = r.clientCAs
+
+		// ClientCAs cannot be changed after the server started,
+		// so each handshake receive a config with the current CAs
+		template := cfg.Clone()
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			clientCfg := template.Clone()
+			clientCfg.ClientCAs = r.getClientCAs()
+			return clientCfg, nil
+		}
+	}
+
+	return cfg, nil
+}
+
+type tlsReloader struct {
+	config TLSConfig
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+}
+
+func (r *tlsReloader) files() []string {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		files = append(files, r.config.ClientCAFile)
+	}
+	return files
+}
+
+// load read all files, caller must hold mu when server is running.
+func (r *tlsReloader) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		modTimes[f] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if r.config.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return err
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("fdhttp: no certificates found in %s", r.config.ClientCAFile)
+		}
+	}
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	r.lastCheck = time.Now()
+
+	return nil
+}
+
+// reloadIfChanged check files at most once per ReloadInterval, in case of
+// error the previous certificates are kept.
+func (r *tlsReloader) reloadIfChanged() {
+	if r.config.ReloadInterval <= 0 || time.Since(r.lastCheck) < r.config.ReloadInterval {
+		return
+	}
+	r.lastCheck = time.Now()
+
+	changed := false
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			defaultLogger.Printf("Unable to check tls file %s: %s", f, err)
+			return
+		}
+		if !info.ModTime().Equal(r.modTimes[f]) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	if err := r.load(); err != nil {
+		defaultLogger.Printf("Unable to reload tls certificates: %s", err)
+		return
+	}
+	defaultLogger.Printf("TLS certificates reloaded")
+}
+
+func (r *tlsReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reloadIfChanged()
+	return r.cert, nil
+}
+
+func (r *tlsReloader) getClientCAs() *x509.CertPool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reloadIfChanged()
+	return r.clientCAs
+}
+
+// PeerIdentity is the identity of a client verified using mTLS.
+type PeerIdentity struct {
+	CommonName   string
+	Organization []string
+	DNSNames     []string
+	// URIs usually contain SPIFFE IDs, like spiffe://cluster.local/ns/default/sa/orders.
+	URIs        []string
+	Certificate *x509.Certificate
+}
+
+// peerIdentity return the identity of the client only if its certificate was verified.
+func peerIdentity(state *tls.ConnectionState) *PeerIdentity {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	cert := state.VerifiedChains[0][0]
+
+	uris := make([]string, len(cert.URIs))
+	for i, u := range cert.URIs {
+		uris[i] = u.String()
+	}
+
+	return &PeerIdentity{
+		CommonName:   cert.Subject.CommonName,
+		Organization: cert.Subject.Organization,
+		DNSNames:     cert.DNSNames,
+		URIs:         uris,
+		Certificate:  cert,
+	}
+}
@@ -0,0 +1,191 @@
+package fdhttp_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, serial int64, cn string, parent *testCert, isCA bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spiffe, _ := url.Parse("spiffe://cluster.local/ns/default/sa/" + cn)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: cn, Organization: []string{"foodora"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		URIs:                  []*url.URL{spiffe},
+	}
+
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+	if err := ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if keyFile == "" {
+		return
+	}
+
+	der, _ := x509.MarshalECPrivateKey(c.key)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{
+		Certificate: [][]byte{c.cert.Raw},
+		PrivateKey:  c.key,
+	}
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fdhttp-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, 1, "ca", nil, true)
+	server := newTestCert(t, 2, "server", ca, false)
+	client := newTestCert(t, 3, "orders", ca, false)
+
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	caFile := filepath.Join(dir, "ca.crt")
+	server.write(t, certFile, keyFile)
+	ca.write(t, caFile, "")
+
+	r := fdhttp.NewRouter()
+	r.GET("/whoami", func(ctx context.Context) (int, interface{}) {
+		peer := fdhttp.Peer(ctx)
+		if peer == nil {
+			return http.StatusUnauthorized, nil
+		}
+		return http.StatusOK, map[string]interface{}{
+			"cn":   peer.CommonName,
+			"uris": peer.URIs,
+		}
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	url := "https://" + l.Addr().String() + "/whoami"
+
+	srv, stopChan := startServer("")
+	srv.TLS = fdhttp.NewTLSConfig(certFile, keyFile)
+	srv.TLS.ClientCAFile = caFile
+	srv.TLS.ReloadInterval = 10 * time.Millisecond
+	go func() {
+		srv.Serve(l, r)
+		stopChan <- struct{}{}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs:      roots,
+					Certificates: certs,
+				},
+				DisableKeepAlives: true,
+			},
+		}
+	}
+
+	resp, err := newClient(client.tlsCertificate()).Get(url)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var body struct {
+		CN   string   `json:"cn"`
+		URIs []string `json:"uris"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "orders", body.CN)
+	assert.Equal(t, []string{"spiffe://cluster.local/ns/default/sa/orders"}, body.URIs)
+	assert.Equal(t, big.NewInt(2), resp.TLS.PeerCertificates[0].SerialNumber)
+
+	// clients without certificate are rejected during handshake
+	_, err = newClient().Get(url)
+	assert.Error(t, err)
+
+	// rotated certificate is used in the next connections
+	rotated := newTestCert(t, 4, "server", ca, false)
+	rotated.write(t, certFile, keyFile)
+	time.Sleep(20 * time.Millisecond)
+
+	resp, err = newClient(client.tlsCertificate()).Get(url)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, big.NewInt(4), resp.TLS.PeerCertificates[0].SerialNumber)
+	}
+
+	err = stopServer(t, srv, stopChan)
+	assert.NoError(t, err)
+}
+
+func TestTLSConfig_InvalidFiles(t *testing.T) {
+	_, err := fdhttp.NewTLSConfig("", "").Config()
+	assert.Error(t, err)
+
+	_, err = fdhttp.NewTLSConfig("not-found.crt", "not-found.key").Config()
+	assert.Error(t, err)
+}
@@ -0,0 +1,321 @@
+package fdhttp
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ValidationFailedCode is the code of fdhttp.Error returned when
+// the request doesn't follow the rules declared with validate tag.
+const ValidationFailedCode = "validation_failed"
+
+// FieldError is sent as Detail of fdhttp.Error for each field that
+// didn't pass the validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// rule is a single validation parsed from the validate tag.
+type rule struct {
+	name  string
+	arg   string
+	num   float64
+	regex *regexp.Regexp
+	enum  []string
+}
+
+// fieldInfo keeps everything that we need to bind and validate a struct field.
+type fieldInfo struct {
+	index  []int
+	name   string
+	source string
+	key    string
+	rules  []rule
+	// body is true if this field is decoded from the JSON body
+	body bool
+}
+
+// structInfo is cached per type, parse tags for each request is expensive.
+var structInfo sync.Map
+
+func structFields(t reflect.Type) []fieldInfo {
+	if fields, ok := structInfo.Load(t); ok {
+		return fields.([]fieldInfo)
+	}
+
+	var fields []fieldInfo
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		jsonName, _ := splitTag(f.Tag.Get("json"))
+
+		if f.Anonymous && f.PkgPath == "" && jsonName == "" && f.Type.Kind() == reflect.Struct {
+			// fields of embedded structs are promoted
+			for _, ef := range structFields(f.Type) {
+				ef.index = append([]int{i}, ef.index...)
+				fields = append(fields, ef)
+			}
+			continue
+		}
+
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		fi := fieldInfo{
+			index: []int{i},
+			name:  f.Name,
+			rules: parseRules(f.Tag.Get("validate")),
+		}
+
+		for _, source := range bindSources {
+			if key := f.Tag.Get(source); key != "" {
+				fi.source = source
+				fi.key = key
+				fi.name = key
+				break
+			}
+		}
+
+		if fi.source == "" && jsonName != "-" {
+			fi.body = true
+			if jsonName != "" {
+				fi.name = jsonName
+			}
+		}
+
+		fields = append(fields, fi)
+	}
+
+	structInfo.Store(t, fields)
+	return fields
+}
+
+func splitTag(tag string) (string, string) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+// parseRules parse the validate tag, invalid rules panics because it's
+// a developer mistake. Regex rule must be the last one because it can
+// contain commas.
+func parseRules(tag string) []rule {
+	var rules []rule
+
+	for tag != "" {
+		var def string
+		if strings.HasPrefix(tag, "regex=") {
+			def, tag = tag, ""
+		} else {
+			def, tag = splitTag(tag)
+		}
+
+		r := rule{name: def}
+		if i := strings.Index(def, "="); i >= 0 {
+			r.name, r.arg = def[:i], def[i+1:]
+		}
+
+		switch r.name {
+		case "required":
+		case "min", "max":
+			num, err := strconv.ParseFloat(r.arg, 64)
+			if err != nil {
+				panic(fmt.Sprintf("fdhttp: invalid validation rule '%s': %s", def, err))
+			}
+			r.num = num
+		case "regex":
+			r.regex = regexp.MustCompile(r.arg)
+		case "enum":
+			r.enum = strings.Split(r.arg, "|")
+		default:
+			panic(fmt.Sprintf("fdhttp: unknown validation rule '%s'", def))
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules
+}
+
+// Validate check v against the rules declared in the validate tag of each field.
+// v need to be a struct or a pointer to struct. Nested structs, slices and maps
+// are also validated. The available rules are:
+//
+//  required      value cannot be empty (zero value or nil)
+//  min=N         minimum value of numbers or minimum length of strings, slices and maps
+//  max=N         maximum value of numbers or maximum length of strings, slices and maps
+//  enum=a|b|c    value must be one of the list
+//  regex=^[a-z]+ value must match the regular expression, it must be the last rule
+//
+// Example:
+//
+//  type Person struct {
+//      Name   string `json:"name" validate:"required,max=50"`
+//      Age    int    `json:"age" validate:"min=18"`
+//      Gender string `json:"gender" validate:"enum=female|male|other"`
+//      Email  string `json:"email" validate:"required,regex=^.+@.+$"`
+//  }
+//
+// It returns a *fdhttp.Error with ValidationFailedCode and a list of
+// fdhttp.FieldError as Detail.
+func Validate(v interface{}) error {
+	var errs []FieldError
+	validateValue(reflect.ValueOf(v), "", &errs)
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &Error{
+		Code:    ValidationFailedCode,
+		Message: "Request is invalid, check detail for more information",
+		Detail:  errs,
+	}
+}
+
+func validateValue(v reflect.Value, path string, errs *[]FieldError) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, f := range structFields(v.Type()) {
+			fv := v.FieldByIndex(f.index)
+			name := joinPath(path, f.name)
+
+			for _, r := range f.rules {
+				if msg := r.check(fv); msg != "" {
+					*errs = append(*errs, FieldError{
+						Field:   name,
+						Rule:    r.name,
+						Message: msg,
+					})
+					// other rules doesn't make sense with a missing value
+					if r.name == "required" {
+						break
+					}
+				}
+			}
+
+			validateValue(fv, name, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			validateValue(v.MapIndex(k), fmt.Sprintf("%s[%v]", path, k.Interface()), errs)
+		}
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// check return an error message in case the value doesn't follow the rule.
+func (r rule) check(v reflect.Value) string {
+	if r.name == "required" {
+		if isEmptyValue(v) {
+			return "is required"
+		}
+		return ""
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			// only required rule check nil values
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch r.name {
+	case "min", "max":
+		n, isLen, ok := numberOf(v)
+		if !ok {
+			return ""
+		}
+
+		if r.name == "min" && n < r.num {
+			if isLen {
+				return fmt.Sprintf("length must be at least %s", r.arg)
+			}
+			return fmt.Sprintf("must be at least %s", r.arg)
+		}
+		if r.name == "max" && n > r.num {
+			if isLen {
+				return fmt.Sprintf("length must be at most %s", r.arg)
+			}
+			return fmt.Sprintf("must be at most %s", r.arg)
+		}
+	case "regex":
+		if v.Kind() == reflect.String && !r.regex.MatchString(v.String()) {
+			return fmt.Sprintf("must match %s", r.arg)
+		}
+	case "enum":
+		if isEmptyValue(v) {
+			// use required to not accept empty values
+			return ""
+		}
+
+		s := fmt.Sprint(v.Interface())
+		for _, e := range r.enum {
+			if s == e {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(r.enum, ", "))
+	}
+
+	return ""
+}
+
+// numberOf return the value of numbers or the length of strings,
+// slices and maps.
+func numberOf(v reflect.Value) (n float64, isLen bool, ok bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, true
+	case reflect.String:
+		return float64(len([]rune(v.String()))), true, true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true, true
+	}
+
+	return 0, false, false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Invalid:
+		return true
+	}
+
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
@@ -0,0 +1,87 @@
+package fdhttp_test
+
+import (
+	"testing"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+type validationAddress struct {
+	Street string `json:"street" validate:"required"`
+	Zip    string `json:"zip" validate:"regex=^[0-9]{5}$"`
+}
+
+type validationPerson struct {
+	Name      string              `json:"name" validate:"required,min=2,max=10"`
+	Age       int                 `json:"age" validate:"min=18,max=120"`
+	Gender    string              `json:"gender,omitempty" validate:"enum=female|male|other"`
+	Nickname  *string             `json:"nickname" validate:"min=3"`
+	Tags      []string            `json:"tags" validate:"max=2"`
+	Addresses []validationAddress `json:"addresses" validate:"required"`
+}
+
+func TestValidate_ValidValue(t *testing.T) {
+	nickname := "johnny"
+	p := &validationPerson{
+		Name:     "John",
+		Age:      30,
+		Gender:   "male",
+		Nickname: &nickname,
+		Tags:     []string{"a"},
+		Addresses: []validationAddress{
+			{Street: "Oranienstraße", Zip: "10999"},
+		},
+	}
+
+	assert.NoError(t, fdhttp.Validate(p))
+}
+
+func TestValidate_InvalidValue(t *testing.T) {
+	nickname := "jo"
+	p := validationPerson{
+		Age:      12,
+		Gender:   "unknown",
+		Nickname: &nickname,
+		Tags:     []string{"a", "b", "c"},
+		Addresses: []validationAddress{
+			{Street: "Oranienstraße", Zip: "10999"},
+			{Zip: "1099"},
+		},
+	}
+
+	err := fdhttp.Validate(p)
+	if !assert.Error(t, err) {
+		return
+	}
+
+	respErr, ok := err.(*fdhttp.Error)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	assert.Equal(t, fdhttp.ValidationFailedCode, respErr.Code)
+	assert.Equal(t, []fdhttp.FieldError{
+		{Field: "name", Rule: "required", Message: "is required"},
+		{Field: "age", Rule: "min", Message: "must be at least 18"},
+		{Field: "gender", Rule: "enum", Message: "must be one of female, male, other"},
+		{Field: "nickname", Rule: "min", Message: "length must be at least 3"},
+		{Field: "tags", Rule: "max", Message: "length must be at most 2"},
+		{Field: "addresses[1].street", Rule: "required", Message: "is required"},
+		{Field: "addresses[1].zip", Rule: "regex", Message: "must match ^[0-9]{5}$"},
+	}, respErr.Detail)
+}
+
+func TestValidate_InvalidRulePanics(t *testing.T) {
+	assert.Panics(t, func() {
+		fdhttp.Validate(struct {
+			Name string `validate:"unknown"`
+		}{})
+	})
+
+	assert.Panics(t, func() {
+		fdhttp.Validate(struct {
+			Age int `validate:"min=abc"`
+		}{})
+	})
+}
@@ -0,0 +1,547 @@
+package fdhttp
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// WebSocketPingInterval is the interval that server send pings to clients,
+	// connections that didn't answer the previous ping are closed. 0 disable it.
+	WebSocketPingInterval = 30 * time.Second
+
+	// WebSocketReadLimit is the max size in bytes of a message received from clients,
+	// bigger messages close the connection with CloseMessageTooBig.
+	WebSocketReadLimit int64 = 1 << 20
+
+	// WebSocketCheckOrigin is called during the handshake to accept or reject
+	// the request. By default only requests without Origin header or with the
+	// same host are accepted, protecting from cross-site WebSocket hijacking.
+	WebSocketCheckOrigin = sameOrigin
+
+	// WebSocketCloseTimeout is the time given to clients to answer the close
+	// frame sent when server is shutting down, after it reads fail.
+	WebSocketCloseTimeout = 5 * time.Second
+)
+
+// websocketGUID is used to build Sec-WebSocket-Accept, see RFC 6455 section 1.3.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// controlWriteWait is the time given to write control frames.
+const controlWriteWait = 5 * time.Second
+
+// MessageType is the type of data messages sent and received through a WebSocketConn.
+type MessageType int
+
+// Message types defined by RFC 6455.
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// Close codes defined by RFC 6455, see section 7.4.1.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseAbnormal        = 1006
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// ErrWebSocketClosed is returned when writing into a connection already closed.
+var ErrWebSocketClosed = errors.New("fdhttp: websocket closed")
+
+// CloseError is returned by WebSocketConn.ReadMessage when the connection was closed.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("fdhttp: websocket closed with code %d: %s", e.Code, e.Reason)
+}
+
+// WebSocketFunc is the method signature to handle WebSocket connections. The context
+// is canceled when Server.Stop() is called and the connection is closed when
+// function returns, with CloseNormal or CloseInternalError in case of error.
+type WebSocketFunc func(ctx context.Context, conn *WebSocketConn) error
+
+// WebSocket register a GET endpoint that upgrade the connection to WebSocket.
+// Control frames (ping, pong and close) are handled inside of ReadMessage, so the
+// function must keep reading messages while the connection is open.
+//
+//  router.WebSocket("/v1/riders/:id/location", func(ctx context.Context, conn *fdhttp.WebSocketConn) error {
+//      for {
+//          var loc Location
+//          if err := conn.ReadJSON(&loc); err != nil {
+//              return err
+//          }
+//          tracker.Update(fdhttp.RouteParam(ctx, "id"), loc)
+//      }
+//  })
+func (r *Router) WebSocket(path string, fn WebSocketFunc) *Endpoint {
+	return r.StdGET(path, webSocketHandler(fn))
+}
+
+func webSocketHandler(fn WebSocketFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if err := checkHandshake(req); err != nil {
+			w.Header().Set("Sec-WebSocket-Version", "13")
+			ResponseJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if !WebSocketCheckOrigin(req) {
+			ResponseJSON(w, http.StatusForbidden, &Error{
+				Code:    "websocket_origin_not_allowed",
+				Message: "Origin is not allowed to open a websocket",
+			})
+			return
+		}
+
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			ResponseJSON(w, http.StatusInternalServerError, &Error{
+				Code:    "websocket_unsupported",
+				Message: "Response writer doesn't support hijack",
+			})
+			return
+		}
+
+		netConn, brw, err := hijacker.Hijack()
+		if err != nil {
+			defaultLogger.Printf("Unable to hijack connection to %s: %s", req.URL.Path, err)
+			return
+		}
+		// deadlines set by http.Server are not valid anymore
+		netConn.SetDeadline(time.Time{})
+
+		conn := newWebSocketConn(netConn, brw.Reader)
+
+		accept := websocketAccept(req.Header.Get("Sec-WebSocket-Key"))
+		handshake := "HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: websocket\r\n" +
+			"Connection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + accept + "\r\n\r\n"
+
+		netConn.SetWriteDeadline(time.Now().Add(controlWriteWait))
+		if _, err := io.WriteString(netConn, handshake); err != nil {
+			netConn.Close()
+			return
+		}
+		netConn.SetWriteDeadline(time.Time{})
+
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-ServerShutdown(ctx):
+				conn.Close(CloseGoingAway, "server is shutting down")
+				// unblock ReadMessage if client never answers the close frame
+				netConn.SetReadDeadline(time.Now().Add(WebSocketCloseTimeout))
+				cancel()
+			}
+		}()
+
+		if WebSocketPingInterval > 0 {
+			go conn.keepalive(ctx, WebSocketPingInterval)
+		}
+
+		err = fn(ctx, conn)
+
+		code := CloseNormal
+		if err != nil {
+			if _, ok := err.(*CloseError); !ok && err != ErrWebSocketClosed && ctx.Err() == nil {
+				defaultLogger.Printf("WebSocket %s finished with error: %s", req.URL.Path, err)
+				code = CloseInternalError
+			}
+		}
+
+		conn.Close(code, "")
+		netConn.Close()
+	}
+}
+
+func checkHandshake(req *http.Request) *Error {
+	var msg string
+
+	switch {
+	case req.Method != http.MethodGet:
+		msg = "Method must be GET"
+	case !headerContains(req.Header, "Connection", "upgrade"):
+		msg = "Connection header must contain 'upgrade'"
+	case !headerContains(req.Header, "Upgrade", "websocket"):
+		msg = "Upgrade header must contain 'websocket'"
+	case req.Header.Get("Sec-WebSocket-Version") != "13":
+		msg = "Sec-WebSocket-Version must be 13"
+	case req.Header.Get("Sec-WebSocket-Key") == "":
+		msg = "Sec-WebSocket-Key is missing"
+	default:
+		return nil
+	}
+
+	return &Error{
+		Code:    "websocket_bad_handshake",
+		Message: msg,
+	}
+}
+
+// headerContains check if header has the token, it can be a comma-separated list.
+func headerContains(header http.Header, name, token string) bool {
+	for _, v := range header[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func websocketAccept(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key+websocketGUID)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func sameOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, req.Host)
+}
+
+// WebSocketConn is a WebSocket connection, it's safe to call ReadMessage from one
+// goroutine and WriteMessage from another one at the same time.
+type WebSocketConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	writeMu       sync.Mutex
+	writeDeadline time.Time
+	closeSent     bool
+
+	pongMu   sync.Mutex
+	pingSent time.Time
+	pongRecv time.Time
+}
+
+func newWebSocketConn(conn net.Conn, r *bufio.Reader) *WebSocketConn {
+	return &WebSocketConn{
+		conn: conn,
+		r:    r,
+	}
+}
+
+// RemoteAddr return the address of the client.
+func (c *WebSocketConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadDeadline set the deadline to receive the next message, zero means no deadline.
+// After a timeout the connection cannot be used anymore.
+func (c *WebSocketConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline set the deadline to send messages, zero means no deadline.
+// After a timeout the connection cannot be used anymore.
+func (c *WebSocketConn) SetWriteDeadline(t time.Time) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.writeDeadline = t
+	return nil
+}
+
+// ReadMessage block until a message is received. When client close the connection
+// it returns *fdhttp.CloseError.
+func (c *WebSocketConn) ReadMessage() (MessageType, []byte, error) {
+	var (
+		msgType MessageType
+		msg     []byte
+	)
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeControl(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			c.pongMu.Lock()
+			c.pongRecv = time.Now()
+			c.pongMu.Unlock()
+			continue
+		case opClose:
+			closeErr := parseClosePayload(payload)
+			code := closeErr.Code
+			if code == CloseNoStatus {
+				code = CloseNormal
+			}
+			c.Close(code, "")
+			return 0, nil, closeErr
+		case opText, opBinary:
+			if msgType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			msgType = MessageType(opcode)
+		case opContinuation:
+			if msgType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+
+		if int64(len(msg)+len(payload)) > WebSocketReadLimit {
+			return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+		msg = append(msg, payload...)
+
+		if fin {
+			break
+		}
+	}
+
+	if msgType == TextMessage && !utf8.Valid(msg) {
+		return 0, nil, c.fail(CloseInvalidPayload, "invalid utf-8")
+	}
+
+	return msgType, msg, nil
+}
+
+// ReadJSON read the next message and decode it into v.
+func (c *WebSocketConn) ReadJSON(v interface{}) error {
+	_, msg, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(msg, v)
+}
+
+// WriteMessage send a message to the client.
+func (c *WebSocketConn) WriteMessage(msgType MessageType, data []byte) error {
+	if msgType != TextMessage && msgType != BinaryMessage {
+		return fmt.Errorf("fdhttp: invalid websocket message type %d", msgType)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.writeFrame(byte(msgType), data, c.writeDeadline)
+}
+
+// WriteJSON encode v as JSON and send it as a text message.
+func (c *WebSocketConn) WriteJSON(v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, buf)
+}
+
+// Close send a close frame to the client with the code and the reason, after that
+// no messages can be sent. Clients answer with a close frame, that is returned by
+// ReadMessage as *fdhttp.CloseError.
+func (c *WebSocketConn) Close(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > 125 {
+		payload = payload[:125]
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return nil
+	}
+
+	err := c.writeFrame(opClose, payload, time.Now().Add(controlWriteWait))
+	c.closeSent = true
+
+	return err
+}
+
+// fail close the connection because client didn't follow the protocol.
+func (c *WebSocketConn) fail(code int, reason string) error {
+	c.Close(code, reason)
+	c.conn.Close()
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// keepalive send pings and close connections that didn't answer the previous one.
+func (c *WebSocketConn) keepalive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.pongMu.Lock()
+			missed := !c.pingSent.IsZero() && c.pongRecv.Before(c.pingSent)
+			c.pingSent = time.Now()
+			c.pongMu.Unlock()
+
+			if missed {
+				c.conn.Close()
+				return
+			}
+
+			if err := c.writeControl(opPing, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *WebSocketConn) writeControl(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.writeFrame(opcode, payload, time.Now().Add(controlWriteWait))
+}
+
+// writeFrame write a single frame, caller must hold writeMu.
+// Frames sent by servers are never masked.
+func (c *WebSocketConn) writeFrame(opcode byte, payload []byte, deadline time.Time) error {
+	if c.closeSent {
+		return ErrWebSocketClosed
+	}
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+
+	switch n := len(payload); {
+	case n <= 125:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = header[:4]
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = header[:10]
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	c.conn.SetWriteDeadline(deadline)
+
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *WebSocketConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.r, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7f)
+
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "reserved bits must be 0")
+	}
+	if !masked {
+		return false, 0, nil, c.fail(CloseProtocolError, "client frames must be masked")
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if opcode >= opClose && (length > 125 || !fin) {
+		return false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if length < 0 || length > WebSocketReadLimit {
+		return false, 0, nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.r, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+func parseClosePayload(payload []byte) *CloseError {
+	if len(payload) < 2 {
+		return &CloseError{Code: CloseNoStatus}
+	}
+
+	return &CloseError{
+		Code:   int(binary.BigEndian.Uint16(payload)),
+		Reason: string(payload[2:]),
+	}
+}
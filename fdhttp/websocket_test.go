@@ -0,0 +1,292 @@
+package fdhttp_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdhttp/fdmiddleware"
+	"github.com/stretchr/testify/assert"
+)
+
+// wsClient is a minimal websocket client used to test the server side.
+type wsClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, addr, path string, header http.Header) (*wsClient, *http.Response) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+addr+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Write(conn)
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &wsClient{conn: conn, r: r}, resp
+}
+
+func (c *wsClient) write(opcode byte, payload []byte) {
+	header := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	mask := []byte{1, 2, 3, 4}
+
+	masked := make([]byte, len(payload))
+	for i := range payload {
+		masked[i] = payload[i] ^ mask[i%4]
+	}
+
+	c.conn.Write(append(append(header, mask...), masked...))
+}
+
+func (c *wsClient) read() (byte, []byte, error) {
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.r, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+
+	payload := make([]byte, length)
+	_, err := io.ReadFull(c.r, payload)
+	return header[0] & 0x0f, payload, err
+}
+
+func TestRouter_WebSocket(t *testing.T) {
+	logMiddleware := fdmiddleware.NewLogMiddleware()
+	logMiddleware.SetLogger(log.New(ioutil.Discard, "", 0))
+
+	r := fdhttp.NewRouter()
+	r.Use(logMiddleware)
+	r.WebSocket("/echo/:id", func(ctx context.Context, conn *fdhttp.WebSocketConn) error {
+		for {
+			msgType, msg, err := conn.ReadMessage()
+			if err != nil {
+				return err
+			}
+
+			reply := fdhttp.RouteParam(ctx, "id") + ":" + string(msg)
+			if err := conn.WriteMessage(msgType, []byte(reply)); err != nil {
+				return err
+			}
+		}
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	client, resp := dialWebSocket(t, strings.TrimPrefix(ts.URL, "http://"), "/echo/42", nil)
+	defer client.conn.Close()
+
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	client.write(0x1, []byte("hello"))
+	opcode, payload, err := client.read()
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0x1), opcode)
+	assert.Equal(t, "42:hello", string(payload))
+
+	// server answer pings even without messages from handler
+	client.write(0x9, []byte("ping"))
+	opcode, payload, err = client.read()
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0xa), opcode)
+	assert.Equal(t, "ping", string(payload))
+
+	client.write(0x8, []byte{0x03, 0xe8})
+	opcode, payload, err = client.read()
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0x8), opcode)
+	assert.Equal(t, uint16(fdhttp.CloseNormal), binary.BigEndian.Uint16(payload))
+}
+
+func TestRouter_WebSocketBadHandshake(t *testing.T) {
+	r := fdhttp.NewRouter()
+	r.WebSocket("/ws", func(ctx context.Context, conn *fdhttp.WebSocketConn) error {
+		t.Error("handler should not be called")
+		return nil
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/ws")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "13", resp.Header.Get("Sec-WebSocket-Version"))
+}
+
+func TestRouter_WebSocketRejectOtherOrigins(t *testing.T) {
+	r := fdhttp.NewRouter()
+	r.WebSocket("/ws", func(ctx context.Context, conn *fdhttp.WebSocketConn) error {
+		t.Error("handler should not be called")
+		return nil
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	header := http.Header{}
+	header.Set("Origin", "http://evil.example.com")
+
+	client, resp := dialWebSocket(t, strings.TrimPrefix(ts.URL, "http://"), "/ws", header)
+	defer client.conn.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestRouter_WebSocketKeepalive(t *testing.T) {
+	defaultInterval := fdhttp.WebSocketPingInterval
+	fdhttp.WebSocketPingInterval = 10 * time.Millisecond
+	defer func() {
+		fdhttp.WebSocketPingInterval = defaultInterval
+	}()
+
+	r := fdhttp.NewRouter()
+	r.WebSocket("/ws", func(ctx context.Context, conn *fdhttp.WebSocketConn) error {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return err
+			}
+		}
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	client, _ := dialWebSocket(t, strings.TrimPrefix(ts.URL, "http://"), "/ws", nil)
+	defer client.conn.Close()
+
+	opcode, _, err := client.read()
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0x9), opcode)
+
+	// without pong the connection is closed in the next ping
+	for err == nil {
+		_, _, err = client.read()
+	}
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestServer_StopClosesWebSockets(t *testing.T) {
+	finished := make(chan struct{})
+
+	r := fdhttp.NewRouter()
+	r.WebSocket("/ws", func(ctx context.Context, conn *fdhttp.WebSocketConn) error {
+		defer close(finished)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return err
+			}
+		}
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	srv, stopChan := startServer("")
+	go func() {
+		srv.Serve(l, r)
+		stopChan <- struct{}{}
+	}()
+
+	client, _ := dialWebSocket(t, l.Addr().String(), "/ws", nil)
+	defer client.conn.Close()
+
+	go func() {
+		opcode, payload, err := client.read()
+		assert.NoError(t, err)
+		assert.Equal(t, byte(0x8), opcode)
+		assert.Equal(t, uint16(fdhttp.CloseGoingAway), binary.BigEndian.Uint16(payload))
+
+		// answer close frame, that way handler returns
+		client.write(0x8, payload[:2])
+	}()
+
+	err = stopServer(t, srv, stopChan)
+	assert.NoError(t, err)
+
+	select {
+	case <-finished:
+	default:
+		t.Error("Server didn't wait websocket handler")
+	}
+}
+
+func TestServer_StopClosesUnresponsiveWebSockets(t *testing.T) {
+	defer func(timeout time.Duration) {
+		fdhttp.WebSocketCloseTimeout = timeout
+	}(fdhttp.WebSocketCloseTimeout)
+	fdhttp.WebSocketCloseTimeout = 50 * time.Millisecond
+
+	finished := make(chan error, 1)
+
+	r := fdhttp.NewRouter()
+	r.WebSocket("/ws", func(ctx context.Context, conn *fdhttp.WebSocketConn) error {
+		_, _, err := conn.ReadMessage()
+		finished <- err
+		return err
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	srv, stopChan := startServer("")
+	go func() {
+		srv.Serve(l, r)
+		stopChan <- struct{}{}
+	}()
+
+	// client never answers the close frame
+	client, _ := dialWebSocket(t, l.Addr().String(), "/ws", nil)
+	defer client.conn.Close()
+
+	err = stopServer(t, srv, stopChan)
+	assert.NoError(t, err)
+
+	select {
+	case err := <-finished:
+		assert.Error(t, err)
+	default:
+		t.Error("Server didn't wait websocket handler")
+	}
+}
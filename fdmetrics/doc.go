@@ -0,0 +1,27 @@
+// Package fdmetrics is a vendor-neutral metrics package with counters, gauges and
+// histograms, exposed in the Prometheus text format.
+//
+//  router := fdhttp.NewRouter()
+//  router.Use(fdmetrics.ServerMiddleware(fdmetrics.DefaultRegistry))
+//  router.Register(fdmetrics.NewHandler(fdmetrics.DefaultRegistry))
+//
+//  client := fdhttp.NewClient()
+//  client.Use(fdmetrics.ClientMiddleware(fdmetrics.DefaultRegistry))
+//
+// Your own metrics are created once and shared by all requests:
+//
+//  var ordersTotal = fdmetrics.NewCounter("orders_total", "Orders created.", "country")
+//
+//  ordersTotal.Inc("de")
+//
+// The same metrics can be sent to a StatsD agent, with DogStatsD tags:
+//
+//  statsd, err := fdmetrics.NewStatsD(fdmetrics.DefaultRegistry, fdmetrics.StatsDConfig{
+//      Addr:      "127.0.0.1:8125",
+//      DogStatsD: true,
+//  })
+//  app.Add("statsd", statsd)
+//
+// Subscribers and database pools are instrumented with fdmetrics.Subscriber
+// and fdmetrics.SQLStats.
+package fdmetrics
@@ -0,0 +1,49 @@
+package fdmetrics
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/foodora/go-ranger/fdhttp"
+)
+
+var _ fdhttp.Handler = &Handler{}
+var _ http.Handler = &Handler{}
+
+// MetricsURL is the endpoint where metrics are exposed.
+var MetricsURL = "/metrics"
+
+// Handler expose metrics of a registry in Prometheus text format.
+type Handler struct {
+	// Prefix will be prefix the fdmetrics.MetricsURL.
+	Prefix string
+
+	registry *Registry
+}
+
+// NewHandler create a handler to expose metrics of registry, it can be
+// registered in a fdhttp.Router or used as http.Handler.
+func NewHandler(registry *Registry) *Handler {
+	return &Handler{
+		registry: registry,
+	}
+}
+
+// Init will be called by fdhttp.Router to register the metrics endpoint.
+func (h *Handler) Init(r *fdhttp.Router) {
+	r.StdGET(h.Prefix+MetricsURL, h.ServeHTTP)
+}
+
+// ServeHTTP write all metrics of the registry.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// write to a buffer first, that way errors are not sent in the middle of the body
+	var buf bytes.Buffer
+	if err := h.registry.WritePrometheus(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-control", "no-cache")
+	buf.WriteTo(w)
+}
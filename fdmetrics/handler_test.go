@@ -0,0 +1,34 @@
+package fdmetrics_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdmetrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	registry := fdmetrics.NewRegistry()
+	registry.NewCounter("jobs_total", "Jobs done.").Inc()
+
+	router := fdhttp.NewRouter()
+	router.Register(fdmetrics.NewHandler(registry))
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "# HELP jobs_total Jobs done.\n# TYPE jobs_total counter\njobs_total 1\n", string(body))
+}
@@ -0,0 +1,27 @@
+package fdmetrics
+
+import (
+	"log"
+	"os"
+)
+
+// Logger is the interface used internally to log
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+var defaultLogger Logger
+
+func init() {
+	// Set default logger
+	SetLogger(nil)
+}
+
+// SetLogger change the logger used by exporters, like StatsD.
+func SetLogger(logger Logger) {
+	if logger == nil {
+		defaultLogger = log.New(os.Stdout, "[fdmetrics] ", log.LstdFlags)
+	} else {
+		defaultLogger = logger
+	}
+}
@@ -0,0 +1,216 @@
+package fdmetrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram buckets used when none are informed,
+// they are tailored to measure latencies in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metricType string
+
+const (
+	counterType   metricType = "counter"
+	gaugeType     metricType = "gauge"
+	histogramType metricType = "histogram"
+)
+
+// family keep all series of a metric, one for each combination of label values.
+type family struct {
+	name    string
+	help    string
+	typ     metricType
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// histograms only
+	counts []uint64
+	count  uint64
+}
+
+func newFamily(name, help string, typ metricType, labels []string, buckets []float64) *family {
+	if !validName(name, true) {
+		panic(fmt.Sprintf("fdmetrics: invalid metric name %s", name))
+	}
+	for _, l := range labels {
+		if !validName(l, false) || strings.HasPrefix(l, "__") || (typ == histogramType && l == "le") {
+			panic(fmt.Sprintf("fdmetrics: invalid label %s in metric %s", l, name))
+		}
+	}
+
+	if typ == histogramType {
+		if len(buckets) == 0 {
+			buckets = DefaultBuckets
+		}
+		buckets = append([]float64(nil), buckets...)
+		sort.Float64s(buckets)
+		// +Inf is always added in the exposition
+		if math.IsInf(buckets[len(buckets)-1], +1) {
+			buckets = buckets[:len(buckets)-1]
+		}
+	}
+
+	return &family{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+}
+
+// sameAs return true when both families can be used interchangeably.
+func (f *family) sameAs(other *family) bool {
+	if f.typ != other.typ || len(f.labels) != len(other.labels) || len(f.buckets) != len(other.buckets) {
+		return false
+	}
+	for i := range f.labels {
+		if f.labels[i] != other.labels[i] {
+			return false
+		}
+	}
+	for i := range f.buckets {
+		if f.buckets[i] != other.buckets[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// get return the series of labelValues, caller must hold mu.
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("fdmetrics: metric %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if f.typ == histogramType {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// snapshot copy all series sorted by label values.
+func (f *family) snapshot() []series {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	snapshot := make([]series, len(keys))
+	for i, k := range keys {
+		s := *f.series[k]
+		s.counts = append([]uint64(nil), s.counts...)
+		snapshot[i] = s
+	}
+	return snapshot
+}
+
+// Counter is a value that only goes up, like the number of requests.
+type Counter struct {
+	f *family
+}
+
+// Inc increment the counter by 1.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increment the counter by v, it panics when v is negative.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("fdmetrics: counter %s cannot decrease", c.f.name))
+	}
+
+	c.f.mu.Lock()
+	c.f.get(labelValues).value += v
+	c.f.mu.Unlock()
+}
+
+// Gauge is a value that can go up and down, like the number of connections.
+type Gauge struct {
+	f *family
+}
+
+// Set the gauge to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	g.f.get(labelValues).value = v
+	g.f.mu.Unlock()
+}
+
+// Add v to the gauge, v can be negative.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	g.f.get(labelValues).value += v
+	g.f.mu.Unlock()
+}
+
+// Inc increment the gauge by 1.
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec decrement the gauge by 1.
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Histogram count observations in buckets, like request latencies.
+type Histogram struct {
+	f *family
+}
+
+// Observe add v to the histogram.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	// first bucket where v fits, buckets are cumulative only in the exposition
+	i := sort.SearchFloat64s(h.f.buckets, v)
+
+	h.f.mu.Lock()
+	s := h.f.get(labelValues)
+	if i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.count++
+	s.value += v
+	h.f.mu.Unlock()
+}
+
+func validName(name string, allowColon bool) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c == ':' && allowColon:
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
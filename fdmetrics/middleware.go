@@ -0,0 +1,59 @@
+package fdmetrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdhttp/fdmiddleware"
+)
+
+// UnmatchedRoute is the route label of requests that didn't match any
+// endpoint, that way invalid paths don't create new series.
+var UnmatchedRoute = "unmatched"
+
+// ServerMiddleware record requests of a fdhttp.Router by endpoint name (check
+// Endpoint.SetName), it must be used in the main router:
+//
+//  http_server_requests_total{method,route,status}
+//  http_server_request_duration_seconds{method,route}
+//  http_server_requests_in_flight{method,route}
+func ServerMiddleware(registry *Registry) fdmiddleware.Middleware {
+	requests := registry.NewCounter("http_server_requests_total",
+		"Number of HTTP requests handled.", "method", "route", "status")
+	duration := registry.NewHistogram("http_server_request_duration_seconds",
+		"Latency of HTTP requests handled.", nil, "method", "route")
+	inFlight := registry.NewGauge("http_server_requests_in_flight",
+		"Number of HTTP requests being handled.", "method", "route")
+
+	return fdmiddleware.MiddlewareFunc(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			started := time.Now()
+
+			// route is only known after the router matched the request
+			var route string
+			fdhttp.OnEndpointMatch(req.Context(), func(e *fdhttp.Endpoint) {
+				route = e.Name
+				inFlight.Inc(req.Method, route)
+			})
+
+			lr := &fdmiddleware.LogResponse{ResponseWriter: w}
+			next.ServeHTTP(lr, req)
+
+			if route != "" {
+				inFlight.Dec(req.Method, route)
+			} else {
+				route = UnmatchedRoute
+			}
+
+			statusCode := lr.StatusCode
+			if statusCode == 0 {
+				statusCode = http.StatusOK
+			}
+
+			requests.Inc(req.Method, route, strconv.Itoa(statusCode))
+			duration.Observe(time.Since(started).Seconds(), req.Method, route)
+		})
+	})
+}
@@ -0,0 +1,54 @@
+package fdmetrics_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdmetrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerMiddleware(t *testing.T) {
+	registry := fdmetrics.NewRegistry()
+
+	var inFlight bytes.Buffer
+
+	router := fdhttp.NewRouter()
+	router.Use(fdmetrics.ServerMiddleware(registry))
+	router.GET("/v1/people/:id", func(ctx context.Context) (int, interface{}) {
+		registry.WritePrometheus(&inFlight)
+		return http.StatusOK, nil
+	}).SetName("get_person")
+
+	subrouter := router.SubRouter()
+	subrouter.Prefix = "/internal"
+	subrouter.StdGET("/ping", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("pong"))
+	})
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	for _, path := range []string{"/v1/people/1", "/v1/people/2", "/internal/ping", "/not-found"} {
+		resp, err := http.Get(ts.URL + path)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+	}
+
+	assert.Contains(t, inFlight.String(), `http_server_requests_in_flight{method="GET",route="get_person"} 1`)
+
+	var buf bytes.Buffer
+	registry.WritePrometheus(&buf)
+	metrics := buf.String()
+
+	assert.Contains(t, metrics, `http_server_requests_total{method="GET",route="get_person",status="200"} 2`)
+	assert.Contains(t, metrics, `http_server_requests_total{method="GET",route="GET_internal_ping",status="200"} 1`)
+	assert.Contains(t, metrics, `http_server_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, metrics, `http_server_request_duration_seconds_count{method="GET",route="get_person"} 2`)
+	assert.Contains(t, metrics, `http_server_requests_in_flight{method="GET",route="get_person"} 0`)
+}
@@ -0,0 +1,148 @@
+package fdmetrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/foodora/go-ranger/pubsub"
+)
+
+// Subscriber wrap sub to record messages consumed, like the one returned by
+// awssub.NewSubscriber. Processing time is measured from the moment the message
+// is received until Done is called:
+//
+//  pubsub_messages_received_total{subscription}
+//  pubsub_messages_done_total{subscription,status}
+//  pubsub_message_processing_seconds{subscription}
+//
+// The wrapper implements HealthCheck only when sub does.
+func Subscriber(registry *Registry, name string, sub pubsub.Subscriber) pubsub.Subscriber {
+	s := &subscriber{
+		Subscriber: sub,
+		name:       name,
+		received: registry.NewCounter("pubsub_messages_received_total",
+			"Number of messages received.", "subscription"),
+		done: registry.NewCounter("pubsub_messages_done_total",
+			"Number of messages marked as done.", "subscription", "status"),
+		processing: registry.NewHistogram("pubsub_message_processing_seconds",
+			"Time between receiving a message and marking it as done.", nil, "subscription"),
+	}
+
+	if checker, ok := sub.(healthChecker); ok {
+		return &healthSubscriber{subscriber: s, checker: checker}
+	}
+	return s
+}
+
+type subscriber struct {
+	pubsub.Subscriber
+	name string
+
+	received   *Counter
+	done       *Counter
+	processing *Histogram
+
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+func (s *subscriber) Start() <-chan pubsub.Message {
+	msgs := s.Subscriber.Start()
+	if msgs == nil {
+		return nil
+	}
+
+	stop := make(chan struct{})
+	s.mu.Lock()
+	s.stop = stop
+	s.mu.Unlock()
+
+	out := make(chan pubsub.Message)
+
+	go func() {
+		defer close(out)
+		for msg := range msgs {
+			s.received.Inc(s.name)
+			select {
+			case out <- &subscriberMessage{Message: msg, sub: s, received: time.Now()}:
+			case <-stop:
+				// nobody is reading anymore, the message can be received again
+				msg.ExtendDoneDeadline(0)
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Stop stop the wrapped subscriber.
+func (s *subscriber) Stop() error {
+	s.closeStop()
+	return s.Subscriber.Stop()
+}
+
+// StopWithContext stop the wrapped subscriber with pubsub.StopSubscriber.
+func (s *subscriber) StopWithContext(ctx context.Context) error {
+	s.closeStop()
+	return pubsub.StopSubscriber(ctx, s.Subscriber)
+}
+
+func (s *subscriber) closeStop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// healthSubscriber is returned when the wrapped subscriber supports health
+// checks, that way fdhealth.Subscriber still rejects the ones that don't.
+type healthSubscriber struct {
+	*subscriber
+	checker healthChecker
+}
+
+type healthChecker interface {
+	HealthCheck(context.Context) (interface{}, error)
+}
+
+// HealthCheck call the wrapped subscriber's HealthCheck.
+func (s *healthSubscriber) HealthCheck(ctx context.Context) (interface{}, error) {
+	return s.checker.HealthCheck(ctx)
+}
+
+type subscriberMessage struct {
+	pubsub.Message
+	sub      *subscriber
+	received time.Time
+}
+
+func (m *subscriberMessage) Done() error {
+	err := m.Message.Done()
+
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.sub.done.Inc(m.sub.name, status)
+	m.sub.processing.Observe(time.Since(m.received).Seconds(), m.sub.name)
+
+	return err
+}
+
+// DuplicateMessages return a callback counting duplicates skipped by
+// pubsub.IdempotentHandler, to be used as IdempotencyConfig.OnDuplicate:
+//
+//  pubsub_messages_duplicate_total{subscription}
+func DuplicateMessages(registry *Registry, name string) func(pubsub.Message) {
+	duplicates := registry.NewCounter("pubsub_messages_duplicate_total",
+		"Number of duplicate messages skipped.", "subscription")
+
+	return func(pubsub.Message) {
+		duplicates.Inc(name)
+	}
+}
@@ -0,0 +1,134 @@
+package fdmetrics_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/fdmetrics"
+	"github.com/foodora/go-ranger/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMessage struct {
+	pubsub.Message
+	err      error
+	released chan struct{}
+}
+
+func (m *testMessage) Done() error {
+	return m.err
+}
+
+func (m *testMessage) ExtendDoneDeadline(time.Duration) error {
+	close(m.released)
+	return nil
+}
+
+type testSubscriber struct {
+	pubsub.Subscriber
+	msgs    chan pubsub.Message
+	stopped bool
+}
+
+func (s *testSubscriber) Start() <-chan pubsub.Message {
+	return s.msgs
+}
+
+func (s *testSubscriber) Stop() error {
+	s.stopped = true
+	return nil
+}
+
+func (s *testSubscriber) HealthCheck(context.Context) (interface{}, error) {
+	return "healthy", nil
+}
+
+func TestSubscriber(t *testing.T) {
+	registry := fdmetrics.NewRegistry()
+
+	sub := &testSubscriber{msgs: make(chan pubsub.Message, 3)}
+	sub.msgs <- &testMessage{}
+	sub.msgs <- &testMessage{}
+	sub.msgs <- &testMessage{err: errors.New("receipt handle expired")}
+	close(sub.msgs)
+
+	var count int
+	for msg := range fdmetrics.Subscriber(registry, "orders", sub).Start() {
+		time.Sleep(time.Millisecond)
+		msg.Done()
+		count++
+	}
+	assert.Equal(t, 3, count)
+
+	var buf bytes.Buffer
+	registry.WritePrometheus(&buf)
+	metrics := buf.String()
+
+	assert.Contains(t, metrics, `pubsub_messages_received_total{subscription="orders"} 3`)
+	assert.Contains(t, metrics, `pubsub_messages_done_total{subscription="orders",status="ok"} 2`)
+	assert.Contains(t, metrics, `pubsub_messages_done_total{subscription="orders",status="error"} 1`)
+	assert.Contains(t, metrics, `pubsub_message_processing_seconds_count{subscription="orders"} 3`)
+}
+
+func TestSubscriberStop(t *testing.T) {
+	registry := fdmetrics.NewRegistry()
+
+	assert.Nil(t, fdmetrics.Subscriber(registry, "orders", &testSubscriber{}).Start())
+
+	sub := &testSubscriber{msgs: make(chan pubsub.Message, 1)}
+	msg := &testMessage{released: make(chan struct{})}
+	sub.msgs <- msg
+
+	wrapped := fdmetrics.Subscriber(registry, "orders", sub)
+	msgs := wrapped.Start()
+
+	// the message is never read
+	assert.NoError(t, wrapped.(pubsub.ContextStopper).StopWithContext(context.Background()))
+	assert.True(t, sub.stopped)
+
+	select {
+	case <-msg.released:
+	case <-time.After(time.Second):
+		t.Error("message was not released")
+	}
+	_, ok := <-msgs
+	assert.False(t, ok)
+
+}
+
+type healthChecker interface {
+	HealthCheck(context.Context) (interface{}, error)
+}
+
+func TestSubscriberHealthCheck(t *testing.T) {
+	registry := fdmetrics.NewRegistry()
+
+	wrapped := fdmetrics.Subscriber(registry, "orders", &testSubscriber{})
+	if assert.Implements(t, (*healthChecker)(nil), wrapped) {
+		detail, err := wrapped.(healthChecker).HealthCheck(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "healthy", detail)
+	}
+	_, ok := wrapped.(pubsub.ContextStopper)
+	assert.True(t, ok)
+
+	// subscribers without health check are still rejected by fdhealth
+	wrapped = fdmetrics.Subscriber(registry, "payments", struct{ pubsub.Subscriber }{})
+	_, ok = wrapped.(healthChecker)
+	assert.False(t, ok)
+}
+
+func TestDuplicateMessages(t *testing.T) {
+	registry := fdmetrics.NewRegistry()
+
+	onDuplicate := fdmetrics.DuplicateMessages(registry, "orders")
+	onDuplicate(&testMessage{})
+	onDuplicate(&testMessage{})
+
+	var buf bytes.Buffer
+	registry.WritePrometheus(&buf)
+	assert.Contains(t, buf.String(), `pubsub_messages_duplicate_total{subscription="orders"} 2`)
+}
@@ -0,0 +1,198 @@
+package fdmetrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultRegistry is used by NewCounter, NewGauge and NewHistogram.
+var DefaultRegistry = NewRegistry()
+
+// Registry keep metrics by name, all of them are exposed together.
+type Registry struct {
+	mu         sync.Mutex
+	families   map[string]*family
+	collectors []func()
+}
+
+// NewRegistry create an empty registry, usually DefaultRegistry is enough.
+func NewRegistry() *Registry {
+	return &Registry{
+		families: make(map[string]*family),
+	}
+}
+
+// register add f to the registry. Registering the same metric twice return the
+// first one, that way middlewares can be created more than once. It panics when
+// the name is used by a metric with different type, labels or buckets.
+func (r *Registry) register(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.families[f.name]; ok {
+		if !existing.sameAs(f) {
+			panic(fmt.Sprintf("fdmetrics: metric %s already registered with different type or labels", f.name))
+		}
+		return existing
+	}
+
+	r.families[f.name] = f
+	return f
+}
+
+// NewCounter create a counter, labels are the names of labels that
+// need to be informed with values every time the counter changes.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{f: r.register(newFamily(name, help, counterType, labels, nil))}
+}
+
+// NewGauge create a gauge, check NewCounter.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{f: r.register(newFamily(name, help, gaugeType, labels, nil))}
+}
+
+// NewHistogram create a histogram with buckets, when it's nil DefaultBuckets
+// are used. Check NewCounter.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{f: r.register(newFamily(name, help, histogramType, labels, buckets))}
+}
+
+// NewCounter create a counter in DefaultRegistry.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return DefaultRegistry.NewCounter(name, help, labels...)
+}
+
+// NewGauge create a gauge in DefaultRegistry.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return DefaultRegistry.NewGauge(name, help, labels...)
+}
+
+// NewHistogram create a histogram in DefaultRegistry.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return DefaultRegistry.NewHistogram(name, help, buckets, labels...)
+}
+
+// OnCollect register fn to be called before metrics are exported, it's useful
+// to update gauges from values that are only read on demand, like pool stats.
+func (r *Registry) OnCollect(fn func()) {
+	r.mu.Lock()
+	r.collectors = append(r.collectors, fn)
+	r.mu.Unlock()
+}
+
+func (r *Registry) collect() {
+	r.mu.Lock()
+	collectors := r.collectors
+	r.mu.Unlock()
+
+	for _, fn := range collectors {
+		fn()
+	}
+}
+
+// sortedFamilies return all families sorted by name.
+func (r *Registry) sortedFamilies() []*family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+	return families
+}
+
+// WritePrometheus write all metrics using the Prometheus text format 0.0.4.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.collect()
+	bw := bufio.NewWriter(w)
+
+	for _, f := range r.sortedFamilies() {
+		snapshot := f.snapshot()
+		if len(snapshot) == 0 {
+			continue
+		}
+
+		if f.help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.name, f.typ)
+
+		for _, s := range snapshot {
+			if f.typ != histogramType {
+				writeSample(bw, f.name, f.labels, s.labelValues, "", "", s.value)
+				continue
+			}
+
+			var cumulative uint64
+			for i, bucket := range f.buckets {
+				cumulative += s.counts[i]
+				writeSample(bw, f.name+"_bucket", f.labels, s.labelValues, "le", formatFloat(bucket), float64(cumulative))
+			}
+			writeSample(bw, f.name+"_bucket", f.labels, s.labelValues, "le", "+Inf", float64(s.count))
+			writeSample(bw, f.name+"_sum", f.labels, s.labelValues, "", "", s.value)
+			writeSample(bw, f.name+"_count", f.labels, s.labelValues, "", "", float64(s.count))
+		}
+	}
+
+	return bw.Flush()
+}
+
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	w.WriteString(name)
+
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", l, escapeLabelValue(values[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, +1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
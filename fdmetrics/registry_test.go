@@ -0,0 +1,90 @@
+package fdmetrics_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdmetrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_WritePrometheus(t *testing.T) {
+	r := fdmetrics.NewRegistry()
+
+	counter := r.NewCounter("orders_total", "Orders created.", "country")
+	counter.Inc("de")
+	counter.Add(2, "at")
+	counter.Inc("de")
+
+	gauge := r.NewGauge("queue_size", "Messages\nwaiting.")
+	gauge.Set(10)
+	gauge.Dec()
+
+	histogram := r.NewHistogram("latency_seconds", "", []float64{0.5, 0.1, 1}, "path")
+	histogram.Observe(0.05, `/a"b`)
+	histogram.Observe(0.3, `/a"b`)
+	histogram.Observe(5, `/a"b`)
+
+	// metrics without values are not exposed
+	r.NewCounter("unused_total", "Never incremented.")
+
+	var buf bytes.Buffer
+	assert.NoError(t, r.WritePrometheus(&buf))
+	assert.Equal(t, `# TYPE latency_seconds histogram
+latency_seconds_bucket{path="/a\"b",le="0.1"} 1
+latency_seconds_bucket{path="/a\"b",le="0.5"} 2
+latency_seconds_bucket{path="/a\"b",le="1"} 2
+latency_seconds_bucket{path="/a\"b",le="+Inf"} 3
+latency_seconds_sum{path="/a\"b"} 5.35
+latency_seconds_count{path="/a\"b"} 3
+# HELP orders_total Orders created.
+# TYPE orders_total counter
+orders_total{country="at"} 2
+orders_total{country="de"} 2
+# HELP queue_size Messages\nwaiting.
+# TYPE queue_size gauge
+queue_size 9
+`, buf.String())
+}
+
+func TestRegistry_RegisterTwice(t *testing.T) {
+	r := fdmetrics.NewRegistry()
+
+	c1 := r.NewCounter("requests_total", "", "method")
+	c2 := r.NewCounter("requests_total", "", "method")
+	c1.Inc("GET")
+	c2.Inc("GET")
+
+	var buf bytes.Buffer
+	r.WritePrometheus(&buf)
+	assert.Contains(t, buf.String(), `requests_total{method="GET"} 2`)
+
+	assert.Panics(t, func() {
+		r.NewGauge("requests_total", "", "method")
+	})
+	assert.Panics(t, func() {
+		r.NewCounter("requests_total", "", "path")
+	})
+}
+
+func TestRegistry_Invalid(t *testing.T) {
+	r := fdmetrics.NewRegistry()
+
+	assert.Panics(t, func() {
+		r.NewCounter("requests-total", "")
+	})
+	assert.Panics(t, func() {
+		r.NewCounter("requests_total", "", "1method")
+	})
+	assert.Panics(t, func() {
+		r.NewHistogram("latency_seconds", "", nil, "le")
+	})
+
+	counter := r.NewCounter("requests_total", "", "method")
+	assert.Panics(t, func() {
+		counter.Inc()
+	})
+	assert.Panics(t, func() {
+		counter.Add(-1, "GET")
+	})
+}
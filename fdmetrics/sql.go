@@ -0,0 +1,51 @@
+package fdmetrics
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// SQLStats record the connection pool stats of db, like the one returned by
+// fddb.OpenSQL. Stats are read every time metrics are exported:
+//
+//  db_connections_max_open{db}
+//  db_connections_open{db}
+//  db_connections_in_use{db}
+//  db_connections_idle{db}
+//  db_connections_wait_total{db}
+//  db_connections_wait_seconds_total{db}
+func SQLStats(registry *Registry, name string, db *sql.DB) {
+	maxOpen := registry.NewGauge("db_connections_max_open",
+		"Maximum number of open connections to the database.", "db")
+	open := registry.NewGauge("db_connections_open",
+		"Number of established connections, in use and idle.", "db")
+	inUse := registry.NewGauge("db_connections_in_use",
+		"Number of connections in use.", "db")
+	idle := registry.NewGauge("db_connections_idle",
+		"Number of idle connections.", "db")
+	waitCount := registry.NewCounter("db_connections_wait_total",
+		"Number of connections waited for.", "db")
+	waitDuration := registry.NewCounter("db_connections_wait_seconds_total",
+		"Time blocked waiting for a new connection.", "db")
+
+	var (
+		mu   sync.Mutex
+		last sql.DBStats
+	)
+
+	registry.OnCollect(func() {
+		stats := db.Stats()
+
+		maxOpen.Set(float64(stats.MaxOpenConnections), name)
+		open.Set(float64(stats.OpenConnections), name)
+		inUse.Set(float64(stats.InUse), name)
+		idle.Set(float64(stats.Idle), name)
+
+		// stats are cumulative, counters receive what changed since last time
+		mu.Lock()
+		waitCount.Add(float64(stats.WaitCount-last.WaitCount), name)
+		waitDuration.Add((stats.WaitDuration - last.WaitDuration).Seconds(), name)
+		last = stats
+		mu.Unlock()
+	})
+}
@@ -0,0 +1,49 @@
+package fdmetrics_test
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdmetrics"
+	"github.com/stretchr/testify/assert"
+)
+
+type testDriver struct{}
+
+func (testDriver) Open(string) (driver.Conn, error) { return testConn{}, nil }
+
+type testConn struct{}
+
+func (testConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (testConn) Close() error                        { return nil }
+func (testConn) Begin() (driver.Tx, error)           { return nil, errors.New("not implemented") }
+
+func init() {
+	sql.Register("fdmetrics-test", testDriver{})
+}
+
+func TestSQLStats(t *testing.T) {
+	db, _ := sql.Open("fdmetrics-test", "")
+	defer db.Close()
+	db.SetMaxOpenConns(10)
+
+	conn, _ := db.Driver().Open("")
+	defer conn.Close()
+	db.Ping()
+
+	registry := fdmetrics.NewRegistry()
+	fdmetrics.SQLStats(registry, "orders", db)
+
+	var buf bytes.Buffer
+	registry.WritePrometheus(&buf)
+	metrics := buf.String()
+
+	assert.Contains(t, metrics, `db_connections_max_open{db="orders"} 10`)
+	assert.Contains(t, metrics, `db_connections_open{db="orders"} 1`)
+	assert.Contains(t, metrics, `db_connections_idle{db="orders"} 1`)
+	assert.Contains(t, metrics, `db_connections_in_use{db="orders"} 0`)
+	assert.Contains(t, metrics, `db_connections_wait_total{db="orders"} 0`)
+}
@@ -0,0 +1,278 @@
+package fdmetrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultStatsDFlushInterval is used when StatsDConfig.FlushInterval is not informed.
+var DefaultStatsDFlushInterval = 10 * time.Second
+
+// DefaultStatsDMaxPacketSize is used when StatsDConfig.MaxPacketSize is not informed,
+// it fits in the MTU of most networks.
+var DefaultStatsDMaxPacketSize = 1432
+
+// StatsDConfig configure the StatsD exporter.
+type StatsDConfig struct {
+	// Addr of the agent, like "127.0.0.1:8125".
+	Addr string
+	// Prefix is added to all metric names, like "orders.".
+	Prefix string
+	// DogStatsD send labels as tags, otherwise label values are
+	// appended to the metric name, like http_server_requests_total.GET.get_person.200.
+	DogStatsD bool
+	// Tags are sent with all metrics, only used by DogStatsD.
+	Tags map[string]string
+	// FlushInterval is the interval that metrics are sent.
+	FlushInterval time.Duration
+	// MaxPacketSize is the maximum size of each UDP packet.
+	MaxPacketSize int
+}
+
+// StatsD send metrics of a registry to a StatsD agent. Metrics are aggregated in
+// memory and sent every FlushInterval, batching many of them in each packet, that
+// way hot paths never wait for the network:
+//
+//  counters   - increment since last flush, as "c"
+//  gauges     - current value, as "g"
+//  histograms - count and sum since last flush as "c", named <name>.count and <name>.sum,
+//               with DogStatsD also each bucket as <name>.bucket tagged with le
+//
+// It can be started by fdapp.App, because it implements fdapp.Component.
+type StatsD struct {
+	registry *Registry
+	cfg      StatsDConfig
+	tags     string
+	conn     net.Conn
+
+	mu   sync.Mutex
+	last map[string]series
+
+	running  uint32
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewStatsD create an exporter of registry, metrics are sent after calling Start.
+func NewStatsD(registry *Registry, cfg StatsDConfig) (*StatsD, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("fdmetrics: statsd address is required")
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultStatsDFlushInterval
+	}
+	if cfg.MaxPacketSize <= 0 {
+		cfg.MaxPacketSize = DefaultStatsDMaxPacketSize
+	}
+
+	conn, err := net.Dial("udp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &StatsD{
+		registry: registry,
+		cfg:      cfg,
+		conn:     conn,
+		last:     make(map[string]series),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if cfg.DogStatsD && len(cfg.Tags) > 0 {
+		keys := make([]string, 0, len(cfg.Tags))
+		for k := range cfg.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		tags := make([]string, len(keys))
+		for i, k := range keys {
+			tags[i] = sanitizeTag(k) + ":" + sanitizeTag(cfg.Tags[k])
+		}
+		s.tags = strings.Join(tags, ",")
+	}
+
+	return s, nil
+}
+
+// Start send metrics every FlushInterval and block until Stop is called.
+// Errors sending metrics are logged, the agent may not be running yet.
+func (s *StatsD) Start() error {
+	if !atomic.CompareAndSwapUint32(&s.running, 0, 1) {
+		return errors.New("fdmetrics: statsd already started")
+	}
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return nil
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				defaultLogger.Printf("Unable to send metrics to statsd: %s", err)
+			}
+		}
+	}
+}
+
+// Stop flushing periodically, send metrics one last time and close the connection.
+func (s *StatsD) Stop(ctx context.Context) error {
+	first := false
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		first = true
+	})
+	if !first {
+		return errors.New("fdmetrics: statsd already stopped")
+	}
+
+	if atomic.LoadUint32(&s.running) == 1 {
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+		}
+	}
+
+	err := s.Flush()
+	s.conn.Close()
+	return err
+}
+
+// Flush send all metrics that changed since the last flush.
+func (s *StatsD) Flush() error {
+	s.registry.collect()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := &statsdPacket{conn: s.conn, maxSize: s.cfg.MaxPacketSize}
+
+	for _, f := range s.registry.sortedFamilies() {
+		for _, cur := range f.snapshot() {
+			key := f.name + "\xfe" + strings.Join(cur.labelValues, "\xff")
+			prev := s.last[key]
+			s.last[key] = cur
+
+			name := s.cfg.Prefix + f.name
+			tags := s.seriesTags(f.labels, cur.labelValues)
+			if !s.cfg.DogStatsD {
+				name = s.seriesName(name, cur.labelValues)
+			}
+
+			switch f.typ {
+			case counterType:
+				if delta := cur.value - prev.value; delta != 0 {
+					p.add(name, delta, "c", tags)
+				}
+			case gaugeType:
+				p.add(name, cur.value, "g", tags)
+			case histogramType:
+				count := cur.count - prev.count
+				if count == 0 {
+					continue
+				}
+				p.add(name+".count", float64(count), "c", tags)
+				p.add(name+".sum", cur.value-prev.value, "c", tags)
+
+				if !s.cfg.DogStatsD {
+					continue
+				}
+
+				var cumulative uint64
+				for i, bucket := range f.buckets {
+					cumulative += cur.counts[i]
+					if prev.counts != nil {
+						cumulative -= prev.counts[i]
+					}
+					if cumulative > 0 {
+						p.add(name+".bucket", float64(cumulative), "c", joinTags(tags, "le:"+formatFloat(bucket)))
+					}
+				}
+				p.add(name+".bucket", float64(count), "c", joinTags(tags, "le:+Inf"))
+			}
+		}
+	}
+
+	return p.flush()
+}
+
+// seriesName append label values to the name, used when tags are not supported.
+func (s *StatsD) seriesName(name string, labelValues []string) string {
+	for _, v := range labelValues {
+		name += "." + statsdNameReplacer.Replace(v)
+	}
+	return name
+}
+
+func (s *StatsD) seriesTags(labels, labelValues []string) string {
+	if !s.cfg.DogStatsD {
+		return ""
+	}
+
+	tags := s.tags
+	for i, l := range labels {
+		tags = joinTags(tags, l+":"+sanitizeTag(labelValues[i]))
+	}
+	return tags
+}
+
+func joinTags(tags, tag string) string {
+	if tags == "" {
+		return tag
+	}
+	return tags + "," + tag
+}
+
+var (
+	statsdNameReplacer = strings.NewReplacer(".", "_", ":", "_", "|", "_", "@", "_", "#", "_", ",", "_", " ", "_", "\n", "_")
+	statsdTagReplacer  = strings.NewReplacer("|", "_", ",", "_", "#", "_", " ", "_", "\n", "_")
+)
+
+func sanitizeTag(s string) string {
+	return statsdTagReplacer.Replace(s)
+}
+
+// statsdPacket batch lines in packets of at most maxSize bytes.
+type statsdPacket struct {
+	conn    net.Conn
+	maxSize int
+	buf     []byte
+	err     error
+}
+
+func (p *statsdPacket) add(name string, v float64, typ, tags string) {
+	line := name + ":" + formatFloat(v) + "|" + typ
+	if tags != "" {
+		line += "|#" + tags
+	}
+
+	if len(p.buf) > 0 && len(p.buf)+1+len(line) > p.maxSize {
+		p.flush()
+	}
+	if len(p.buf) > 0 {
+		p.buf = append(p.buf, '\n')
+	}
+	p.buf = append(p.buf, line...)
+}
+
+// flush send the current packet, keeping the first error.
+func (p *statsdPacket) flush() error {
+	if len(p.buf) > 0 {
+		if _, err := p.conn.Write(p.buf); err != nil && p.err == nil {
+			p.err = err
+		}
+		p.buf = p.buf[:0]
+	}
+	return p.err
+}
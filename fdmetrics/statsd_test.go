@@ -0,0 +1,178 @@
+package fdmetrics_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/fdmetrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	fdmetrics.SetLogger(log.New(ioutil.Discard, "", 0))
+}
+
+// statsdAgent listen to UDP packets like a local agent.
+type statsdAgent struct {
+	conn net.PacketConn
+}
+
+func newStatsDAgent(t *testing.T) *statsdAgent {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &statsdAgent{conn: conn}
+}
+
+func (a *statsdAgent) Addr() string {
+	return a.conn.LocalAddr().String()
+}
+
+// packets read all packets received until nothing arrives for a while.
+func (a *statsdAgent) packets() []string {
+	var packets []string
+	buf := make([]byte, 65536)
+	for {
+		a.conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, _, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			return packets
+		}
+		packets = append(packets, string(buf[:n]))
+	}
+}
+
+// lines return all lines received, sorted.
+func (a *statsdAgent) lines() []string {
+	var lines []string
+	for _, p := range a.packets() {
+		lines = append(lines, strings.Split(p, "\n")...)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestStatsD_Flush(t *testing.T) {
+	agent := newStatsDAgent(t)
+	defer agent.conn.Close()
+
+	registry := fdmetrics.NewRegistry()
+	counter := registry.NewCounter("requests_total", "", "method", "route")
+	gauge := registry.NewGauge("queue_size", "")
+	histogram := registry.NewHistogram("latency_seconds", "", []float64{0.1, 1}, "route")
+
+	statsd, err := fdmetrics.NewStatsD(registry, fdmetrics.StatsDConfig{
+		Addr:   agent.Addr(),
+		Prefix: "orders.",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	counter.Inc("GET", "get.person")
+	counter.Inc("GET", "get.person")
+	gauge.Set(3)
+	histogram.Observe(0.5, "home")
+
+	assert.NoError(t, statsd.Flush())
+	assert.Equal(t, []string{
+		"orders.latency_seconds.home.count:1|c",
+		"orders.latency_seconds.home.sum:0.5|c",
+		"orders.queue_size:3|g",
+		"orders.requests_total.GET.get_person:2|c",
+	}, agent.lines())
+
+	// only changes since last flush are sent
+	counter.Inc("GET", "get.person")
+	assert.NoError(t, statsd.Flush())
+	assert.Equal(t, []string{
+		"orders.queue_size:3|g",
+		"orders.requests_total.GET.get_person:1|c",
+	}, agent.lines())
+}
+
+func TestStatsD_DogStatsD(t *testing.T) {
+	agent := newStatsDAgent(t)
+	defer agent.conn.Close()
+
+	registry := fdmetrics.NewRegistry()
+	counter := registry.NewCounter("requests_total", "", "route")
+	histogram := registry.NewHistogram("latency_seconds", "", []float64{0.1, 1}, "route")
+
+	statsd, _ := fdmetrics.NewStatsD(registry, fdmetrics.StatsDConfig{
+		Addr:      agent.Addr(),
+		DogStatsD: true,
+		Tags:      map[string]string{"env": "live", "app": "orders"},
+	})
+
+	counter.Inc("get_person")
+	histogram.Observe(0.5, "home")
+	histogram.Observe(5, "home")
+
+	assert.NoError(t, statsd.Flush())
+	assert.Equal(t, []string{
+		"latency_seconds.bucket:1|c|#app:orders,env:live,route:home,le:1",
+		"latency_seconds.bucket:2|c|#app:orders,env:live,route:home,le:+Inf",
+		"latency_seconds.count:2|c|#app:orders,env:live,route:home",
+		"latency_seconds.sum:5.5|c|#app:orders,env:live,route:home",
+		"requests_total:1|c|#app:orders,env:live,route:get_person",
+	}, agent.lines())
+}
+
+func TestStatsD_MaxPacketSize(t *testing.T) {
+	agent := newStatsDAgent(t)
+	defer agent.conn.Close()
+
+	registry := fdmetrics.NewRegistry()
+	gauge := registry.NewGauge("queue_size", "", "queue")
+	for _, q := range []string{"a", "b", "c", "d"} {
+		gauge.Set(1, q)
+	}
+
+	statsd, _ := fdmetrics.NewStatsD(registry, fdmetrics.StatsDConfig{
+		Addr:          agent.Addr(),
+		MaxPacketSize: 40,
+	})
+
+	assert.NoError(t, statsd.Flush())
+	assert.Equal(t, []string{
+		"queue_size.a:1|g\nqueue_size.b:1|g",
+		"queue_size.c:1|g\nqueue_size.d:1|g",
+	}, agent.packets())
+}
+
+func TestStatsD_StartStop(t *testing.T) {
+	agent := newStatsDAgent(t)
+	defer agent.conn.Close()
+
+	registry := fdmetrics.NewRegistry()
+	counter := registry.NewCounter("jobs_total", "")
+
+	statsd, _ := fdmetrics.NewStatsD(registry, fdmetrics.StatsDConfig{
+		Addr:          agent.Addr(),
+		FlushInterval: 10 * time.Millisecond,
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- statsd.Start()
+	}()
+
+	counter.Inc()
+	assert.Equal(t, []string{"jobs_total:1|c"}, agent.lines())
+
+	// metrics changed after the last flush are sent when stopping
+	counter.Inc()
+	assert.NoError(t, statsd.Stop(context.Background()))
+	assert.NoError(t, <-done)
+	assert.Equal(t, []string{"jobs_total:1|c"}, agent.lines())
+
+	assert.Error(t, statsd.Stop(context.Background()))
+}
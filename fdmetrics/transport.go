@@ -0,0 +1,38 @@
+package fdmetrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/foodora/go-ranger/fdhttp/fdmiddleware"
+)
+
+// ClientMiddleware record outbound requests by host, it can be used with
+// fdhttp.ClientImpl.Use. Requests that fail without response have status "error".
+//
+//  http_client_requests_total{method,host,status}
+//  http_client_request_duration_seconds{method,host}
+func ClientMiddleware(registry *Registry) fdmiddleware.ClientMiddleware {
+	requests := registry.NewCounter("http_client_requests_total",
+		"Number of HTTP requests sent.", "method", "host", "status")
+	duration := registry.NewHistogram("http_client_request_duration_seconds",
+		"Latency of HTTP requests sent.", nil, "method", "host")
+
+	return fdmiddleware.ClientMiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
+		return fdmiddleware.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			started := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			status := "error"
+			if err == nil {
+				status = strconv.Itoa(resp.StatusCode)
+			}
+
+			requests.Inc(req.Method, req.URL.Host, status)
+			duration.Observe(time.Since(started).Seconds(), req.Method, req.URL.Host)
+
+			return resp, err
+		})
+	})
+}
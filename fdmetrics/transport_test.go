@@ -0,0 +1,42 @@
+package fdmetrics_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/foodora/go-ranger/fdhttp"
+	"github.com/foodora/go-ranger/fdmetrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientMiddleware(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	u, _ := url.Parse(ts.URL)
+
+	registry := fdmetrics.NewRegistry()
+
+	client := fdhttp.NewClient()
+	client.Use(fdmetrics.ClientMiddleware(registry))
+
+	resp, err := client.Get(ts.URL + "/orders")
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+
+	ts.Close()
+	_, err = client.Get(ts.URL + "/orders")
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	registry.WritePrometheus(&buf)
+	metrics := buf.String()
+
+	assert.Contains(t, metrics, `http_client_requests_total{method="GET",host="`+u.Host+`",status="202"} 1`)
+	assert.Contains(t, metrics, `http_client_requests_total{method="GET",host="`+u.Host+`",status="error"} 1`)
+	assert.Contains(t, metrics, `http_client_request_duration_seconds_count{method="GET",host="`+u.Host+`"} 2`)
+}
@@ -0,0 +1,226 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var _ Publisher = &AsyncPublisher{}
+
+// Defaults used by NewAsyncPublisher when AsyncPublisherConfig fields are not
+// informed.
+var (
+	DefaultAsyncBatchSize     = 10
+	DefaultAsyncFlushInterval = time.Second
+	DefaultAsyncQueueSize     = 1000
+)
+
+// ErrPublisherClosed is returned when publishing after Close.
+var ErrPublisherClosed = errors.New("pubsub: publisher is closed")
+
+// AsyncPublisherConfig configure how messages are buffered.
+type AsyncPublisherConfig struct {
+	// BatchSize is the number of messages sent at once.
+	BatchSize int
+	// FlushInterval is the maximum time a message waits to be sent.
+	FlushInterval time.Duration
+	// QueueSize is the maximum number of messages waiting to be sent, publish
+	// blocks when the queue is full.
+	QueueSize int
+	// OnError is called for each message that can't be published, by default
+	// errors are logged.
+	OnError func(PublishResult)
+	// Propagator add values of the context as message attributes when it's
+	// queued, it should be the same used by the underlying publisher, like
+	// awspub.SNSConfig.Propagator. By default DefaultPropagator is used.
+	Propagator Propagator
+}
+
+// AsyncPublisher buffer messages and publish them in batches in background,
+// using PublishBatch of the underlying publisher. Publish only fails when the
+// queue is full until ctx is done, or the publisher is closed:
+//
+//  async := pubsub.NewAsyncPublisher(pub, pubsub.AsyncPublisherConfig{})
+//  defer async.Close(ctx)
+//
+// Batches are published in background with their own context, so values of
+// the publish context, like trace ids, are injected as attributes by
+// AsyncPublisherConfig.Propagator when the message is queued.
+type AsyncPublisher struct {
+	pub Publisher
+	cfg AsyncPublisherConfig
+
+	mu     sync.RWMutex
+	closed bool
+
+	queue chan *OutgoingMessage
+	flush chan chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewAsyncPublisher create the publisher and start sending messages.
+func NewAsyncPublisher(pub Publisher, cfg AsyncPublisherConfig) *AsyncPublisher {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultAsyncBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultAsyncFlushInterval
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultAsyncQueueSize
+	}
+
+	p := &AsyncPublisher{
+		pub:   pub,
+		cfg:   cfg,
+		queue: make(chan *OutgoingMessage, cfg.QueueSize),
+		flush: make(chan chan struct{}),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+
+	return p
+}
+
+// Publish queue the message to the default topic.
+func (p *AsyncPublisher) Publish(ctx context.Context, key string, m string) error {
+	return p.PublishMessage(ctx, &OutgoingMessage{
+		Key:  key,
+		Body: m,
+	})
+}
+
+// PublishToTopic queue the message to the specified topic.
+func (p *AsyncPublisher) PublishToTopic(ctx context.Context, key string, m string, topic string) error {
+	return p.PublishMessage(ctx, &OutgoingMessage{
+		Key:   key,
+		Body:  m,
+		Topic: topic,
+	})
+}
+
+// PublishMessage queue the message, blocking while the queue is full.
+func (p *AsyncPublisher) PublishMessage(ctx context.Context, m *OutgoingMessage) error {
+	msg := *m
+	msg.Attributes = InjectAttributes(ctx, p.cfg.Propagator, m.Attributes)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrPublisherClosed
+	}
+
+	select {
+	case p.queue <- &msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush publish all queued messages.
+func (p *AsyncPublisher) Flush(ctx context.Context) error {
+	reply := make(chan struct{})
+
+	select {
+	case p.flush <- reply:
+	case <-p.done:
+		return ErrPublisherClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-reply:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close publish queued messages and stop the publisher, messages can't be
+// published anymore.
+func (p *AsyncPublisher) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPublisherClosed
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	close(p.stop)
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *AsyncPublisher) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*OutgoingMessage, 0, p.cfg.BatchSize)
+
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		p.send(batch)
+		batch = make([]*OutgoingMessage, 0, p.cfg.BatchSize)
+	}
+
+	// drain publish all messages waiting in the queue
+	drain := func() {
+		for {
+			select {
+			case m := <-p.queue:
+				batch = append(batch, m)
+				if len(batch) >= p.cfg.BatchSize {
+					send()
+				}
+			default:
+				send()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case m := <-p.queue:
+			batch = append(batch, m)
+			if len(batch) >= p.cfg.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case reply := <-p.flush:
+			drain()
+			close(reply)
+		case <-p.stop:
+			drain()
+			return
+		}
+	}
+}
+
+func (p *AsyncPublisher) send(batch []*OutgoingMessage) {
+	for _, res := range PublishBatch(context.Background(), p.pub, batch).Failed() {
+		if p.cfg.OnError != nil {
+			p.cfg.OnError(res)
+			continue
+		}
+		DefaultLogger.Printf("Unable to publish message: %s", res.Err)
+	}
+}
@@ -0,0 +1,164 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+// testBatchPublisher record each batch and block while gate is not closed.
+type testBatchPublisher struct {
+	testPublisher
+	mu      sync.Mutex
+	batches [][]*pubsub.OutgoingMessage
+	gate    chan struct{}
+}
+
+func (p *testBatchPublisher) PublishBatch(ctx context.Context, msgs []*pubsub.OutgoingMessage) pubsub.PublishResults {
+	if p.gate != nil {
+		<-p.gate
+	}
+
+	p.mu.Lock()
+	p.batches = append(p.batches, msgs)
+	p.mu.Unlock()
+
+	results := make(pubsub.PublishResults, len(msgs))
+	for i, m := range msgs {
+		results[i] = pubsub.PublishResult{Message: m}
+		if m.Body == "fail" {
+			results[i].Err = errors.New("unable to publish")
+		}
+	}
+	return results
+}
+
+func (p *testBatchPublisher) sizes() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sizes := make([]int, len(p.batches))
+	for i, b := range p.batches {
+		sizes[i] = len(b)
+	}
+	return sizes
+}
+
+func TestPublishBatchFallback(t *testing.T) {
+	pub := &testPublisher{}
+	msgs := []*pubsub.OutgoingMessage{{Body: "1"}, {Body: "2"}}
+
+	results := pubsub.PublishBatch(context.Background(), pub, msgs)
+	assert.NoError(t, results.Err())
+	assert.Len(t, results, 2)
+	assert.Equal(t, msgs, pub.messages())
+
+	pub.err = errors.New("topic does not exist")
+	results = pubsub.PublishBatch(context.Background(), pub, msgs)
+	assert.Len(t, results.Failed(), 2)
+	assert.EqualError(t, results.Err(), "pubsub: 2 of 2 messages not published, first error: topic does not exist")
+}
+
+func TestAsyncPublisher(t *testing.T) {
+	pub := &testBatchPublisher{}
+
+	var (
+		mu     sync.Mutex
+		failed []pubsub.PublishResult
+	)
+	async := pubsub.NewAsyncPublisher(pub, pubsub.AsyncPublisherConfig{
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+		OnError: func(res pubsub.PublishResult) {
+			mu.Lock()
+			failed = append(failed, res)
+			mu.Unlock()
+		},
+	})
+
+	ctx := pubsub.SetCorrelationID(context.Background(), "req-123")
+	for i := 0; i < 7; i++ {
+		assert.NoError(t, async.Publish(ctx, "", strconv.Itoa(i)))
+	}
+	assert.NoError(t, async.Publish(ctx, "", "fail"))
+
+	assert.NoError(t, async.Flush(context.Background()))
+	assert.Equal(t, []int{3, 3, 2}, pub.sizes())
+	assert.Equal(t, "req-123", pub.batches[0][0].Attributes.Get(pubsub.CorrelationIDAttribute))
+
+	mu.Lock()
+	assert.Len(t, failed, 1)
+	mu.Unlock()
+
+	assert.NoError(t, async.PublishToTopic(ctx, "", "last", "orders"))
+	assert.NoError(t, async.Close(context.Background()))
+	assert.Equal(t, []int{3, 3, 2, 1}, pub.sizes())
+	assert.Equal(t, "orders", pub.batches[3][0].Topic)
+
+	assert.Equal(t, pubsub.ErrPublisherClosed, async.Publish(ctx, "", "closed"))
+	assert.Equal(t, pubsub.ErrPublisherClosed, async.Close(context.Background()))
+	assert.Equal(t, pubsub.ErrPublisherClosed, async.Flush(context.Background()))
+}
+
+func TestAsyncPublisherPropagator(t *testing.T) {
+	pub := &testBatchPublisher{}
+	async := pubsub.NewAsyncPublisher(pub, pubsub.AsyncPublisherConfig{
+		FlushInterval: time.Hour,
+		Propagator:    testPropagator{key: "trace_id", value: "abc"},
+	})
+
+	ctx := pubsub.SetCorrelationID(context.Background(), "req-123")
+	assert.NoError(t, async.Publish(ctx, "", "1"))
+	assert.NoError(t, async.Close(context.Background()))
+
+	attrs := pub.batches[0][0].Attributes
+	assert.Equal(t, "abc", attrs.Get("trace_id"))
+	assert.Equal(t, "", attrs.Get(pubsub.CorrelationIDAttribute))
+}
+
+func TestAsyncPublisherFlushInterval(t *testing.T) {
+	pub := &testBatchPublisher{}
+	async := pubsub.NewAsyncPublisher(pub, pubsub.AsyncPublisherConfig{
+		BatchSize:     10,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	defer async.Close(context.Background())
+
+	assert.NoError(t, async.Publish(context.Background(), "", "1"))
+
+	deadline := time.Now().Add(time.Second)
+	for len(pub.sizes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, []int{1}, pub.sizes())
+}
+
+func TestAsyncPublisherBackpressure(t *testing.T) {
+	pub := &testBatchPublisher{gate: make(chan struct{})}
+	async := pubsub.NewAsyncPublisher(pub, pubsub.AsyncPublisherConfig{
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		QueueSize:     1,
+	})
+
+	// first message is being sent and the second one fills the queue
+	assert.NoError(t, async.Publish(context.Background(), "", "1"))
+	assert.NoError(t, async.Publish(context.Background(), "", "2"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var err error
+	for err == nil {
+		err = async.Publish(ctx, "", "blocked")
+	}
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	close(pub.gate)
+	assert.NoError(t, async.Close(context.Background()))
+}
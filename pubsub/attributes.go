@@ -0,0 +1,142 @@
+package pubsub
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Data types of message attributes, compatible with SNS and SQS.
+const (
+	AttributeTypeString = "String"
+	AttributeTypeNumber = "Number"
+	AttributeTypeBinary = "Binary"
+)
+
+// Attribute is a typed value sent with the message, apart from the body.
+// Numbers are kept as strings, that way no precision is lost.
+type Attribute struct {
+	Type   string
+	Value  string
+	Binary []byte
+}
+
+// StringAttribute create an attribute of type String.
+func StringAttribute(v string) Attribute {
+	return Attribute{Type: AttributeTypeString, Value: v}
+}
+
+// IntAttribute create an attribute of type Number.
+func IntAttribute(v int64) Attribute {
+	return Attribute{Type: AttributeTypeNumber, Value: strconv.FormatInt(v, 10)}
+}
+
+// FloatAttribute create an attribute of type Number.
+func FloatAttribute(v float64) Attribute {
+	return Attribute{Type: AttributeTypeNumber, Value: strconv.FormatFloat(v, 'f', -1, 64)}
+}
+
+// BinaryAttribute create an attribute of type Binary.
+func BinaryAttribute(v []byte) Attribute {
+	return Attribute{Type: AttributeTypeBinary, Binary: v}
+}
+
+// String return the value of String and Number attributes.
+func (a Attribute) String() string {
+	return a.Value
+}
+
+// Int parse the value of a Number attribute.
+func (a Attribute) Int() (int64, error) {
+	return strconv.ParseInt(a.Value, 10, 64)
+}
+
+// Float parse the value of a Number attribute.
+func (a Attribute) Float() (float64, error) {
+	return strconv.ParseFloat(a.Value, 64)
+}
+
+// Attributes of a message by name.
+type Attributes map[string]Attribute
+
+// Get return the value of a String or Number attribute, or an empty string if
+// it doesn't exist.
+func (a Attributes) Get(name string) string {
+	return a[name].Value
+}
+
+// Strings return String and Number attributes, they're the ones used by
+// Propagator.
+func (a Attributes) Strings() map[string]string {
+	m := make(map[string]string, len(a))
+	for k, v := range a {
+		if v.Type != AttributeTypeBinary {
+			m[k] = v.Value
+		}
+	}
+	return m
+}
+
+// OutgoingMessage is a message to be published with Publisher.PublishMessage.
+type OutgoingMessage struct {
+	// Key is used as message subject, it's optional.
+	Key string
+	// Body of the message.
+	Body string
+	// Topic the message is sent to, the default one of the publisher is used
+	// when it's empty.
+	Topic string
+	// Attributes sent with the message, values injected by the Propagator are
+	// added to them.
+	Attributes Attributes
+	// GroupID is the message group of FIFO queues, messages of the same group
+	// are delivered in order.
+	GroupID string
+	// DeduplicationID is used by FIFO queues to discard messages sent again,
+	// it's required when the queue doesn't use content-based deduplication.
+	DeduplicationID string
+}
+
+// SubjectAttribute carry the message key when it's sent directly to a queue,
+// brokers like SNS have their own field for it.
+const SubjectAttribute = "subject"
+
+// Delivery is the metadata of a received message, it's available in the
+// message context:
+//
+//  if d, ok := pubsub.DeliveryFromContext(msg.Context()); ok {
+//  	log.Printf("message %s published at %s", d.MessageID, d.PublishedAt)
+//  }
+type Delivery struct {
+	// MessageID is the id given by the broker, like the SNS message id when
+	// the message was published to a topic.
+	MessageID string
+	// Topic is the topic the message was published to, if known.
+	Topic string
+	// Subject is the key used to publish the message.
+	Subject string
+	// Queue the message was received from.
+	Queue string
+	// GroupID is the message group of FIFO queues.
+	GroupID string
+	// ReceiveCount is the number of times the message was received.
+	ReceiveCount int
+	// PublishedAt is when the message was published.
+	PublishedAt time.Time
+	// FirstReceivedAt is when the message was received for the first time.
+	FirstReceivedAt time.Time
+}
+
+type deliveryKey struct{}
+
+// SetDelivery return a copy of ctx with the delivery metadata, it's used by
+// subscribers.
+func SetDelivery(ctx context.Context, d *Delivery) context.Context {
+	return context.WithValue(ctx, deliveryKey{}, d)
+}
+
+// DeliveryFromContext return the delivery metadata of the message.
+func DeliveryFromContext(ctx context.Context) (*Delivery, bool) {
+	d, ok := ctx.Value(deliveryKey{}).(*Delivery)
+	return d, ok
+}
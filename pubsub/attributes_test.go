@@ -0,0 +1,48 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/foodora/go-ranger/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributes(t *testing.T) {
+	attrs := pubsub.Attributes{
+		"event":    pubsub.StringAttribute("order_created"),
+		"quantity": pubsub.IntAttribute(3),
+		"amount":   pubsub.FloatAttribute(12.5),
+		"raw":      pubsub.BinaryAttribute([]byte{1, 2}),
+	}
+
+	assert.Equal(t, "order_created", attrs.Get("event"))
+	assert.Equal(t, "", attrs.Get("unknown"))
+
+	n, err := attrs["quantity"].Int()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	f, err := attrs["amount"].Float()
+	assert.NoError(t, err)
+	assert.Equal(t, 12.5, f)
+
+	_, err = attrs["event"].Int()
+	assert.Error(t, err)
+
+	assert.Equal(t, map[string]string{
+		"event":    "order_created",
+		"quantity": "3",
+		"amount":   "12.5",
+	}, attrs.Strings())
+}
+
+func TestDeliveryFromContext(t *testing.T) {
+	_, ok := pubsub.DeliveryFromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := pubsub.SetDelivery(context.Background(), &pubsub.Delivery{MessageID: "123"})
+	d, ok := pubsub.DeliveryFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "123", d.MessageID)
+}
@@ -1,18 +1,74 @@
 package awspub
 
-import "github.com/aws/aws-sdk-go/aws"
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/foodora/go-ranger/pubsub"
+)
+
+// DefaultRequestTimeout is the maximum time of each AWS call when
+// RequestTimeout is not informed.
+var DefaultRequestTimeout = 10 * time.Second
 
 // SNSConfig holds the info required to work with Amazon SNS.
 type SNSConfig struct {
 	aws.Config
 
 	Topic string
+
+	// RequestTimeout will override the DefaultRequestTimeout, the deadline
+	// of the publish context is also respected. Use a negative value to
+	// disable it.
+	RequestTimeout time.Duration
+
+	// Propagator add values of the context, like trace ids, as message
+	// attributes. By default pubsub.DefaultPropagator is used.
+	Propagator pubsub.Propagator
 }
 
 // NewSNSConfig return a SNSConfig instance to work with
 func NewSNSConfig(config aws.Config, topic string) SNSConfig {
 	return SNSConfig{
-		config,
-		topic,
+		Config: config,
+		Topic:  topic,
+	}
+}
+
+// SQSConfig holds the info required to publish directly to an Amazon SQS
+// queue.
+type SQSConfig struct {
+	aws.Config
+
+	QueueName           string
+	QueueOwnerAccountID string
+	// QueueURL can be used instead of QueueName and QueueOwnerAccountID.
+	// If provided, the client will skip the "GetQueueUrl" call to AWS.
+	QueueURL string
+
+	// RequestTimeout will override the DefaultRequestTimeout, the deadline
+	// of the publish context is also respected. Use a negative value to
+	// disable it.
+	RequestTimeout time.Duration
+
+	// Propagator add values of the context, like trace ids, as message
+	// attributes. By default pubsub.DefaultPropagator is used.
+	Propagator pubsub.Propagator
+}
+
+func requestTimeout(d time.Duration) time.Duration {
+	if d == 0 {
+		return DefaultRequestTimeout
+	}
+	return d
+}
+
+// requestContext return a context to call AWS, bounded by timeout when it's
+// positive.
+func requestContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, timeout)
 }
@@ -8,14 +8,18 @@ import (
 	"github.com/aws/aws-sdk-go/service/sns"
 	"github.com/aws/aws-sdk-go/service/sns/snsiface"
 	"github.com/foodora/go-ranger/pubsub"
+	"strconv"
+	"time"
 )
 
 // publisher will accept AWS configuration and an SNS topic name
 // and it will emit any publish events to it.
 type publisher struct {
-	sns    snsiface.SNSAPI
-	topic  string
-	Logger pubsub.Logger
+	sns        snsiface.SNSAPI
+	topic      string
+	propagator pubsub.Propagator
+	timeout    time.Duration
+	Logger     pubsub.Logger
 }
 
 // NewPublisher will initiate the SNS client.
@@ -24,6 +28,8 @@ func NewPublisher(cfg SNSConfig) (pubsub.Publisher, error) {
 	p.Logger = pubsub.DefaultLogger
 
 	p.topic = cfg.Topic
+	p.propagator = cfg.Propagator
+	p.timeout = requestTimeout(cfg.RequestTimeout)
 
 	if cfg.Region == nil {
 		return p, errors.New("SNS region is required")
@@ -44,30 +50,205 @@ func NewPublisher(cfg SNSConfig) (pubsub.Publisher, error) {
 // Publish send the message to the default SNS topic of the publisher.
 // The key will be used as the SNS message subject which is optional.
 func (p *publisher) Publish(ctx context.Context, key string, m string) error {
+	return p.PublishMessage(ctx, &pubsub.OutgoingMessage{
+		Key:  key,
+		Body: m,
+	})
+}
 
-	if p.topic == "" {
-		return errors.New("default sns topic not configured")
+// Publish send the message to the specified SNS topic.
+// The key will be used as the SNS message subject which is optional.
+func (p *publisher) PublishToTopic(ctx context.Context, key string, m string, topic string) error {
+	if topic == "" {
+		return errors.New("sns topic not informed")
 	}
 
-	msg := &sns.PublishInput{
-		TopicArn: &p.topic,
-		Subject:  &key, //optional
-		Message:  aws.String(m),
-	}
+	return p.PublishMessage(ctx, &pubsub.OutgoingMessage{
+		Key:   key,
+		Body:  m,
+		Topic: topic,
+	})
+}
 
-	_, err := p.sns.Publish(msg)
+// PublishMessage send the message to its topic, or the default one of the
+// publisher. Attributes are sent as SNS message attributes, SQS subscribers
+// receive them as message attributes when raw message delivery is enabled,
+// otherwise they are in the notification sent as body.
+func (p *publisher) PublishMessage(ctx context.Context, m *pubsub.OutgoingMessage) error {
+	_, err := p.publishMessage(ctx, m)
 	return err
 }
 
-// Publish send the message to the specified SNS topic.
-// The key will be used as the SNS message subject which is optional.
-func (p *publisher) PublishToTopic(ctx context.Context, key string, m string, topic string) error {
+// maxBatchSize is the maximum number of messages sent in a single SNS
+// PublishBatch or SQS SendMessageBatch request.
+const maxBatchSize = 10
+
+// PublishBatch send messages with SNS PublishBatch, up to maxBatchSize
+// messages of the same topic per request. It implements
+// pubsub.BatchPublisher.
+func (p *publisher) PublishBatch(ctx context.Context, msgs []*pubsub.OutgoingMessage) pubsub.PublishResults {
+	results := make(pubsub.PublishResults, len(msgs))
+
+	var topics []string
+	byTopic := make(map[string][]int)
+	for i, m := range msgs {
+		results[i].Message = m
+
+		topic, err := p.topicOf(m)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		if _, ok := byTopic[topic]; !ok {
+			topics = append(topics, topic)
+		}
+		byTopic[topic] = append(byTopic[topic], i)
+	}
+
+	for _, topic := range topics {
+		indexes := byTopic[topic]
+		for start := 0; start < len(indexes); start += maxBatchSize {
+			end := start + maxBatchSize
+			if end > len(indexes) {
+				end = len(indexes)
+			}
+			p.publishBatch(ctx, topic, msgs, indexes[start:end], results)
+		}
+	}
+
+	return results
+}
+
+func (p *publisher) publishBatch(ctx context.Context, topic string, msgs []*pubsub.OutgoingMessage, indexes []int, results pubsub.PublishResults) {
+	entries := make([]*sns.PublishBatchRequestEntry, len(indexes))
+	for i, idx := range indexes {
+		m := msgs[idx]
+		entries[i] = &sns.PublishBatchRequestEntry{
+			// entry ids are the index of the message in the batch
+			Id:                aws.String(strconv.Itoa(idx)),
+			Message:           aws.String(m.Body),
+			MessageAttributes: snsAttributes(pubsub.InjectAttributes(ctx, p.propagator, m.Attributes)),
+		}
+		if m.Key != "" {
+			entries[i].Subject = aws.String(m.Key)
+		}
+	}
+
+	ctx, cancel := requestContext(ctx, p.timeout)
+	defer cancel()
+
+	out, err := p.sns.PublishBatchWithContext(ctx, &sns.PublishBatchInput{
+		TopicArn:                   aws.String(topic),
+		PublishBatchRequestEntries: entries,
+	})
+	if err != nil {
+		for _, idx := range indexes {
+			results[idx].Err = err
+		}
+		return
+	}
+
+	for _, entry := range out.Successful {
+		idx, _ := strconv.Atoi(aws.StringValue(entry.Id))
+		results[idx].MessageID = aws.StringValue(entry.MessageId)
+	}
+	for _, entry := range out.Failed {
+		idx, _ := strconv.Atoi(aws.StringValue(entry.Id))
+		results[idx].Err = errors.New(aws.StringValue(entry.Code) + ": " + aws.StringValue(entry.Message))
+	}
+}
+
+// topicOf return the topic of m, or the default one of the publisher.
+func (p *publisher) topicOf(m *pubsub.OutgoingMessage) (string, error) {
+	topic := m.Topic
+	if topic == "" {
+		topic = p.topic
+	}
+	if topic == "" {
+		return "", errors.New("default sns topic not configured")
+	}
+	if m.GroupID != "" || m.DeduplicationID != "" {
+		return "", errors.New("sns fifo topics are not supported, use an sqs publisher")
+	}
+	return topic, nil
+}
+
+func (p *publisher) publishMessage(ctx context.Context, m *pubsub.OutgoingMessage) (string, error) {
+	topic, err := p.topicOf(m)
+	if err != nil {
+		return "", err
+	}
+
 	msg := &sns.PublishInput{
 		TopicArn: &topic,
-		Subject:  &key, //optional
-		Message:  aws.String(m),
+		Message:  aws.String(m.Body),
+
+		MessageAttributes: snsAttributes(pubsub.InjectAttributes(ctx, p.propagator, m.Attributes)),
+	}
+	if m.Key != "" {
+		msg.Subject = aws.String(m.Key) //optional
 	}
 
-	_, err := p.sns.Publish(msg)
-	return err
+	ctx, cancel := requestContext(ctx, p.timeout)
+	defer cancel()
+
+	out, err := p.sns.PublishWithContext(ctx, msg)
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.MessageId), nil
+}
+
+// snsAttributes convert attributes to SNS message attributes.
+func snsAttributes(attrs pubsub.Attributes) map[string]*sns.MessageAttributeValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	msgAttrs := make(map[string]*sns.MessageAttributeValue, len(attrs))
+	for k, v := range attrs {
+		value := &sns.MessageAttributeValue{
+			DataType: aws.String(v.Type),
+		}
+		if v.Type == pubsub.AttributeTypeBinary {
+			value.BinaryValue = v.Binary
+		} else {
+			value.StringValue = aws.String(v.Value)
+		}
+		msgAttrs[k] = value
+	}
+	return msgAttrs
+}
+
+// TopicStats is the detail returned by the publisher health check.
+type TopicStats struct {
+	TopicArn               string `json:"topic_arn"`
+	SubscriptionsConfirmed int64  `json:"subscriptions_confirmed"`
+	SubscriptionsPending   int64  `json:"subscriptions_pending"`
+}
+
+// HealthCheck check if the default topic is reachable, returning the number
+// of subscriptions as detail. It implements fdhandler.HealthChecker.
+func (p *publisher) HealthCheck(ctx context.Context) (interface{}, error) {
+	if p.topic == "" {
+		return nil, errors.New("default sns topic not configured")
+	}
+
+	out, err := p.sns.GetTopicAttributesWithContext(ctx, &sns.GetTopicAttributesInput{
+		TopicArn: &p.topic,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attr := func(name string) int64 {
+		n, _ := strconv.ParseInt(aws.StringValue(out.Attributes[name]), 10, 64)
+		return n
+	}
+
+	return &TopicStats{
+		TopicArn:               p.topic,
+		SubscriptionsConfirmed: attr("SubscriptionsConfirmed"),
+		SubscriptionsPending:   attr("SubscriptionsPending"),
+	}, nil
 }
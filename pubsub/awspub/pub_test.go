@@ -9,7 +9,10 @@ import (
 	"github.com/aws/aws-sdk-go/service/sns/snsiface"
 	"github.com/foodora/go-ranger/pubsub"
 	"github.com/stretchr/testify/assert"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 const DefaultTopic = "default-topic"
@@ -96,18 +99,279 @@ func TestPublisherToTopic(t *testing.T) {
 	}
 }
 
+func TestPublisherHealthCheck(t *testing.T) {
+	snstest := &TestSNSAPI{
+		Attributes: map[string]*string{
+			"SubscriptionsConfirmed": aws.String("2"),
+		},
+	}
+	pub := &publisher{
+		topic:  DefaultTopic,
+		sns:    snstest,
+		Logger: pubsub.DefaultLogger,
+	}
+
+	detail, err := pub.HealthCheck(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &TopicStats{
+		TopicArn:               DefaultTopic,
+		SubscriptionsConfirmed: 2,
+	}, detail)
+
+	snstest.Error = errors.New("topic does not exist")
+	_, err = pub.HealthCheck(context.Background())
+	assert.Error(t, err)
+
+	pub.topic = ""
+	_, err = pub.HealthCheck(context.Background())
+	assert.Error(t, err)
+}
+
+func TestPublisherRequestTimeout(t *testing.T) {
+	snstest := &TestSNSAPI{Block: true}
+	pub := &publisher{
+		topic:   DefaultTopic,
+		sns:     snstest,
+		timeout: 10 * time.Millisecond,
+		Logger:  pubsub.DefaultLogger,
+	}
+
+	err := pub.Publish(context.Background(), "subject", "message")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pub.timeout = time.Hour
+	snstest.Block = false
+	err = pub.Publish(ctx, "subject", "message")
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, snstest.Published)
+}
+
+func TestPublisherPropagation(t *testing.T) {
+	snstest := &TestSNSAPI{}
+	pub := &publisher{
+		topic:      DefaultTopic,
+		sns:        snstest,
+		propagator: pubsub.CorrelationIDPropagator{},
+		Logger:     pubsub.DefaultLogger,
+	}
+
+	ctx := pubsub.SetCorrelationID(context.Background(), "req-123")
+	assert.NoError(t, pub.Publish(ctx, "subject", "message"))
+	assert.NoError(t, pub.PublishToTopic(ctx, "subject", "message", "this-is-a-topic"))
+	assert.NoError(t, pub.Publish(context.Background(), "subject", "message"))
+
+	if !assert.Len(t, snstest.Published, 3) {
+		return
+	}
+	expected := map[string]*sns.MessageAttributeValue{
+		pubsub.CorrelationIDAttribute: {
+			DataType:    aws.String("String"),
+			StringValue: aws.String("req-123"),
+		},
+	}
+	assert.Equal(t, expected, snstest.Published[0].MessageAttributes)
+	assert.Equal(t, expected, snstest.Published[1].MessageAttributes)
+	assert.Nil(t, snstest.Published[2].MessageAttributes)
+}
+
+func TestPublishMessage(t *testing.T) {
+	snstest := &TestSNSAPI{}
+	pub := &publisher{
+		topic:      DefaultTopic,
+		sns:        snstest,
+		propagator: pubsub.CorrelationIDPropagator{},
+		Logger:     pubsub.DefaultLogger,
+	}
+
+	ctx := pubsub.SetCorrelationID(context.Background(), "req-123")
+	err := pub.PublishMessage(ctx, &pubsub.OutgoingMessage{
+		Body: "message",
+		Attributes: pubsub.Attributes{
+			"event":  pubsub.StringAttribute("order_created"),
+			"amount": pubsub.FloatAttribute(12.5),
+			"raw":    pubsub.BinaryAttribute([]byte{1, 2}),
+		},
+	})
+	assert.NoError(t, err)
+
+	err = pub.PublishMessage(context.Background(), &pubsub.OutgoingMessage{
+		Key:   "subject",
+		Body:  "message",
+		Topic: "this-is-a-topic",
+	})
+	assert.NoError(t, err)
+
+	if !assert.Len(t, snstest.Published, 2) {
+		return
+	}
+
+	assert.Equal(t, DefaultTopic, *snstest.Published[0].TopicArn)
+	assert.Nil(t, snstest.Published[0].Subject)
+	assert.Equal(t, map[string]*sns.MessageAttributeValue{
+		pubsub.CorrelationIDAttribute: {
+			DataType:    aws.String("String"),
+			StringValue: aws.String("req-123"),
+		},
+		"event": {
+			DataType:    aws.String("String"),
+			StringValue: aws.String("order_created"),
+		},
+		"amount": {
+			DataType:    aws.String("Number"),
+			StringValue: aws.String("12.5"),
+		},
+		"raw": {
+			DataType:    aws.String("Binary"),
+			BinaryValue: []byte{1, 2},
+		},
+	}, snstest.Published[0].MessageAttributes)
+
+	assert.Equal(t, "this-is-a-topic", *snstest.Published[1].TopicArn)
+	assert.Equal(t, "subject", *snstest.Published[1].Subject)
+	assert.Nil(t, snstest.Published[1].MessageAttributes)
+
+	pub.topic = ""
+	assert.Error(t, pub.PublishMessage(context.Background(), &pubsub.OutgoingMessage{Body: "message"}))
+}
+
+func TestPublishBatch(t *testing.T) {
+	snstest := &TestSNSAPI{FailTopic: "unknown-topic", FailBody: "7"}
+	pub := &publisher{
+		topic:      DefaultTopic,
+		sns:        snstest,
+		propagator: pubsub.CorrelationIDPropagator{},
+		Logger:     pubsub.DefaultLogger,
+	}
+
+	msgs := make([]*pubsub.OutgoingMessage, 25)
+	for i := range msgs {
+		msgs[i] = &pubsub.OutgoingMessage{Body: strconv.Itoa(i)}
+	}
+	msgs[0].Key = "subject"
+	msgs[12].Topic = "unknown-topic"
+	msgs[13].Topic = "this-is-a-topic"
+	msgs[14].GroupID = "payments"
+
+	ctx := pubsub.SetCorrelationID(context.Background(), "req-123")
+	results := pub.PublishBatch(ctx, msgs)
+	if !assert.Len(t, results, 25) {
+		return
+	}
+	assert.Empty(t, snstest.Published)
+
+	// 22 messages of the default topic in 3 requests, one of each other topic
+	if !assert.Len(t, snstest.Batches, 5) {
+		return
+	}
+	var sizes []int
+	for _, b := range snstest.Batches {
+		sizes = append(sizes, len(b.PublishBatchRequestEntries))
+	}
+	assert.Equal(t, []int{10, 10, 2, 1, 1}, sizes)
+	assert.Equal(t, DefaultTopic, *snstest.Batches[0].TopicArn)
+	assert.Equal(t, "unknown-topic", *snstest.Batches[3].TopicArn)
+	assert.Equal(t, "this-is-a-topic", *snstest.Batches[4].TopicArn)
+
+	entry := snstest.Batches[0].PublishBatchRequestEntries[0]
+	assert.Equal(t, "0", *entry.Id)
+	assert.Equal(t, "subject", *entry.Subject)
+	assert.Equal(t, "req-123", *entry.MessageAttributes[pubsub.CorrelationIDAttribute].StringValue)
+
+	for i, res := range results {
+		assert.Equal(t, msgs[i], res.Message)
+		switch i {
+		case 7:
+			assert.EqualError(t, res.Err, "InvalidParameter: invalid message")
+		case 12:
+			assert.EqualError(t, res.Err, "topic does not exist")
+		case 14:
+			assert.Error(t, res.Err)
+		default:
+			assert.NoError(t, res.Err)
+			assert.Equal(t, "id-"+strconv.Itoa(i), res.MessageID)
+		}
+	}
+	assert.Len(t, results.Failed(), 3)
+}
+
 type TestSNSAPI struct {
+	snsiface.SNSAPI
+
+	mu sync.Mutex
 	// Error will be returned by the API when Publish() is called.
 	Error error
+	// FailTopic make Publish() fail only for this topic.
+	FailTopic string
+	// FailBody make entries with this body fail in PublishBatch().
+	FailBody string
 	// Published allows users to inspect which values have been published.
 	Published []*sns.PublishInput
+	// Batches are the requests sent to PublishBatch().
+	Batches []*sns.PublishBatchInput
+	// Attributes are returned by GetTopicAttributesWithContext.
+	Attributes map[string]*string
+	// Block make PublishWithContext() wait until the context is done.
+	Block bool
 }
 
 var _ snsiface.SNSAPI = &TestSNSAPI{}
 
 func (t *TestSNSAPI) Publish(i *sns.PublishInput) (*sns.PublishOutput, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.FailTopic != "" && *i.TopicArn == t.FailTopic {
+		return nil, errors.New("topic does not exist")
+	}
+
 	t.Published = append(t.Published, i)
-	return &sns.PublishOutput{}, t.Error
+	return &sns.PublishOutput{MessageId: aws.String(strconv.Itoa(len(t.Published)))}, t.Error
+}
+
+func (t *TestSNSAPI) PublishWithContext(ctx aws.Context, i *sns.PublishInput, _ ...request.Option) (*sns.PublishOutput, error) {
+	if t.Block {
+		<-ctx.Done()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return t.Publish(i)
+}
+
+func (t *TestSNSAPI) PublishBatchWithContext(ctx aws.Context, i *sns.PublishBatchInput, _ ...request.Option) (*sns.PublishBatchOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.Batches = append(t.Batches, i)
+	if t.FailTopic != "" && *i.TopicArn == t.FailTopic {
+		return nil, errors.New("topic does not exist")
+	}
+	if t.Error != nil {
+		return nil, t.Error
+	}
+
+	out := &sns.PublishBatchOutput{}
+	for _, e := range i.PublishBatchRequestEntries {
+		if aws.StringValue(e.Message) == t.FailBody {
+			out.Failed = append(out.Failed, &sns.BatchResultErrorEntry{
+				Id:      e.Id,
+				Code:    aws.String("InvalidParameter"),
+				Message: aws.String("invalid message"),
+			})
+			continue
+		}
+		out.Successful = append(out.Successful, &sns.PublishBatchResultEntry{
+			Id:        e.Id,
+			MessageId: aws.String("id-" + aws.StringValue(e.Message)),
+		})
+	}
+	return out, nil
 }
 
 ///////////
@@ -276,7 +540,10 @@ func (t *TestSNSAPI) GetTopicAttributes(*sns.GetTopicAttributesInput) (*sns.GetT
 	return nil, errNotImpl
 }
 func (t *TestSNSAPI) GetTopicAttributesWithContext(aws.Context, *sns.GetTopicAttributesInput, ...request.Option) (*sns.GetTopicAttributesOutput, error) {
-	return nil, errNotImpl
+	if t.Attributes == nil {
+		return nil, errNotImpl
+	}
+	return &sns.GetTopicAttributesOutput{Attributes: t.Attributes}, t.Error
 }
 func (t *TestSNSAPI) ListEndpointsByPlatformApplicationRequest(*sns.ListEndpointsByPlatformApplicationInput) (*request.Request, *sns.ListEndpointsByPlatformApplicationOutput) {
 	return nil, nil
@@ -356,9 +623,6 @@ func (t *TestSNSAPI) ListTopicsPagesWithContext(aws.Context, *sns.ListTopicsInpu
 func (t *TestSNSAPI) PublishRequest(*sns.PublishInput) (*request.Request, *sns.PublishOutput) {
 	return nil, nil
 }
-func (t *TestSNSAPI) PublishWithContext(aws.Context, *sns.PublishInput, ...request.Option) (*sns.PublishOutput, error) {
-	return nil, errNotImpl
-}
 
 func (t *TestSNSAPI) RemovePermissionRequest(*sns.RemovePermissionInput) (*request.Request, *sns.RemovePermissionOutput) {
 	return nil, nil
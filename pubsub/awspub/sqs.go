@@ -0,0 +1,271 @@
+package awspub
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/foodora/go-ranger/pubsub"
+)
+
+// sqsPublisher send messages directly to an SQS queue, without SNS. Topics
+// are queue urls.
+type sqsPublisher struct {
+	sqs        sqsiface.SQSAPI
+	queueURL   string
+	propagator pubsub.Propagator
+	timeout    time.Duration
+	Logger     pubsub.Logger
+}
+
+var sqsClientFactoryFunc = createSqsClient
+
+func createSqsClient(cfg *SQSConfig) (sqsiface.SQSAPI, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	return sqs.New(sess, &aws.Config{
+		Region:   cfg.Region,
+		Endpoint: cfg.Endpoint, //optional
+	}), nil
+}
+
+// NewSQSPublisher will initiate the SQS client and fetch the queue url.
+// FIFO queues are supported, messages must have GroupID and, when the queue
+// doesn't use content-based deduplication, DeduplicationID.
+func NewSQSPublisher(cfg SQSConfig) (pubsub.Publisher, error) {
+	p := &sqsPublisher{
+		propagator: cfg.Propagator,
+		timeout:    requestTimeout(cfg.RequestTimeout),
+		Logger:     pubsub.DefaultLogger,
+	}
+
+	if cfg.QueueName == "" && cfg.QueueURL == "" {
+		return p, errors.New("sqs queue name or url is required")
+	}
+
+	sqsClient, err := sqsClientFactoryFunc(&cfg)
+	if err != nil {
+		return p, err
+	}
+	p.sqs = sqsClient
+
+	if cfg.QueueURL != "" {
+		p.queueURL = cfg.QueueURL
+		return p, nil
+	}
+
+	input := &sqs.GetQueueUrlInput{
+		QueueName: &cfg.QueueName,
+	}
+	if cfg.QueueOwnerAccountID != "" {
+		input.QueueOwnerAWSAccountId = &cfg.QueueOwnerAccountID
+	}
+	ctx, cancel := requestContext(context.Background(), p.timeout)
+	defer cancel()
+
+	urlResp, err := p.sqs.GetQueueUrlWithContext(ctx, input)
+	if err != nil {
+		return p, err
+	}
+	p.queueURL = aws.StringValue(urlResp.QueueUrl)
+
+	return p, nil
+}
+
+// Publish send the message to the queue of the publisher. The key is sent as
+// pubsub.SubjectAttribute.
+func (p *sqsPublisher) Publish(ctx context.Context, key string, m string) error {
+	return p.PublishMessage(ctx, &pubsub.OutgoingMessage{
+		Key:  key,
+		Body: m,
+	})
+}
+
+// PublishToTopic send the message to the queue url given as topic.
+func (p *sqsPublisher) PublishToTopic(ctx context.Context, key string, m string, topic string) error {
+	if topic == "" {
+		return errors.New("sqs queue url not informed")
+	}
+
+	return p.PublishMessage(ctx, &pubsub.OutgoingMessage{
+		Key:   key,
+		Body:  m,
+		Topic: topic,
+	})
+}
+
+// PublishMessage send the message to its queue url, or the one of the
+// publisher.
+func (p *sqsPublisher) PublishMessage(ctx context.Context, m *pubsub.OutgoingMessage) error {
+	input := &sqs.SendMessageInput{
+		QueueUrl:          aws.String(p.queue(m)),
+		MessageBody:       aws.String(m.Body),
+		MessageAttributes: p.attributes(ctx, m),
+	}
+	if m.GroupID != "" {
+		input.MessageGroupId = aws.String(m.GroupID)
+	}
+	if m.DeduplicationID != "" {
+		input.MessageDeduplicationId = aws.String(m.DeduplicationID)
+	}
+
+	ctx, cancel := requestContext(ctx, p.timeout)
+	defer cancel()
+
+	_, err := p.sqs.SendMessageWithContext(ctx, input)
+	return err
+}
+
+// PublishBatch send messages with SendMessageBatch, up to 10 messages of the
+// same queue per request. It implements pubsub.BatchPublisher.
+func (p *sqsPublisher) PublishBatch(ctx context.Context, msgs []*pubsub.OutgoingMessage) pubsub.PublishResults {
+	results := make(pubsub.PublishResults, len(msgs))
+
+	// keep the order of messages of each queue, it matters for FIFO queues
+	var queues []string
+	byQueue := make(map[string][]int)
+	for i, m := range msgs {
+		results[i].Message = m
+
+		queue := p.queue(m)
+		if _, ok := byQueue[queue]; !ok {
+			queues = append(queues, queue)
+		}
+		byQueue[queue] = append(byQueue[queue], i)
+	}
+
+	for _, queue := range queues {
+		indexes := byQueue[queue]
+		for start := 0; start < len(indexes); start += maxBatchSize {
+			end := start + maxBatchSize
+			if end > len(indexes) {
+				end = len(indexes)
+			}
+			p.sendBatch(ctx, queue, msgs, indexes[start:end], results)
+		}
+	}
+
+	return results
+}
+
+func (p *sqsPublisher) sendBatch(ctx context.Context, queue string, msgs []*pubsub.OutgoingMessage, indexes []int, results pubsub.PublishResults) {
+	entries := make([]*sqs.SendMessageBatchRequestEntry, len(indexes))
+	for i, idx := range indexes {
+		m := msgs[idx]
+		entries[i] = &sqs.SendMessageBatchRequestEntry{
+			// entry ids are the index of the message in the batch
+			Id:                aws.String(strconv.Itoa(idx)),
+			MessageBody:       aws.String(m.Body),
+			MessageAttributes: p.attributes(ctx, m),
+		}
+		if m.GroupID != "" {
+			entries[i].MessageGroupId = aws.String(m.GroupID)
+		}
+		if m.DeduplicationID != "" {
+			entries[i].MessageDeduplicationId = aws.String(m.DeduplicationID)
+		}
+	}
+
+	ctx, cancel := requestContext(ctx, p.timeout)
+	defer cancel()
+
+	out, err := p.sqs.SendMessageBatchWithContext(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: aws.String(queue),
+		Entries:  entries,
+	})
+	if err != nil {
+		for _, idx := range indexes {
+			results[idx].Err = err
+		}
+		return
+	}
+
+	for _, entry := range out.Successful {
+		idx, _ := strconv.Atoi(aws.StringValue(entry.Id))
+		results[idx].MessageID = aws.StringValue(entry.MessageId)
+	}
+	for _, entry := range out.Failed {
+		idx, _ := strconv.Atoi(aws.StringValue(entry.Id))
+		results[idx].Err = errors.New(aws.StringValue(entry.Code) + ": " + aws.StringValue(entry.Message))
+	}
+}
+
+func (p *sqsPublisher) queue(m *pubsub.OutgoingMessage) string {
+	if m.Topic != "" {
+		return m.Topic
+	}
+	return p.queueURL
+}
+
+// attributes return SQS message attributes of m, including the key.
+func (p *sqsPublisher) attributes(ctx context.Context, m *pubsub.OutgoingMessage) map[string]*sqs.MessageAttributeValue {
+	attrs := pubsub.InjectAttributes(ctx, p.propagator, m.Attributes)
+	if m.Key != "" {
+		attrs[pubsub.SubjectAttribute] = pubsub.StringAttribute(m.Key)
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	msgAttrs := make(map[string]*sqs.MessageAttributeValue, len(attrs))
+	for k, v := range attrs {
+		value := &sqs.MessageAttributeValue{
+			DataType: aws.String(v.Type),
+		}
+		if v.Type == pubsub.AttributeTypeBinary {
+			value.BinaryValue = v.Binary
+		} else {
+			value.StringValue = aws.String(v.Value)
+		}
+		msgAttrs[k] = value
+	}
+	return msgAttrs
+}
+
+// QueueStats is the detail returned by the SQS publisher health check.
+type QueueStats struct {
+	QueueURL           string `json:"queue_url"`
+	Messages           int64  `json:"messages"`
+	MessagesNotVisible int64  `json:"messages_not_visible"`
+	MessagesDelayed    int64  `json:"messages_delayed"`
+}
+
+// HealthCheck check if the queue of the publisher is reachable, returning the
+// approximate number of messages as detail. It implements
+// fdhandler.HealthChecker.
+func (p *sqsPublisher) HealthCheck(ctx context.Context) (interface{}, error) {
+	ctx, cancel := requestContext(ctx, p.timeout)
+	defer cancel()
+
+	out, err := p.sqs.GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl: aws.String(p.queueURL),
+		AttributeNames: aws.StringSlice([]string{
+			sqs.QueueAttributeNameApproximateNumberOfMessages,
+			sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+			sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed,
+		}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attr := func(name string) int64 {
+		n, _ := strconv.ParseInt(aws.StringValue(out.Attributes[name]), 10, 64)
+		return n
+	}
+
+	return &QueueStats{
+		QueueURL:           p.queueURL,
+		Messages:           attr(sqs.QueueAttributeNameApproximateNumberOfMessages),
+		MessagesNotVisible: attr(sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible),
+		MessagesDelayed:    attr(sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed),
+	}, nil
+}
@@ -0,0 +1,259 @@
+package awspub
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/foodora/go-ranger/fdhttp/fdhandler/fdhealth"
+	"github.com/foodora/go-ranger/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+const testQueueURL = "https://sqs.eu-west-1.amazonaws.com/123/payments.fifo"
+
+// TestSQSAPI only implements calls used by the publisher, the other ones
+// panic.
+type TestSQSAPI struct {
+	sqsiface.SQSAPI
+
+	mu      sync.Mutex
+	Sent    []*sqs.SendMessageInput
+	Batches []*sqs.SendMessageBatchInput
+	// FailBody make entries with this body fail in SendMessageBatch.
+	FailBody string
+	Err      error
+	// Attributes are returned by GetQueueAttributesWithContext.
+	Attributes map[string]*string
+}
+
+func (s *TestSQSAPI) GetQueueAttributesWithContext(ctx aws.Context, i *sqs.GetQueueAttributesInput, _ ...request.Option) (*sqs.GetQueueAttributesOutput, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return &sqs.GetQueueAttributesOutput{Attributes: s.Attributes}, nil
+}
+
+func (s *TestSQSAPI) GetQueueUrl(i *sqs.GetQueueUrlInput) (*sqs.GetQueueUrlOutput, error) {
+	return &sqs.GetQueueUrlOutput{QueueUrl: aws.String(testQueueURL)}, s.Err
+}
+
+func (s *TestSQSAPI) SendMessage(i *sqs.SendMessageInput) (*sqs.SendMessageOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Sent = append(s.Sent, i)
+	return &sqs.SendMessageOutput{}, s.Err
+}
+
+func (s *TestSQSAPI) GetQueueUrlWithContext(ctx aws.Context, i *sqs.GetQueueUrlInput, _ ...request.Option) (*sqs.GetQueueUrlOutput, error) {
+	return s.GetQueueUrl(i)
+}
+
+func (s *TestSQSAPI) SendMessageWithContext(ctx aws.Context, i *sqs.SendMessageInput, _ ...request.Option) (*sqs.SendMessageOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.SendMessage(i)
+}
+
+func (s *TestSQSAPI) SendMessageBatchWithContext(ctx aws.Context, i *sqs.SendMessageBatchInput, _ ...request.Option) (*sqs.SendMessageBatchOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.SendMessageBatch(i)
+}
+
+func (s *TestSQSAPI) SendMessageBatch(i *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Batches = append(s.Batches, i)
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	out := &sqs.SendMessageBatchOutput{}
+	for _, e := range i.Entries {
+		if aws.StringValue(e.MessageBody) == s.FailBody {
+			out.Failed = append(out.Failed, &sqs.BatchResultErrorEntry{
+				Id:      e.Id,
+				Code:    aws.String("InvalidParameterValue"),
+				Message: aws.String("invalid message"),
+			})
+			continue
+		}
+		out.Successful = append(out.Successful, &sqs.SendMessageBatchResultEntry{
+			Id:        e.Id,
+			MessageId: aws.String("id-" + aws.StringValue(e.MessageBody)),
+		})
+	}
+	return out, nil
+}
+
+func createSQSPublisher(cfg SQSConfig, sqstest sqsiface.SQSAPI) (pubsub.Publisher, error) {
+	sqsClientFactoryFunc = func(cfg *SQSConfig) (sqsiface.SQSAPI, error) {
+		return sqstest, nil
+	}
+
+	return NewSQSPublisher(cfg)
+}
+
+func TestNewSQSPublisher(t *testing.T) {
+	_, err := createSQSPublisher(SQSConfig{}, &TestSQSAPI{})
+	assert.Error(t, err)
+
+	pub, err := createSQSPublisher(SQSConfig{QueueName: "payments.fifo"}, &TestSQSAPI{})
+	assert.NoError(t, err)
+	assert.Equal(t, testQueueURL, pub.(*sqsPublisher).queueURL)
+
+	_, err = createSQSPublisher(SQSConfig{QueueName: "payments.fifo"}, &TestSQSAPI{Err: errors.New("queue does not exist")})
+	assert.Error(t, err)
+}
+
+func TestSQSPublisher(t *testing.T) {
+	sqstest := &TestSQSAPI{}
+	pub, err := createSQSPublisher(SQSConfig{
+		QueueURL:   testQueueURL,
+		Propagator: pubsub.CorrelationIDPropagator{},
+	}, sqstest)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx := pubsub.SetCorrelationID(context.Background(), "req-123")
+	assert.NoError(t, pub.PublishMessage(ctx, &pubsub.OutgoingMessage{
+		Key:             "payment_captured",
+		Body:            "payment",
+		GroupID:         "order-1",
+		DeduplicationID: "payment-1",
+		Attributes:      pubsub.Attributes{"amount": pubsub.FloatAttribute(12.5)},
+	}))
+	assert.NoError(t, pub.PublishToTopic(context.Background(), "", "other", "http://other-queue"))
+	assert.Error(t, pub.PublishToTopic(context.Background(), "", "other", ""))
+
+	if !assert.Len(t, sqstest.Sent, 2) {
+		return
+	}
+
+	assert.Equal(t, &sqs.SendMessageInput{
+		QueueUrl:               aws.String(testQueueURL),
+		MessageBody:            aws.String("payment"),
+		MessageGroupId:         aws.String("order-1"),
+		MessageDeduplicationId: aws.String("payment-1"),
+		MessageAttributes: map[string]*sqs.MessageAttributeValue{
+			pubsub.CorrelationIDAttribute: {
+				DataType:    aws.String("String"),
+				StringValue: aws.String("req-123"),
+			},
+			pubsub.SubjectAttribute: {
+				DataType:    aws.String("String"),
+				StringValue: aws.String("payment_captured"),
+			},
+			"amount": {
+				DataType:    aws.String("Number"),
+				StringValue: aws.String("12.5"),
+			},
+		},
+	}, sqstest.Sent[0])
+
+	assert.Equal(t, "http://other-queue", *sqstest.Sent[1].QueueUrl)
+	assert.Nil(t, sqstest.Sent[1].MessageAttributes)
+}
+
+func TestSQSPublisherBatch(t *testing.T) {
+	sqstest := &TestSQSAPI{FailBody: "5"}
+	pub, _ := createSQSPublisher(SQSConfig{QueueURL: testQueueURL}, sqstest)
+
+	msgs := make([]*pubsub.OutgoingMessage, 13)
+	for i := range msgs {
+		msgs[i] = &pubsub.OutgoingMessage{Body: strconv.Itoa(i), GroupID: "order-1"}
+	}
+	msgs[12].Topic = "http://other-queue"
+
+	results := pub.(pubsub.BatchPublisher).PublishBatch(context.Background(), msgs)
+	if !assert.Len(t, results, 13) {
+		return
+	}
+
+	if assert.Len(t, sqstest.Batches, 3) {
+		assert.Len(t, sqstest.Batches[0].Entries, 10)
+		assert.Len(t, sqstest.Batches[1].Entries, 2)
+		assert.Equal(t, "http://other-queue", *sqstest.Batches[2].QueueUrl)
+
+		// order is kept for FIFO queues
+		for i, e := range sqstest.Batches[0].Entries {
+			assert.Equal(t, strconv.Itoa(i), *e.MessageBody)
+			assert.Equal(t, "order-1", *e.MessageGroupId)
+		}
+	}
+
+	for i, res := range results {
+		assert.Equal(t, msgs[i], res.Message)
+		if i == 5 {
+			assert.EqualError(t, res.Err, "InvalidParameterValue: invalid message")
+			continue
+		}
+		assert.NoError(t, res.Err)
+		assert.Equal(t, "id-"+strconv.Itoa(i), res.MessageID)
+	}
+
+	sqstest.Err = errors.New("queue does not exist")
+	results = pub.(pubsub.BatchPublisher).PublishBatch(context.Background(), msgs[:2])
+	assert.Len(t, results.Failed(), 2)
+}
+
+func TestPublisherFIFONotSupported(t *testing.T) {
+	pub := &publisher{
+		topic: DefaultTopic,
+		sns:   &TestSNSAPI{},
+	}
+	err := pub.PublishMessage(context.Background(), &pubsub.OutgoingMessage{Body: "message", GroupID: "order-1"})
+	assert.Error(t, err)
+}
+
+func TestSQSPublisherContext(t *testing.T) {
+	sqstest := &TestSQSAPI{}
+	pub, err := createSQSPublisher(SQSConfig{QueueURL: testQueueURL}, sqstest)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, pub.Publish(ctx, "", "payment"))
+	results := pub.(pubsub.BatchPublisher).PublishBatch(ctx, []*pubsub.OutgoingMessage{{Body: "payment"}})
+	assert.Equal(t, context.Canceled, results[0].Err)
+	assert.Empty(t, sqstest.Sent)
+	assert.Empty(t, sqstest.Batches)
+}
+
+func TestSQSPublisherHealthCheck(t *testing.T) {
+	sqstest := &TestSQSAPI{
+		Attributes: map[string]*string{
+			sqs.QueueAttributeNameApproximateNumberOfMessages:           aws.String("3"),
+			sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible: aws.String("1"),
+		},
+	}
+	pub, err := createSQSPublisher(SQSConfig{QueueURL: testQueueURL}, sqstest)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	detail, err := fdhealth.Publisher(pub).HealthCheck(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &QueueStats{
+		QueueURL:           testQueueURL,
+		Messages:           3,
+		MessagesNotVisible: 1,
+	}, detail)
+
+	sqstest.Err = errors.New("queue does not exist")
+	_, err = fdhealth.Publisher(pub).HealthCheck(context.Background())
+	assert.Error(t, err)
+}
@@ -2,6 +2,7 @@ package awssub
 
 import (
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/foodora/go-ranger/pubsub"
 	"time"
 )
 
@@ -21,6 +22,16 @@ type SQSConfig struct {
 	SleepInterval time.Duration
 	// DeleteBufferSize will override the DefaultSQSDeleteBufferSize.
 	DeleteBufferSize *int
+	// RequestTimeout will override the DefaultSQSRequestTimeout, it's the
+	// maximum time of each AWS call besides TimeoutSeconds of receive calls.
+	// Use a negative value to disable it.
+	RequestTimeout time.Duration
+	// UnwrapSNSEnvelope make messages return the body published to SNS, instead
+	// of the notification sent by SNS when raw message delivery is disabled.
+	UnwrapSNSEnvelope bool
+	// Propagator build the context of each message from its attributes.
+	// By default pubsub.DefaultPropagator is used.
+	Propagator pubsub.Propagator
 }
 
 // NewSQSConfig return a SQSConfig instance to work with
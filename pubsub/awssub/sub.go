@@ -1,6 +1,9 @@
 package awssub
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
@@ -9,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 	"github.com/foodora/go-ranger/pubsub"
 	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 )
@@ -23,7 +27,7 @@ type (
 		queueURL *string
 
 		toDelete chan *deleteRequest
-		flush    chan chan error
+		flush    chan *flushRequest
 		// inFlight and stopped are signals to manage delete requests
 		// at shutdown.
 		inFlight uint64
@@ -32,6 +36,11 @@ type (
 		stop   chan chan error
 		sqsErr error
 
+		// ctx is canceled when the subscriber is stopped, interrupting
+		// receive calls and sleeps.
+		ctx    context.Context
+		cancel context.CancelFunc
+
 		Logger pubsub.Logger
 
 		// onErrorFunc is a func is being called when an error occurs
@@ -42,12 +51,22 @@ type (
 	subscriberMessage struct {
 		sub     *subscriber
 		message *sqs.Message
+		ctx     context.Context
+		attrs   pubsub.Attributes
+		// notification is the SNS envelope of the body, when raw message
+		// delivery is disabled in the subscription.
+		notification *snsNotification
 	}
 
 	deleteRequest struct {
 		entry   *sqs.DeleteMessageBatchRequestEntry
 		receipt chan error
 	}
+
+	flushRequest struct {
+		ctx context.Context
+		err chan error
+	}
 )
 
 var (
@@ -68,6 +87,10 @@ var (
 	// executing a 'delete batch' request.
 	defaultSQSDeleteBufferSize = 0
 
+	// defaultSQSRequestTimeout is the default time.Duration AWS calls
+	// can take, receive calls also wait up to TimeoutSeconds.
+	defaultSQSRequestTimeout = 10 * time.Second
+
 	defaultSQSConsumeBase64 = true
 )
 
@@ -89,6 +112,18 @@ func defaultSQSConfig(cfg *SQSConfig) {
 	if cfg.DeleteBufferSize == nil {
 		cfg.DeleteBufferSize = &defaultSQSDeleteBufferSize
 	}
+
+	if cfg.RequestTimeout == 0 {
+		cfg.RequestTimeout = defaultSQSRequestTimeout
+	}
+}
+
+// requestContext return a context to call AWS, bounded by RequestTimeout.
+func (s *subscriber) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.cfg.RequestTimeout < 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.cfg.RequestTimeout)
 }
 
 // incrementInflight will increment the add in flight count.
@@ -113,6 +148,7 @@ func (s *subscriber) inFlightCount() uint64 {
 func NewSubscriber(cfg SQSConfig) (pubsub.Subscriber, error) {
 	var err error
 
+	defaultSQSConfig(&cfg)
 	s := &subscriber{
 		cfg:     cfg,
 		stopped: 1,
@@ -130,8 +166,11 @@ func NewSubscriber(cfg SQSConfig) (pubsub.Subscriber, error) {
 	s.sqs = sqsClient
 
 	if len(cfg.QueueURL) == 0 {
+		ctx, cancel := s.requestContext(context.Background())
+		defer cancel()
+
 		var urlResp *sqs.GetQueueUrlOutput
-		urlResp, err = s.sqs.GetQueueUrl(&sqs.GetQueueUrlInput{
+		urlResp, err = s.sqs.GetQueueUrlWithContext(ctx, &sqs.GetQueueUrlInput{
 			QueueName:              &cfg.QueueName,
 			QueueOwnerAWSAccountId: &cfg.QueueOwnerAccountID,
 		})
@@ -163,7 +202,11 @@ func createSqsClient(cfg *SQSConfig) (sqsiface.SQSAPI, error) {
 }
 
 // Message will decode message bodies and simply return string message.
+// The message published to SNS is returned when UnwrapSNSEnvelope is enabled.
 func (m *subscriberMessage) String() string {
+	if m.notification != nil && m.sub.cfg.UnwrapSNSEnvelope {
+		return m.notification.Message
+	}
 	msgBody := aws.StringValue(m.message.Body)
 	return msgBody
 }
@@ -180,7 +223,10 @@ func (m *subscriberMessage) GetMessageId() string {
 // message. It will set the visibility timeout of the message to the given
 // duration.
 func (m *subscriberMessage) ExtendDoneDeadline(d time.Duration) error {
-	_, err := m.sub.sqs.ChangeMessageVisibility(&sqs.ChangeMessageVisibilityInput{
+	ctx, cancel := m.sub.requestContext(context.Background())
+	defer cancel()
+
+	_, err := m.sub.sqs.ChangeMessageVisibilityWithContext(ctx, &sqs.ChangeMessageVisibilityInput{
 		QueueUrl:          m.sub.queueURL,
 		ReceiptHandle:     m.message.ReceiptHandle,
 		VisibilityTimeout: aws.Int64(int64(d.Seconds())),
@@ -198,7 +244,7 @@ func (m *subscriberMessage) Done() error {
 		ReceiptHandle: m.message.ReceiptHandle,
 	}
 	if m.sub.isStopped() {
-		return m.sub.deleteMessageBatch(batchInput)
+		return m.sub.deleteMessageBatch(context.Background(), batchInput)
 	}
 	receipt := make(chan error)
 	m.sub.toDelete <- &deleteRequest{
@@ -208,6 +254,21 @@ func (m *subscriberMessage) Done() error {
 	return <-receipt
 }
 
+// Attributes return the message attributes, read from the SNS envelope when
+// raw message delivery is disabled.
+func (m *subscriberMessage) Attributes() pubsub.Attributes {
+	return m.attrs
+}
+
+// Context return the context extracted from the message attributes, with the
+// pubsub.Delivery metadata.
+func (m *subscriberMessage) Context() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
 // Returns the number of times a message has been received from the queue but not deleted.
 func (m *subscriberMessage) GetReceiveCount() (int, error) {
 	val, ok := m.message.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]
@@ -231,8 +292,9 @@ func (s *subscriber) Start() <-chan pubsub.Message {
 		return nil
 	}
 	atomic.SwapUint32(&s.stopped, uint32(0))
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 	s.stop = make(chan chan error, 1)
-	s.flush = make(chan chan error, 1)
+	s.flush = make(chan *flushRequest, 1)
 	s.toDelete = make(chan *deleteRequest)
 
 	output := make(chan pubsub.Message)
@@ -253,13 +315,28 @@ func (s *subscriber) Start() <-chan pubsub.Message {
 			default:
 			}
 			// get messages
-			nameApproximateReceiveCount := sqs.MessageSystemAttributeNameApproximateReceiveCount
-			resp, err = s.sqs.ReceiveMessage(&sqs.ReceiveMessageInput{
+			resp, err = s.receiveMessage(&sqs.ReceiveMessageInput{
 				MaxNumberOfMessages: aws.Int64(s.cfg.MaxMessages),
 				QueueUrl:            s.queueURL,
 				WaitTimeSeconds:     s.cfg.TimeoutSeconds,
-				AttributeNames:      []*string{&nameApproximateReceiveCount},
+				AttributeNames: aws.StringSlice([]string{
+					sqs.MessageSystemAttributeNameApproximateReceiveCount,
+					sqs.MessageSystemAttributeNameApproximateFirstReceiveTimestamp,
+					sqs.MessageSystemAttributeNameSentTimestamp,
+					sqs.MessageSystemAttributeNameMessageGroupId,
+				}),
+				MessageAttributeNames: []*string{aws.String("All")},
 			})
+			if s.ctx.Err() != nil {
+				// receive was interrupted by Stop, or it finished meanwhile
+				// and the messages can be received again right away
+				if err == nil {
+					s.release(resp.Messages)
+				}
+				exit := <-s.stop
+				exit <- nil
+				return
+			}
 			if err != nil {
 				// we've encountered a major error
 				s.Logger.Printf("Error occurred %s", err.Error())
@@ -267,26 +344,24 @@ func (s *subscriber) Start() <-chan pubsub.Message {
 				if s.onErrorFunc != nil {
 					s.onErrorFunc(err)
 				}
-				time.Sleep(s.cfg.SleepInterval)
+				s.sleep()
 				continue
 			}
 
 			// if we didn't get any messages, lets chill out for a sec
 			if len(resp.Messages) == 0 {
-				time.Sleep(s.cfg.SleepInterval)
+				s.sleep()
 				continue
 			}
 
 			// for each message, pass to output
-			for _, msg := range resp.Messages {
+			for i, msg := range resp.Messages {
 				select {
 				case exit := <-s.stop:
+					s.release(resp.Messages[i:])
 					exit <- nil
 					return
-				case output <- &subscriberMessage{
-					sub:     s,
-					message: msg,
-				}:
+				case output <- s.newMessage(msg):
 					s.incrementInFlight()
 				}
 			}
@@ -295,6 +370,165 @@ func (s *subscriber) Start() <-chan pubsub.Message {
 	return output
 }
 
+// receiveMessage wait up to TimeoutSeconds for messages, it's interrupted when
+// the subscriber is stopped.
+func (s *subscriber) receiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if s.cfg.RequestTimeout < 0 {
+		ctx, cancel = context.WithCancel(s.ctx)
+	} else {
+		// long polling keeps the request open up to TimeoutSeconds
+		wait := time.Duration(aws.Int64Value(s.cfg.TimeoutSeconds)) * time.Second
+		ctx, cancel = context.WithTimeout(s.ctx, s.cfg.RequestTimeout+wait)
+	}
+	defer cancel()
+
+	return s.sqs.ReceiveMessageWithContext(ctx, input)
+}
+
+// release make messages that were not delivered visible again, otherwise they
+// wait for the visibility timeout of the queue.
+func (s *subscriber) release(msgs []*sqs.Message) {
+	for _, msg := range msgs {
+		m := &subscriberMessage{sub: s, message: msg}
+		if err := m.ExtendDoneDeadline(0); err != nil {
+			s.Logger.Printf("Unable to release message %s: %s", m.GetMessageId(), err)
+		}
+	}
+}
+
+// sleep wait for SleepInterval or until the subscriber is stopped.
+func (s *subscriber) sleep() {
+	t := time.NewTimer(s.cfg.SleepInterval)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+	case <-s.ctx.Done():
+	}
+}
+
+// newMessage read attributes and delivery metadata of msg and extract the
+// context sent by the publisher.
+func (s *subscriber) newMessage(msg *sqs.Message) *subscriberMessage {
+	m := &subscriberMessage{
+		sub:          s,
+		message:      msg,
+		notification: parseSNSNotification(msg),
+	}
+	m.attrs = messageAttributes(msg, m.notification)
+
+	ctx := pubsub.SetDelivery(context.Background(), s.delivery(m))
+	m.ctx = pubsub.ExtractAttributes(ctx, s.cfg.Propagator, m.attrs)
+
+	return m
+}
+
+func (s *subscriber) delivery(m *subscriberMessage) *pubsub.Delivery {
+	d := &pubsub.Delivery{
+		MessageID:       m.GetMessageId(),
+		Subject:         m.attrs.Get(pubsub.SubjectAttribute),
+		Queue:           aws.StringValue(s.queueURL),
+		GroupID:         aws.StringValue(m.message.Attributes[sqs.MessageSystemAttributeNameMessageGroupId]),
+		PublishedAt:     millisToTime(m.message.Attributes[sqs.MessageSystemAttributeNameSentTimestamp]),
+		FirstReceivedAt: millisToTime(m.message.Attributes[sqs.MessageSystemAttributeNameApproximateFirstReceiveTimestamp]),
+	}
+	d.ReceiveCount, _ = m.GetReceiveCount()
+
+	if n := m.notification; n != nil {
+		d.MessageID = n.MessageId
+		d.Topic = n.TopicArn
+		d.Subject = n.Subject
+		if t, err := time.Parse(time.RFC3339, n.Timestamp); err == nil {
+			d.PublishedAt = t
+		}
+	}
+
+	return d
+}
+
+// millisToTime parse timestamps of SQS attributes, given in milliseconds.
+func millisToTime(v *string) time.Time {
+	ms, err := strconv.ParseInt(aws.StringValue(v), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// snsNotification is the body of messages sent by SNS when raw message
+// delivery is disabled in the subscription.
+type snsNotification struct {
+	Type              string
+	MessageId         string
+	TopicArn          string
+	Subject           string
+	Message           string
+	Timestamp         string
+	MessageAttributes map[string]struct {
+		Type  string
+		Value string
+	}
+}
+
+// parseSNSNotification return the SNS envelope of the body, or nil if it's
+// not a notification.
+func parseSNSNotification(msg *sqs.Message) *snsNotification {
+	body := aws.StringValue(msg.Body)
+	if !strings.HasPrefix(strings.TrimSpace(body), "{") {
+		return nil
+	}
+
+	var n snsNotification
+	if err := json.Unmarshal([]byte(body), &n); err != nil || n.Type != "Notification" || n.TopicArn == "" {
+		return nil
+	}
+	return &n
+}
+
+// messageAttributes return the attributes of the message. Attributes sent
+// to SNS are only available in SQS with raw message delivery, otherwise they
+// are read from the notification in the body.
+func messageAttributes(msg *sqs.Message, n *snsNotification) pubsub.Attributes {
+	attrs := make(pubsub.Attributes)
+	for k, v := range msg.MessageAttributes {
+		if v == nil {
+			continue
+		}
+
+		// custom types, like "Number.float", keep the base type
+		dataType := strings.SplitN(aws.StringValue(v.DataType), ".", 2)[0]
+		if dataType == pubsub.AttributeTypeBinary {
+			attrs[k] = pubsub.BinaryAttribute(v.BinaryValue)
+		} else {
+			attrs[k] = pubsub.Attribute{Type: dataType, Value: aws.StringValue(v.StringValue)}
+		}
+	}
+	if len(attrs) > 0 || n == nil {
+		return attrs
+	}
+
+	for k, v := range n.MessageAttributes {
+		switch v.Type {
+		case pubsub.AttributeTypeBinary:
+			b, err := base64.StdEncoding.DecodeString(v.Value)
+			if err != nil {
+				continue
+			}
+			attrs[k] = pubsub.BinaryAttribute(b)
+		case pubsub.AttributeTypeNumber:
+			attrs[k] = pubsub.Attribute{Type: pubsub.AttributeTypeNumber, Value: v.Value}
+		default:
+			// String and String.Array
+			attrs[k] = pubsub.StringAttribute(v.Value)
+		}
+	}
+	return attrs
+}
+
 // OnErrorFunc sets subscriber's onErrorFunc field
 func (s *subscriber) SetOnErrorFunc(fn func(error)) {
 	s.onErrorFunc = fn
@@ -308,16 +542,16 @@ func (s *subscriber) handleDeletes() {
 		select {
 		case flush := <-s.flush:
 			if len(entriesBuffer) > 0 {
-				err = s.deleteMessageBatch(entriesBuffer...)
+				err = s.deleteMessageBatch(flush.ctx, entriesBuffer...)
 			}
-			flush <- err
+			flush.err <- err
 			return
 		case delRequest = <-s.toDelete:
 		}
 		entriesBuffer = append(entriesBuffer, delRequest.entry)
 		// if buffer is full, send the request
 		if len(entriesBuffer) > *s.cfg.DeleteBufferSize {
-			err = s.deleteMessageBatch(entriesBuffer...)
+			err = s.deleteMessageBatch(context.Background(), entriesBuffer...)
 			entriesBuffer = []*sqs.DeleteMessageBatchRequestEntry{}
 		}
 		delRequest.receipt <- err
@@ -325,12 +559,15 @@ func (s *subscriber) handleDeletes() {
 }
 
 // deleteMessageBatch is helper function to remove messages from sqs in batches
-func (s *subscriber) deleteMessageBatch(batchReq ...*sqs.DeleteMessageBatchRequestEntry) error {
+func (s *subscriber) deleteMessageBatch(ctx context.Context, batchReq ...*sqs.DeleteMessageBatchRequestEntry) error {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
 	batchInput := &sqs.DeleteMessageBatchInput{
 		QueueUrl: s.queueURL,
 		Entries:  batchReq,
 	}
-	_, err := s.sqs.DeleteMessageBatch(batchInput)
+	_, err := s.sqs.DeleteMessageBatchWithContext(ctx, batchInput)
 
 	return err
 }
@@ -342,9 +579,32 @@ func (s *subscriber) isStopped() bool {
 // Stop will block until the consumer has stopped consuming
 // messages.
 func (s *subscriber) Stop() error {
+	return s.StopWithContext(context.Background())
+}
+
+// StopWithContext stop consuming messages and flush the delete buffer,
+// returning when ctx is done even if it didn't finish.
+func (s *subscriber) StopWithContext(ctx context.Context) error {
 	if s.isStopped() {
 		return errors.New("sqs subscriber is not running")
 	}
+	// interrupt receive calls and sleeps
+	s.cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.shutdown(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *subscriber) shutdown(ctx context.Context) error {
 	defer func() {
 		close(s.stop)
 		close(s.toDelete)
@@ -360,11 +620,11 @@ func (s *subscriber) Stop() error {
 		return err
 	}
 	//flush deleted msg buffer
-	flush := make(chan error)
-	defer close(flush)
+	flush := &flushRequest{ctx: ctx, err: make(chan error)}
+	defer close(flush.err)
 	s.flush <- flush
 
-	return <-flush
+	return <-flush.err
 }
 
 // Err will contain any errors that occurred during
@@ -373,3 +633,39 @@ func (s *subscriber) Stop() error {
 func (s *subscriber) Err() error {
 	return s.sqsErr
 }
+
+// QueueStats is the detail returned by the subscriber health check.
+type QueueStats struct {
+	QueueURL           string `json:"queue_url"`
+	Messages           int64  `json:"messages"`
+	MessagesNotVisible int64  `json:"messages_not_visible"`
+	MessagesDelayed    int64  `json:"messages_delayed"`
+}
+
+// HealthCheck check if the queue is reachable, returning the approximate number
+// of messages as detail. It implements fdhandler.HealthChecker.
+func (s *subscriber) HealthCheck(ctx context.Context) (interface{}, error) {
+	out, err := s.sqs.GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl: s.queueURL,
+		AttributeNames: aws.StringSlice([]string{
+			sqs.QueueAttributeNameApproximateNumberOfMessages,
+			sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+			sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed,
+		}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attr := func(name string) int64 {
+		n, _ := strconv.ParseInt(aws.StringValue(out.Attributes[name]), 10, 64)
+		return n
+	}
+
+	return &QueueStats{
+		QueueURL:           aws.StringValue(s.queueURL),
+		Messages:           attr(sqs.QueueAttributeNameApproximateNumberOfMessages),
+		MessagesNotVisible: attr(sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible),
+		MessagesDelayed:    attr(sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed),
+	}, nil
+}
@@ -1,6 +1,7 @@
 package awssub
 
 import (
+	"context"
 	"errors"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -146,6 +147,13 @@ func TestSubscriberStopBetweenBatch(t *testing.T) {
 
 	assert.True(t, len(sqstest.Deleted) == 1, "Messages were not removed from the delete buffer")
 	verifyRemovedMsg(t, sqstest, msq1, 0)
+
+	// messages never delivered are visible again
+	if assert.Len(t, sqstest.Extended, 2) {
+		assert.Equal(t, test2, *sqstest.Extended[0].ReceiptHandle)
+		assert.Equal(t, test3, *sqstest.Extended[1].ReceiptHandle)
+		assert.Equal(t, int64(0), *sqstest.Extended[1].VisibilityTimeout)
+	}
 }
 
 func TestSQSDoneAfterStop(t *testing.T) {
@@ -187,6 +195,61 @@ func TestSQSDoneAfterStop(t *testing.T) {
 	}
 }
 
+func TestSubscriberStopInterruptsReceive(t *testing.T) {
+	sqstest := &TestSQSAPI{LongPoll: true}
+	cfg := SQSConfig{
+		QueueURL:       "http://test_queue",
+		TimeoutSeconds: aws.Int64(20),
+		SleepInterval:  time.Minute,
+	}
+	defaultSQSConfig(&cfg)
+	sub, err := createSubscriber(cfg, sqstest)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	queue := sub.Start()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	assert.NoError(t, sub.(*subscriber).StopWithContext(ctx))
+	assert.True(t, time.Since(start) < time.Second, "stop waited for the receive call")
+
+	_, ok := <-queue
+	assert.False(t, ok)
+}
+
+func TestSubscriberStopReleasesLateMessages(t *testing.T) {
+	late := "received while stopping"
+	sqstest := &TestSQSAPI{
+		LongPoll:     true,
+		LateMessages: []*sqs.Message{{Body: &late, ReceiptHandle: &late}},
+	}
+	cfg := SQSConfig{
+		QueueURL:       "http://test_queue",
+		TimeoutSeconds: aws.Int64(20),
+	}
+	defaultSQSConfig(&cfg)
+	sub, err := createSubscriber(cfg, sqstest)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	queue := sub.Start()
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, sub.Stop())
+
+	_, ok := <-queue
+	assert.False(t, ok)
+	if assert.Len(t, sqstest.Extended, 1) {
+		assert.Equal(t, late, *sqstest.Extended[0].ReceiptHandle)
+		assert.Equal(t, int64(0), *sqstest.Extended[0].VisibilityTimeout)
+	}
+}
+
 func TestExtendDoneTimeout(t *testing.T) {
 	test := "some test"
 	sqstest := &TestSQSAPI{
@@ -222,6 +285,198 @@ func TestExtendDoneTimeout(t *testing.T) {
 	}
 }
 
+func TestSubscriberHealthCheck(t *testing.T) {
+	sqstest := &TestSQSAPI{
+		Attributes: map[string]*string{
+			sqs.QueueAttributeNameApproximateNumberOfMessages:           aws.String("3"),
+			sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible: aws.String("1"),
+		},
+	}
+	queueURL := "https://sqs.eu-west-1.amazonaws.com/123/orders"
+	sub, _ := createSubscriber(SQSConfig{QueueURL: queueURL}, sqstest)
+
+	checker, ok := sub.(interface {
+		HealthCheck(context.Context) (interface{}, error)
+	})
+	if !assert.True(t, ok) {
+		return
+	}
+
+	detail, err := checker.HealthCheck(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &QueueStats{
+		QueueURL:           queueURL,
+		Messages:           3,
+		MessagesNotVisible: 1,
+	}, detail)
+
+	sqstest.Err = errors.New("queue does not exist")
+	_, err = checker.HealthCheck(context.Background())
+	assert.Error(t, err)
+}
+
+func TestSubscriberPropagation(t *testing.T) {
+	raw := "raw message"
+	notification := `{
+		"Type": "Notification",
+		"MessageId": "sns-id",
+		"TopicArn": "arn:aws:sns:eu-west-1:123:orders",
+		"Message": "sns message",
+		"MessageAttributes": {
+			"correlation_id": {"Type": "String", "Value": "req-456"}
+		}
+	}`
+	plain := "plain message"
+	sqstest := &TestSQSAPI{
+		Messages: [][]*sqs.Message{
+			{
+				{
+					Body:          &raw,
+					ReceiptHandle: &raw,
+					MessageAttributes: map[string]*sqs.MessageAttributeValue{
+						pubsub.CorrelationIDAttribute: {
+							DataType:    aws.String("String"),
+							StringValue: aws.String("req-123"),
+						},
+					},
+				},
+				{
+					Body:          &notification,
+					ReceiptHandle: &notification,
+				},
+				{
+					Body:          &plain,
+					ReceiptHandle: &plain,
+				},
+			},
+		},
+	}
+
+	cfg := SQSConfig{
+		QueueURL:   "http://test_queue",
+		Propagator: pubsub.CorrelationIDPropagator{},
+	}
+	defaultSQSConfig(&cfg)
+	sub, err := createSubscriber(cfg, sqstest)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	queue := sub.Start()
+	defer sub.Stop()
+
+	msg := <-queue
+	assert.Equal(t, "req-123", pubsub.CorrelationID(msg.Context()))
+	msg = <-queue
+	assert.Equal(t, "req-456", pubsub.CorrelationID(msg.Context()))
+	msg = <-queue
+	assert.Equal(t, "", pubsub.CorrelationID(msg.Context()))
+}
+
+func TestSubscriberAttributes(t *testing.T) {
+	raw := "raw message"
+	notification := `{
+		"Type": "Notification",
+		"MessageId": "sns-id",
+		"TopicArn": "arn:aws:sns:eu-west-1:123:orders",
+		"Subject": "order",
+		"Message": "sns message",
+		"Timestamp": "2019-04-01T10:00:00.000Z",
+		"MessageAttributes": {
+			"event": {"Type": "String", "Value": "order_created"},
+			"amount": {"Type": "Number", "Value": "12.5"},
+			"raw": {"Type": "Binary", "Value": "AQI="}
+		}
+	}`
+	sqstest := &TestSQSAPI{
+		Messages: [][]*sqs.Message{
+			{
+				{
+					Body:          &raw,
+					MessageId:     aws.String("sqs-id"),
+					ReceiptHandle: &raw,
+					Attributes: map[string]*string{
+						sqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String("2"),
+						sqs.MessageSystemAttributeNameSentTimestamp:           aws.String("1554112800000"),
+						sqs.MessageSystemAttributeNameMessageGroupId:          aws.String("order-1"),
+					},
+					MessageAttributes: map[string]*sqs.MessageAttributeValue{
+						pubsub.SubjectAttribute: {
+							DataType:    aws.String("String"),
+							StringValue: aws.String("order"),
+						},
+						"event": {
+							DataType:    aws.String("String"),
+							StringValue: aws.String("order_created"),
+						},
+						"amount": {
+							DataType:    aws.String("Number.float"),
+							StringValue: aws.String("12.5"),
+						},
+						"raw": {
+							DataType:    aws.String("Binary"),
+							BinaryValue: []byte{1, 2},
+						},
+					},
+				},
+				{
+					Body:          &notification,
+					MessageId:     aws.String("sqs-id"),
+					ReceiptHandle: &notification,
+				},
+			},
+		},
+	}
+
+	cfg := SQSConfig{
+		QueueURL:          "http://test_queue",
+		UnwrapSNSEnvelope: true,
+	}
+	defaultSQSConfig(&cfg)
+	sub, err := createSubscriber(cfg, sqstest)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	queue := sub.Start()
+	defer sub.Stop()
+
+	expected := pubsub.Attributes{
+		"event":  pubsub.StringAttribute("order_created"),
+		"amount": pubsub.FloatAttribute(12.5),
+		"raw":    pubsub.BinaryAttribute([]byte{1, 2}),
+	}
+	publishedAt := time.Date(2019, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	msg := <-queue
+	assert.Equal(t, raw, msg.String())
+	assert.Equal(t, "order", msg.Attributes().Get(pubsub.SubjectAttribute))
+	delete(msg.Attributes(), pubsub.SubjectAttribute)
+	assert.Equal(t, expected, msg.Attributes())
+	d, ok := pubsub.DeliveryFromContext(msg.Context())
+	if assert.True(t, ok) {
+		assert.Equal(t, "sqs-id", d.MessageID)
+		assert.Equal(t, "order", d.Subject)
+		assert.Equal(t, "order-1", d.GroupID)
+		assert.Equal(t, "http://test_queue", d.Queue)
+		assert.Equal(t, 2, d.ReceiveCount)
+		assert.True(t, publishedAt.Equal(d.PublishedAt))
+	}
+
+	msg = <-queue
+	assert.Equal(t, "sns message", msg.String())
+	assert.Equal(t, expected, msg.Attributes())
+	d, ok = pubsub.DeliveryFromContext(msg.Context())
+	if assert.True(t, ok) {
+		assert.Equal(t, "sns-id", d.MessageID)
+		assert.Equal(t, "arn:aws:sns:eu-west-1:123:orders", d.Topic)
+		assert.Equal(t, "order", d.Subject)
+		assert.True(t, publishedAt.Equal(d.PublishedAt))
+	}
+}
+
 func createSubscriber(cfg SQSConfig, sqstest sqsiface.SQSAPI) (pubsub.Subscriber, error) {
 	sqsClientFactoryFunc = func(cfg *SQSConfig) (sqsiface.SQSAPI, error) {
 		return sqstest, nil
@@ -257,11 +512,21 @@ func verifyRemovedMsg(t *testing.T, testsqs *TestSQSAPI, msg pubsub.Message, ind
 }
 
 type TestSQSAPI struct {
+	sqsiface.SQSAPI
+
 	Offset   int
 	Messages [][]*sqs.Message
 	Deleted  []*sqs.DeleteMessageBatchRequestEntry
 	Extended []*sqs.ChangeMessageVisibilityInput
 	Err      error
+	// Attributes are returned by GetQueueAttributesWithContext.
+	Attributes map[string]*string
+	// LongPoll make receive calls wait until the context is done when there
+	// are no messages left, like SQS does for WaitTimeSeconds.
+	LongPoll bool
+	// LateMessages are returned successfully by an interrupted long poll,
+	// like a response that arrives while the subscriber is stopped.
+	LateMessages []*sqs.Message
 }
 
 var _ sqsiface.SQSAPI = &TestSQSAPI{}
@@ -286,6 +551,25 @@ func (s *TestSQSAPI) ChangeMessageVisibility(i *sqs.ChangeMessageVisibilityInput
 	return nil, nil
 }
 
+func (s *TestSQSAPI) ReceiveMessageWithContext(ctx aws.Context, i *sqs.ReceiveMessageInput, _ ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	if s.LongPoll && s.Offset >= len(s.Messages) {
+		<-ctx.Done()
+		if s.LateMessages != nil {
+			return &sqs.ReceiveMessageOutput{Messages: s.LateMessages}, nil
+		}
+		return nil, ctx.Err()
+	}
+	return s.ReceiveMessage(i)
+}
+
+func (s *TestSQSAPI) DeleteMessageBatchWithContext(_ aws.Context, i *sqs.DeleteMessageBatchInput, _ ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	return s.DeleteMessageBatch(i)
+}
+
+func (s *TestSQSAPI) ChangeMessageVisibilityWithContext(_ aws.Context, i *sqs.ChangeMessageVisibilityInput, _ ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	return s.ChangeMessageVisibility(i)
+}
+
 ///////////
 // ALL METHODS BELOW HERE ARE EMPTY AND JUST SATISFYING THE SQSAPI interface
 ///////////
@@ -300,9 +584,6 @@ func (s *TestSQSAPI) DeleteMessageWithContext(aws.Context, *sqs.DeleteMessageInp
 func (s *TestSQSAPI) DeleteMessageBatchRequest(i *sqs.DeleteMessageBatchInput) (*request.Request, *sqs.DeleteMessageBatchOutput) {
 	return nil, nil
 }
-func (s *TestSQSAPI) DeleteMessageBatchWithContext(aws.Context, *sqs.DeleteMessageBatchInput, ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
-	return nil, errNotImpl
-}
 
 func (s *TestSQSAPI) AddPermissionRequest(*sqs.AddPermissionInput) (*request.Request, *sqs.AddPermissionOutput) {
 	return nil, nil
@@ -316,9 +597,6 @@ func (s *TestSQSAPI) AddPermissionWithContext(aws.Context, *sqs.AddPermissionInp
 func (s *TestSQSAPI) ChangeMessageVisibilityRequest(*sqs.ChangeMessageVisibilityInput) (*request.Request, *sqs.ChangeMessageVisibilityOutput) {
 	return nil, nil
 }
-func (s *TestSQSAPI) ChangeMessageVisibilityWithContext(aws.Context, *sqs.ChangeMessageVisibilityInput, ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
-	return nil, errNotImpl
-}
 
 func (s *TestSQSAPI) ChangeMessageVisibilityBatchRequest(*sqs.ChangeMessageVisibilityBatchInput) (*request.Request, *sqs.ChangeMessageVisibilityBatchOutput) {
 	return nil, nil
@@ -358,7 +636,10 @@ func (s *TestSQSAPI) GetQueueAttributes(*sqs.GetQueueAttributesInput) (*sqs.GetQ
 	return nil, errNotImpl
 }
 func (s *TestSQSAPI) GetQueueAttributesWithContext(aws.Context, *sqs.GetQueueAttributesInput, ...request.Option) (*sqs.GetQueueAttributesOutput, error) {
-	return nil, errNotImpl
+	if s.Attributes == nil {
+		return nil, errNotImpl
+	}
+	return &sqs.GetQueueAttributesOutput{Attributes: s.Attributes}, s.Err
 }
 func (s *TestSQSAPI) GetQueueUrlRequest(*sqs.GetQueueUrlInput) (*request.Request, *sqs.GetQueueUrlOutput) {
 	return nil, nil
@@ -399,9 +680,6 @@ func (s *TestSQSAPI) PurgeQueueWithContext(aws.Context, *sqs.PurgeQueueInput, ..
 func (s *TestSQSAPI) ReceiveMessageRequest(*sqs.ReceiveMessageInput) (*request.Request, *sqs.ReceiveMessageOutput) {
 	return nil, nil
 }
-func (s *TestSQSAPI) ReceiveMessageWithContext(aws.Context, *sqs.ReceiveMessageInput, ...request.Option) (*sqs.ReceiveMessageOutput, error) {
-	return nil, errNotImpl
-}
 
 func (s *TestSQSAPI) RemovePermissionRequest(*sqs.RemovePermissionInput) (*request.Request, *sqs.RemovePermissionOutput) {
 	return nil, nil
@@ -0,0 +1,62 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+)
+
+// PublishResult is the outcome of publishing one message of a batch.
+type PublishResult struct {
+	Message *OutgoingMessage
+	// MessageID is the id given by the broker when it succeeds.
+	MessageID string
+	Err       error
+}
+
+// PublishResults are returned in the same order of the messages.
+type PublishResults []PublishResult
+
+// Failed return results of messages that were not published.
+func (r PublishResults) Failed() PublishResults {
+	var failed PublishResults
+	for _, res := range r {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Err return an error describing the failures, or nil if all messages were
+// published.
+func (r PublishResults) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("pubsub: %d of %d messages not published, first error: %s", len(failed), len(r), failed[0].Err)
+}
+
+// BatchPublisher is implemented by publishers able to send many messages in a
+// single call.
+type BatchPublisher interface {
+	// PublishBatch publish all messages, reporting the result of each one.
+	PublishBatch(ctx context.Context, msgs []*OutgoingMessage) PublishResults
+}
+
+// PublishBatch publish msgs with pub.PublishBatch, or one by one when pub is
+// not a BatchPublisher.
+func PublishBatch(ctx context.Context, pub Publisher, msgs []*OutgoingMessage) PublishResults {
+	if bp, ok := pub.(BatchPublisher); ok {
+		return bp.PublishBatch(ctx, msgs)
+	}
+
+	results := make(PublishResults, len(msgs))
+	for i, m := range msgs {
+		results[i] = PublishResult{
+			Message: m,
+			Err:     pub.PublishMessage(ctx, m),
+		}
+	}
+	return results
+}
@@ -0,0 +1,247 @@
+package pubsub
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// ContentTypeAttribute is the attribute telling consumers which codec encoded
+// the message body.
+const ContentTypeAttribute = "content_type"
+
+// Content types of the codecs of this package.
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+
+	gzipContentTypeSuffix = "+gzip"
+)
+
+// Codec convert values to message bodies and back. Message bodies are strings,
+// so binary formats must be encoded as text.
+type Codec interface {
+	// ContentType is sent as ContentTypeAttribute.
+	ContentType() string
+	Encode(v interface{}) (string, error)
+	Decode(body string, v interface{}) error
+}
+
+// Available codecs.
+var (
+	// JSONCodec encode values with encoding/json.
+	JSONCodec Codec = jsonCodec{}
+	// ProtobufCodec encode ProtoMessage values using the protobuf wire
+	// format, as base64.
+	ProtobufCodec Codec = protobufCodec{}
+)
+
+// DefaultCodec decode messages without ContentTypeAttribute, like the ones
+// published with Publish.
+var DefaultCodec = JSONCodec
+
+// DefaultCodecs are used to find the codec of received messages by their
+// content type.
+var DefaultCodecs = Codecs{
+	JSONCodec,
+	ProtobufCodec,
+	GzipCodec(JSONCodec),
+	GzipCodec(ProtobufCodec),
+}
+
+// Codecs is a list of codecs used to decode messages.
+type Codecs []Codec
+
+// Lookup return the codec of contentType.
+func (c Codecs) Lookup(contentType string) (Codec, bool) {
+	for _, codec := range c {
+		if codec.ContentType() == contentType {
+			return codec, true
+		}
+	}
+	return nil, false
+}
+
+// Decode msg into v with the codec of its ContentTypeAttribute, or with
+// DefaultCodec when the attribute is missing.
+func (c Codecs) Decode(msg Message, v interface{}) error {
+	codec := DefaultCodec
+	if contentType := msg.Attributes().Get(ContentTypeAttribute); contentType != "" {
+		var ok bool
+		codec, ok = c.Lookup(contentType)
+		if !ok {
+			return fmt.Errorf("pubsub: unsupported content type %q", contentType)
+		}
+	}
+	return codec.Decode(msg.String(), v)
+}
+
+// Decode msg into v using DefaultCodecs.
+func Decode(msg Message, v interface{}) error {
+	return DefaultCodecs.Decode(msg, v)
+}
+
+// Encode v as a message with ContentTypeAttribute, to be published with
+// PublishMessage.
+func Encode(codec Codec, key string, v interface{}) (*OutgoingMessage, error) {
+	body, err := codec.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutgoingMessage{
+		Key:  key,
+		Body: body,
+		Attributes: Attributes{
+			ContentTypeAttribute: StringAttribute(codec.ContentType()),
+		},
+	}, nil
+}
+
+// CodecPublisher publish values encoded by a codec:
+//
+//  orders := pubsub.NewCodecPublisher(pub, pubsub.JSONCodec)
+//  err := orders.Publish(ctx, "order_created", &Order{ID: 1})
+type CodecPublisher struct {
+	pub   Publisher
+	codec Codec
+}
+
+// NewCodecPublisher create a publisher encoding values with codec.
+func NewCodecPublisher(pub Publisher, codec Codec) *CodecPublisher {
+	if pub == nil || codec == nil {
+		panic("pubsub: publisher and codec are required")
+	}
+
+	return &CodecPublisher{
+		pub:   pub,
+		codec: codec,
+	}
+}
+
+// Publish v to the default topic.
+func (p *CodecPublisher) Publish(ctx context.Context, key string, v interface{}) error {
+	return p.PublishToTopic(ctx, key, v, "")
+}
+
+// PublishToTopic publish v to the specified topic.
+func (p *CodecPublisher) PublishToTopic(ctx context.Context, key string, v interface{}, topic string) error {
+	m, err := Encode(p.codec, key, v)
+	if err != nil {
+		return err
+	}
+	m.Topic = topic
+
+	return p.pub.PublishMessage(ctx, m)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (jsonCodec) Encode(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (jsonCodec) Decode(body string, v interface{}) error {
+	return json.Unmarshal([]byte(body), v)
+}
+
+// ProtoMessage is implemented by protobuf messages able to marshal themselves
+// to the wire format, like the ones generated by gogo/protobuf.
+type ProtoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal([]byte) error
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (protobufCodec) Encode(v interface{}) (string, error) {
+	pm, ok := v.(ProtoMessage)
+	if !ok {
+		return "", fmt.Errorf("pubsub: %T is not a ProtoMessage", v)
+	}
+
+	b, err := pm.Marshal()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func (protobufCodec) Decode(body string, v interface{}) error {
+	pm, ok := v.(ProtoMessage)
+	if !ok {
+		return fmt.Errorf("pubsub: %T is not a ProtoMessage", v)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return err
+	}
+	return pm.Unmarshal(b)
+}
+
+// GzipCodec compress bodies encoded by codec with gzip, as base64. It's useful
+// to fit big messages in the 256KB limit of SNS and SQS.
+func GzipCodec(codec Codec) Codec {
+	return gzipCodec{codec: codec}
+}
+
+type gzipCodec struct {
+	codec Codec
+}
+
+func (c gzipCodec) ContentType() string {
+	return c.codec.ContentType() + gzipContentTypeSuffix
+}
+
+func (c gzipCodec) Encode(v interface{}) (string, error) {
+	body, err := c.codec.Encode(v)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func (c gzipCodec) Decode(body string, v interface{}) error {
+	b, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return err
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	decompressed, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return c.codec.Decode(string(decompressed), v)
+}
@@ -0,0 +1,170 @@
+package pubsub_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/foodora/go-ranger/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+type testOrder struct {
+	ID   uint64 `json:"id"`
+	Name string `json:"name"`
+}
+
+// Marshal encode the order as a protobuf message with id as field 1 and name
+// as field 2.
+func (o *testOrder) Marshal() ([]byte, error) {
+	b := appendUvarint([]byte{0x08}, o.ID)
+	b = append(b, 0x12)
+	b = appendUvarint(b, uint64(len(o.Name)))
+	return append(b, o.Name...), nil
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(b, buf[:binary.PutUvarint(buf, v)]...)
+}
+
+func (o *testOrder) Unmarshal(b []byte) error {
+	for len(b) > 0 {
+		field := b[0]
+		id, n := binary.Uvarint(b[1:])
+		if n <= 0 {
+			return errors.New("invalid varint")
+		}
+		b = b[1+n:]
+
+		switch field {
+		case 0x08:
+			o.ID = id
+		case 0x12:
+			if uint64(len(b)) < id {
+				return errors.New("invalid length")
+			}
+			o.Name = string(b[:id])
+			b = b[id:]
+		default:
+			return errors.New("unknown field")
+		}
+	}
+	return nil
+}
+
+func TestCodecs(t *testing.T) {
+	order := &testOrder{ID: 300, Name: "pizza"}
+
+	codecs := []struct {
+		codec       pubsub.Codec
+		contentType string
+	}{
+		{pubsub.JSONCodec, "application/json"},
+		{pubsub.ProtobufCodec, "application/x-protobuf"},
+		{pubsub.GzipCodec(pubsub.JSONCodec), "application/json+gzip"},
+		{pubsub.GzipCodec(pubsub.ProtobufCodec), "application/x-protobuf+gzip"},
+	}
+	for _, tc := range codecs {
+		msg, err := pubsub.Encode(tc.codec, "order_created", order)
+		if !assert.NoError(t, err, tc.contentType) {
+			continue
+		}
+		assert.Equal(t, "order_created", msg.Key)
+		assert.Equal(t, tc.contentType, msg.Attributes[pubsub.ContentTypeAttribute].String())
+
+		var decoded testOrder
+		err = pubsub.Decode(&testMessage{body: msg.Body, attrs: msg.Attributes}, &decoded)
+		assert.NoError(t, err, tc.contentType)
+		assert.Equal(t, *order, decoded, tc.contentType)
+	}
+
+	body, err := pubsub.JSONCodec.Encode(order)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":300,"name":"pizza"}`, body)
+
+	_, err = pubsub.ProtobufCodec.Encode(testOrder{})
+	assert.Error(t, err)
+}
+
+func TestDecode(t *testing.T) {
+	var order testOrder
+
+	// messages without content type are decoded with DefaultCodec
+	err := pubsub.Decode(&testMessage{body: `{"id":1}`}, &order)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), order.ID)
+
+	err = pubsub.Decode(&testMessage{
+		body:  "id: 1",
+		attrs: pubsub.Attributes{pubsub.ContentTypeAttribute: pubsub.StringAttribute("text/yaml")},
+	}, &order)
+	assert.EqualError(t, err, `pubsub: unsupported content type "text/yaml"`)
+}
+
+func TestCodecPublisher(t *testing.T) {
+	pub := &testPublisher{}
+	orders := pubsub.NewCodecPublisher(pub, pubsub.GzipCodec(pubsub.JSONCodec))
+
+	assert.NoError(t, orders.Publish(context.Background(), "order_created", &testOrder{ID: 1}))
+	assert.NoError(t, orders.PublishToTopic(context.Background(), "order_created", &testOrder{ID: 2}, "orders"))
+
+	msgs := pub.messages()
+	if !assert.Len(t, msgs, 2) {
+		return
+	}
+	assert.Equal(t, "", msgs[0].Topic)
+	assert.Equal(t, "orders", msgs[1].Topic)
+
+	var order testOrder
+	assert.NoError(t, pubsub.Decode(&testMessage{body: msgs[1].Body, attrs: msgs[1].Attributes}, &order))
+	assert.Equal(t, uint64(2), order.ID)
+
+	assert.Error(t, orders.Publish(context.Background(), "invalid", func() {}))
+}
+
+func TestTypedHandler(t *testing.T) {
+	var received []testOrder
+	handler := pubsub.TypedHandler(func(ctx context.Context, o testOrder) error {
+		received = append(received, o)
+		if o.ID == 0 {
+			return errors.New("invalid order")
+		}
+		return nil
+	}, pubsub.TypedHandlerConfig{})
+
+	assert.NoError(t, handler(context.Background(), &testMessage{body: `{"id":1}`}))
+	assert.EqualError(t, handler(context.Background(), &testMessage{body: `{}`}), "invalid order")
+
+	err := handler(context.Background(), &testMessage{body: "not json"})
+	if assert.IsType(t, &pubsub.DecodeError{}, err) {
+		assert.Contains(t, err.Error(), "pubsub: unable to decode message")
+	}
+	assert.Equal(t, []testOrder{{ID: 1}, {}}, received)
+
+	var undecodable []string
+	handler = pubsub.TypedHandler(func(ctx context.Context, o *testOrder) error {
+		assert.Equal(t, "pizza", o.Name)
+		return nil
+	}, pubsub.TypedHandlerConfig{
+		Codecs: pubsub.Codecs{pubsub.ProtobufCodec},
+		OnDecodeError: func(ctx context.Context, msg pubsub.Message, err error) error {
+			undecodable = append(undecodable, msg.String())
+			return nil
+		},
+	})
+
+	msg, err := pubsub.Encode(pubsub.ProtobufCodec, "", &testOrder{Name: "pizza"})
+	assert.NoError(t, err)
+	assert.NoError(t, handler(context.Background(), &testMessage{body: msg.Body, attrs: msg.Attributes}))
+	assert.NoError(t, handler(context.Background(), &testMessage{body: "not base64", attrs: msg.Attributes}))
+	assert.Equal(t, []string{"not base64"}, undecodable)
+
+	assert.Panics(t, func() {
+		pubsub.TypedHandler(func(o testOrder) error { return nil }, pubsub.TypedHandlerConfig{})
+	})
+	assert.Panics(t, func() {
+		pubsub.TypedHandler(nil, pubsub.TypedHandlerConfig{})
+	})
+}
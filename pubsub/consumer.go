@@ -0,0 +1,494 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/foodora/go-ranger/fdbackoff"
+)
+
+// Defaults used by NewConsumer when ConsumerConfig fields are not informed.
+var (
+	DefaultConsumerWorkers           = 10
+	DefaultConsumerVisibilityTimeout = 30 * time.Second
+	DefaultConsumerBackoff           = fdbackoff.Exponential(time.Second)
+)
+
+// maxVisibilityTimeout is the maximum time a message can be hidden by SQS.
+const maxVisibilityTimeout = 12 * time.Hour
+
+// Handler process a message, it's acknowledged when nil is returned, otherwise
+// it's received again after a backoff.
+type Handler func(ctx context.Context, msg Message) error
+
+// ConsumerConfig configure how messages are processed.
+type ConsumerConfig struct {
+	// Workers is the number of messages processed concurrently.
+	Workers int
+	// VisibilityTimeout is the visibility timeout of the queue, messages are
+	// extended by this time while the handler is running. Use a negative
+	// value to disable it.
+	VisibilityTimeout time.Duration
+	// Backoff return how long a failed message waits before being received
+	// again, attempt is its receive count.
+	Backoff fdbackoff.Func
+	// OnError is called when the handler fails or the message can't be
+	// acknowledged, by default errors are logged.
+	OnError func(Message, error)
+
+	// MaxReceiveCount is the number of times a message is handled before it's
+	// forwarded to DeadLetter and removed from the queue. Messages are retried
+	// forever when it's 0 or DeadLetter is nil.
+	MaxReceiveCount int
+	// DeadLetter receive messages that failed MaxReceiveCount times, with
+	// the failure reason as attributes. See ForwardToDeadLetter.
+	DeadLetter Publisher
+}
+
+// Consumer read messages from a Subscriber and call the handler for each one
+// of them, using multiple workers. Messages of the same FIFO group, given by
+// pubsub.Delivery, are processed one at a time and in order. It implements
+// fdapp.Component:
+//
+//  consumer := pubsub.NewConsumer(sub, func(ctx context.Context, msg pubsub.Message) error {
+//  	return processOrder(ctx, msg.String())
+//  }, pubsub.ConsumerConfig{Workers: 5})
+//  app.Add("orders-consumer", consumer)
+type Consumer struct {
+	sub     Subscriber
+	handler Handler
+	cfg     ConsumerConfig
+
+	// ctx is given to handlers, it's canceled when Stop deadline is reached
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	running  uint32
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	stopped  uint32
+}
+
+// NewConsumer create a consumer, call Start to receive messages.
+func NewConsumer(sub Subscriber, handler Handler, cfg ConsumerConfig) *Consumer {
+	if sub == nil || handler == nil {
+		panic(errors.New("pubsub: consumer requires a subscriber and a handler"))
+	}
+
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultConsumerWorkers
+	}
+	if cfg.VisibilityTimeout == 0 {
+		cfg.VisibilityTimeout = DefaultConsumerVisibilityTimeout
+	}
+	if cfg.Backoff == nil {
+		cfg.Backoff = DefaultConsumerBackoff
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Consumer{
+		sub:     sub,
+		handler: handler,
+		cfg:     cfg,
+		ctx:     ctx,
+		cancel:  cancel,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start receive messages until Stop is called, returning the subscriber error
+// if it stops before.
+func (c *Consumer) Start() error {
+	if !atomic.CompareAndSwapUint32(&c.running, 0, 1) {
+		return errors.New("pubsub: consumer already started")
+	}
+	defer close(c.done)
+
+	msgs := c.sub.Start()
+	if msgs == nil {
+		return c.sub.Err()
+	}
+
+	// messages of FIFO groups are always processed by the same worker, one at
+	// a time, the other ones by any worker
+	shared := make(chan Message)
+	grouped := make([]chan Message, c.cfg.Workers)
+	for i := range grouped {
+		grouped[i] = make(chan Message)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(c.cfg.Workers)
+	for i := 0; i < c.cfg.Workers; i++ {
+		go func(i int) {
+			defer wg.Done()
+			c.work(shared, grouped[i])
+		}(i)
+	}
+
+	c.dispatch(msgs, shared, grouped)
+	wg.Wait()
+
+	if atomic.LoadUint32(&c.stopped) == 1 {
+		return nil
+	}
+
+	if err := c.sub.Err(); err != nil {
+		return err
+	}
+	return errors.New("pubsub: subscriber stopped")
+}
+
+// Stop stop receiving messages and wait the ones in progress. When ctx is done
+// before, handlers' context is canceled and their messages are not waited.
+// The subscriber is stopped with StopSubscriber, bounded by ctx as well.
+func (c *Consumer) Stop(ctx context.Context) error {
+	first := false
+	c.stopOnce.Do(func() {
+		atomic.StoreUint32(&c.stopped, 1)
+		close(c.stop)
+		first = true
+	})
+	if !first {
+		return errors.New("pubsub: consumer already stopped")
+	}
+
+	if atomic.LoadUint32(&c.running) == 0 {
+		c.cancel()
+		return nil
+	}
+
+	var err error
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	c.cancel()
+
+	// messages are not read anymore, the subscriber can be stopped safely
+	if stopErr := StopSubscriber(ctx, c.sub); stopErr != nil && err == nil {
+		err = stopErr
+	}
+	return err
+}
+
+// dispatch send messages to workers until Stop is called or msgs is closed.
+func (c *Consumer) dispatch(msgs <-chan Message, shared chan<- Message, grouped []chan Message) {
+	defer func() {
+		close(shared)
+		for _, ch := range grouped {
+			close(ch)
+		}
+	}()
+
+	for {
+		// give priority to stop, otherwise a message could be read after it
+		select {
+		case <-c.stop:
+			return
+		default:
+		}
+
+		var msg Message
+		select {
+		case <-c.stop:
+			return
+		case m, ok := <-msgs:
+			if !ok {
+				return
+			}
+			msg = m
+		}
+
+		out := shared
+		if group := messageGroup(msg); group != "" {
+			h := fnv.New32a()
+			h.Write([]byte(group))
+			out = grouped[h.Sum32()%uint32(len(grouped))]
+		}
+
+		// read messages are processed even if Stop is called meanwhile, unless
+		// its deadline is reached
+		select {
+		case out <- msg:
+		case <-c.ctx.Done():
+			// message was not processed, it can be received again
+			msg.ExtendDoneDeadline(0)
+			return
+		}
+	}
+}
+
+func (c *Consumer) work(shared, grouped <-chan Message) {
+	groups := newGroupState()
+
+	for {
+		var msg Message
+		select {
+		case m, ok := <-shared:
+			if !ok {
+				return
+			}
+			msg = m
+		case m, ok := <-grouped:
+			if !ok {
+				return
+			}
+			msg = m
+		}
+
+		group := messageGroup(msg)
+		if group == "" {
+			c.process(msg, 0)
+			continue
+		}
+
+		id := msg.GetMessageId()
+		now := time.Now()
+		if groups.blocked(group, id, now) {
+			groups.release(id, now)
+			if err := msg.ExtendDoneDeadline(0); err != nil {
+				c.onError(msg, fmt.Errorf("pubsub: unable to release message: %s", err))
+			}
+			continue
+		}
+
+		if retry, ok := c.process(msg, groups.releases(id)); ok {
+			groups.forget(id)
+		} else {
+			groups.block(group, id, now.Add(retry))
+		}
+	}
+}
+
+// groupPruneInterval is how often expired group state is removed.
+const groupPruneInterval = time.Minute
+
+// groupState keep FIFO groups blocked by a failed message of a worker. Messages
+// of the group received after it are released until it's received again or its
+// backoff expires, like when it was received by another consumer. That way
+// messages of the same group are not processed out of order.
+type groupState struct {
+	// failed message by group
+	failed map[string]groupBlock
+	// released count the times a message was released by message id, they
+	// don't count as attempts of the handler.
+	released  map[string]groupRelease
+	lastPrune time.Time
+}
+
+type groupBlock struct {
+	id    string
+	until time.Time
+}
+
+type groupRelease struct {
+	count int
+	// expires when the message is not seen anymore, like when it was
+	// processed by another consumer.
+	expires time.Time
+}
+
+func newGroupState() *groupState {
+	return &groupState{
+		failed:    make(map[string]groupBlock),
+		released:  make(map[string]groupRelease),
+		lastPrune: time.Now(),
+	}
+}
+
+// blocked report whether messages of group other than id must wait.
+func (g *groupState) blocked(group, id string, now time.Time) bool {
+	b, ok := g.failed[group]
+	if !ok {
+		return false
+	}
+	if b.id == id || !now.Before(b.until) {
+		delete(g.failed, group)
+		return false
+	}
+	return true
+}
+
+func (g *groupState) block(group, id string, until time.Time) {
+	g.failed[group] = groupBlock{id: id, until: until}
+	g.prune(time.Now())
+}
+
+func (g *groupState) release(id string, now time.Time) {
+	r := g.released[id]
+	r.count++
+	r.expires = now.Add(maxVisibilityTimeout)
+	g.released[id] = r
+	g.prune(now)
+}
+
+func (g *groupState) releases(id string) int {
+	return g.released[id].count
+}
+
+func (g *groupState) forget(id string) {
+	delete(g.released, id)
+}
+
+// prune remove expired state, so it doesn't grow forever.
+func (g *groupState) prune(now time.Time) {
+	if now.Sub(g.lastPrune) < groupPruneInterval {
+		return
+	}
+	g.lastPrune = now
+
+	for group, b := range g.failed {
+		if !now.Before(b.until) {
+			delete(g.failed, group)
+		}
+	}
+	for id, r := range g.released {
+		if !now.Before(r.expires) {
+			delete(g.released, id)
+		}
+	}
+}
+
+// messageGroup return the FIFO group of the message, if any.
+func messageGroup(msg Message) string {
+	if d, ok := DeliveryFromContext(msg.Context()); ok {
+		return d.GroupID
+	}
+	return ""
+}
+
+// process handle the message, returning false and the backoff when it will
+// be retried. Receives of the message that were released without calling the
+// handler don't count as attempts.
+func (c *Consumer) process(msg Message, released int) (time.Duration, bool) {
+	attempt, countErr := msg.GetReceiveCount()
+	if countErr != nil {
+		attempt = 1
+	}
+	attempt -= released
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	deadLetter := c.cfg.DeadLetter != nil && c.cfg.MaxReceiveCount > 0
+
+	// handler was not able to finish previous attempts, like when it crashed
+	// the message is retried when it's visible again
+	delay := c.cfg.Backoff(attempt)
+	if delay > maxVisibilityTimeout {
+		delay = maxVisibilityTimeout
+	}
+
+	if deadLetter && attempt > c.cfg.MaxReceiveCount {
+		return delay, c.deadLetter(msg, ErrMaxReceiveCount)
+	}
+
+	err := c.handle(msg)
+	if err == nil {
+		if err := msg.Done(); err != nil {
+			c.onError(msg, fmt.Errorf("pubsub: unable to ack message: %s", err))
+		}
+		return 0, true
+	}
+
+	c.onError(msg, err)
+
+	if deadLetter && attempt >= c.cfg.MaxReceiveCount {
+		return delay, c.deadLetter(msg, err)
+	}
+
+	if err := msg.ExtendDoneDeadline(delay); err != nil {
+		c.onError(msg, fmt.Errorf("pubsub: unable to backoff message: %s", err))
+	}
+	return delay, false
+}
+
+// deadLetter forward the message and remove it from the queue, when it fails
+// the message is received again.
+func (c *Consumer) deadLetter(msg Message, reason error) bool {
+	if err := ForwardToDeadLetter(msg.Context(), c.cfg.DeadLetter, msg, reason); err != nil {
+		c.onError(msg, fmt.Errorf("pubsub: unable to forward message to dead-letter: %s", err))
+		return false
+	}
+
+	if err := msg.Done(); err != nil {
+		c.onError(msg, fmt.Errorf("pubsub: unable to ack message: %s", err))
+	}
+	return true
+}
+
+// handle call the handler, extending the message while it runs.
+func (c *Consumer) handle(msg Message) (err error) {
+	ctx, cancel := context.WithCancel(msg.Context())
+	defer cancel()
+
+	// handler context is also canceled on shutdown
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if c.cfg.VisibilityTimeout > 0 {
+		extended := make(chan struct{})
+		go func() {
+			defer close(extended)
+			c.extend(ctx, msg)
+		}()
+
+		// message can't be extended after it's acknowledged
+		defer func() {
+			cancel()
+			<-extended
+		}()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			DefaultLogger.Printf("Handler panic: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("pubsub: handler panic: %v", r)
+		}
+	}()
+
+	return c.handler(ctx, msg)
+}
+
+// extend keep the message hidden until ctx is done, it's extended at half of
+// the visibility timeout to not lose it.
+func (c *Consumer) extend(ctx context.Context, msg Message) {
+	ticker := time.NewTicker(c.cfg.VisibilityTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := msg.ExtendDoneDeadline(c.cfg.VisibilityTimeout); err != nil {
+				c.onError(msg, fmt.Errorf("pubsub: unable to extend message: %s", err))
+			}
+		}
+	}
+}
+
+func (c *Consumer) onError(msg Message, err error) {
+	if c.cfg.OnError != nil {
+		c.cfg.OnError(msg, err)
+		return
+	}
+
+	DefaultLogger.Printf("Unable to process message %s: %s", msg.GetMessageId(), err)
+}
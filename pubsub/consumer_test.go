@@ -0,0 +1,341 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/fdbackoff"
+	"github.com/foodora/go-ranger/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMessage struct {
+	mu       sync.Mutex
+	body     string
+	count    int
+	attrs    pubsub.Attributes
+	ctx      context.Context
+	done     bool
+	extended []time.Duration
+}
+
+func (m *testMessage) String() string                { return m.body }
+func (m *testMessage) GetMessageId() string          { return m.body }
+func (m *testMessage) GetReceiveCount() (int, error) { return m.count, nil }
+func (m *testMessage) Attributes() pubsub.Attributes { return m.attrs }
+
+func (m *testMessage) Context() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
+func (m *testMessage) Done() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.done = true
+	return nil
+}
+
+func (m *testMessage) ExtendDoneDeadline(d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.extended = append(m.extended, d)
+	return nil
+}
+
+func (m *testMessage) state() (bool, []time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.done, append([]time.Duration(nil), m.extended...)
+}
+
+type testSubscriber struct {
+	msgs    chan pubsub.Message
+	stopped bool
+}
+
+func (s *testSubscriber) Start() <-chan pubsub.Message { return s.msgs }
+func (s *testSubscriber) Err() error                   { return nil }
+func (s *testSubscriber) SetOnErrorFunc(func(error))   {}
+
+func (s *testSubscriber) Stop() error {
+	s.stopped = true
+	return nil
+}
+
+// blockingSubscriber takes forever to stop.
+type blockingSubscriber struct {
+	testSubscriber
+}
+
+func (s *blockingSubscriber) Stop() error {
+	select {}
+}
+
+func TestStopSubscriber(t *testing.T) {
+	sub := &testSubscriber{}
+	assert.NoError(t, pubsub.StopSubscriber(context.Background(), sub))
+	assert.True(t, sub.stopped)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, pubsub.StopSubscriber(ctx, &blockingSubscriber{}))
+}
+
+func TestConsumer(t *testing.T) {
+	ok := &testMessage{body: "ok", count: 1}
+	failed := &testMessage{body: "failed", count: 3}
+	panicked := &testMessage{body: "panic", count: 1}
+
+	sub := &testSubscriber{msgs: make(chan pubsub.Message)}
+
+	var (
+		mu     sync.Mutex
+		errs   []error
+		called int
+	)
+	handler := func(ctx context.Context, msg pubsub.Message) error {
+		mu.Lock()
+		called++
+		mu.Unlock()
+
+		switch msg.String() {
+		case "failed":
+			return errors.New("unable to process")
+		case "panic":
+			panic("something went wrong")
+		}
+		return nil
+	}
+
+	consumer := pubsub.NewConsumer(sub, handler, pubsub.ConsumerConfig{
+		Workers: 2,
+		Backoff: fdbackoff.Linear(time.Second),
+		OnError: func(msg pubsub.Message, err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	})
+
+	started := make(chan error)
+	go func() {
+		started <- consumer.Start()
+	}()
+
+	sub.msgs <- ok
+	sub.msgs <- failed
+	sub.msgs <- panicked
+
+	assert.NoError(t, consumer.Stop(context.Background()))
+	assert.NoError(t, <-started)
+	assert.True(t, sub.stopped)
+
+	assert.Equal(t, 3, called)
+	assert.Len(t, errs, 2)
+
+	done, extended := ok.state()
+	assert.True(t, done)
+	assert.Empty(t, extended)
+
+	done, extended = failed.state()
+	assert.False(t, done)
+	assert.Equal(t, []time.Duration{3 * time.Second}, extended)
+
+	done, extended = panicked.state()
+	assert.False(t, done)
+	assert.Equal(t, []time.Duration{time.Second}, extended)
+
+	assert.Error(t, consumer.Stop(context.Background()))
+}
+
+func TestConsumerExtendVisibility(t *testing.T) {
+	msg := &testMessage{body: "slow", count: 1}
+	sub := &testSubscriber{msgs: make(chan pubsub.Message)}
+
+	consumer := pubsub.NewConsumer(sub, func(ctx context.Context, msg pubsub.Message) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}, pubsub.ConsumerConfig{
+		Workers:           1,
+		VisibilityTimeout: 20 * time.Millisecond,
+	})
+	go consumer.Start()
+
+	sub.msgs <- msg
+	assert.NoError(t, consumer.Stop(context.Background()))
+
+	done, extended := msg.state()
+	assert.True(t, done)
+	assert.True(t, len(extended) >= 2, "message should be extended while handler runs")
+	for _, d := range extended {
+		assert.Equal(t, 20*time.Millisecond, d)
+	}
+}
+
+func TestConsumerStopTimeout(t *testing.T) {
+	sub := &testSubscriber{msgs: make(chan pubsub.Message)}
+
+	canceled := make(chan struct{})
+	consumer := pubsub.NewConsumer(sub, func(ctx context.Context, msg pubsub.Message) error {
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	}, pubsub.ConsumerConfig{Workers: 1})
+	go consumer.Start()
+
+	sub.msgs <- &testMessage{body: "blocked", count: 1}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, consumer.Stop(ctx))
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("handler context was not canceled")
+	}
+}
+
+func groupMessage(id, group string) *testMessage {
+	return &testMessage{
+		body:  id,
+		count: 1,
+		ctx:   pubsub.SetDelivery(context.Background(), &pubsub.Delivery{GroupID: group}),
+	}
+}
+
+func TestConsumerGroupOrder(t *testing.T) {
+	sub := &testSubscriber{msgs: make(chan pubsub.Message)}
+
+	var (
+		mu        sync.Mutex
+		processed []string
+		running   = make(map[string]bool)
+		parallel  bool
+	)
+	consumer := pubsub.NewConsumer(sub, func(ctx context.Context, msg pubsub.Message) error {
+		group := msg.String()[:1]
+
+		mu.Lock()
+		if running[group] {
+			parallel = true
+		}
+		running[group] = true
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running[group] = false
+		processed = append(processed, msg.String())
+		mu.Unlock()
+
+		if n, _ := msg.GetReceiveCount(); msg.String() == "b1" && n == 1 {
+			return errors.New("unable to process")
+		}
+		return nil
+	}, pubsub.ConsumerConfig{
+		Workers: 4,
+		OnError: func(pubsub.Message, error) {},
+	})
+	go consumer.Start()
+
+	msgs := []*testMessage{
+		groupMessage("a1", "a"),
+		groupMessage("b1", "b"),
+		groupMessage("a2", "a"),
+		groupMessage("b2", "b"),
+		groupMessage("a3", "a"),
+	}
+	for _, m := range msgs {
+		sub.msgs <- m
+	}
+
+	// b1 is received again, unblocking the group
+	retry := groupMessage("b1", "b")
+	retry.count = 2
+	sub.msgs <- retry
+	sub.msgs <- groupMessage("b2", "b")
+	assert.NoError(t, consumer.Stop(context.Background()))
+
+	assert.False(t, parallel, "messages of the same group were processed in parallel")
+
+	var groupA, groupB []string
+	for _, id := range processed {
+		if id[:1] == "a" {
+			groupA = append(groupA, id)
+		} else {
+			groupB = append(groupB, id)
+		}
+	}
+	assert.Equal(t, []string{"a1", "a2", "a3"}, groupA)
+	assert.Equal(t, []string{"b1", "b1", "b2"}, groupB)
+
+	// b2 was released while b1 was waiting to be retried
+	done, extended := msgs[3].state()
+	assert.False(t, done)
+	assert.Equal(t, []time.Duration{0}, extended)
+
+	done, _ = retry.state()
+	assert.True(t, done)
+}
+
+func TestConsumerGroupBlockExpires(t *testing.T) {
+	sub := &testSubscriber{msgs: make(chan pubsub.Message)}
+	deadLetter := &testPublisher{}
+
+	var (
+		mu      sync.Mutex
+		handled []string
+	)
+	consumer := pubsub.NewConsumer(sub, func(ctx context.Context, msg pubsub.Message) error {
+		mu.Lock()
+		handled = append(handled, msg.String())
+		mu.Unlock()
+
+		if msg.String() == "b1" {
+			return errors.New("unable to process")
+		}
+		return nil
+	}, pubsub.ConsumerConfig{
+		Workers:         1,
+		Backoff:         func(int) time.Duration { return 100 * time.Millisecond },
+		OnError:         func(pubsub.Message, error) {},
+		MaxReceiveCount: 2,
+		DeadLetter:      deadLetter,
+	})
+	go consumer.Start()
+
+	// b1 fails and never comes back, like when it's received by another
+	// consumer, b2 is released while the group is blocked
+	sub.msgs <- groupMessage("b1", "b")
+	released := []*testMessage{groupMessage("b2", "b"), groupMessage("b2", "b")}
+	released[1].count = 2
+	for _, m := range released {
+		sub.msgs <- m
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	// releases don't count as attempts, so it's not dead-lettered
+	last := groupMessage("b2", "b")
+	last.count = 3
+	sub.msgs <- last
+	assert.NoError(t, consumer.Stop(context.Background()))
+
+	assert.Equal(t, []string{"b1", "b2"}, handled)
+	assert.Empty(t, deadLetter.messages())
+	for _, m := range released {
+		done, extended := m.state()
+		assert.False(t, done)
+		assert.Equal(t, []time.Duration{0}, extended)
+	}
+	done, _ := last.state()
+	assert.True(t, done)
+}
@@ -0,0 +1,153 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Attributes added to messages sent to the dead-letter publisher.
+const (
+	DeadLetterReasonAttribute       = "dead_letter_reason"
+	DeadLetterMessageIDAttribute    = "dead_letter_message_id"
+	DeadLetterReceiveCountAttribute = "dead_letter_receive_count"
+	DeadLetterSourceAttribute       = "dead_letter_source"
+
+	deadLetterAttributePrefix = "dead_letter_"
+)
+
+// ErrMaxReceiveCount is the reason of messages received more times than
+// allowed without being handled, like when the handler is killed.
+var ErrMaxReceiveCount = errors.New("max receive count exceeded")
+
+// DefaultReplayIdleTimeout is used by Replay when ReplayConfig.IdleTimeout is
+// not informed.
+var DefaultReplayIdleTimeout = 10 * time.Second
+
+// ForwardToDeadLetter publish msg to pub with the failure reason as attributes,
+// the message is not acknowledged. Messages of FIFO queues keep their group and
+// are deduplicated by message id, FIFO queues require a FIFO dead-letter queue.
+func ForwardToDeadLetter(ctx context.Context, pub Publisher, msg Message, reason error) error {
+	attrs := make(Attributes, len(msg.Attributes())+4)
+	for k, v := range msg.Attributes() {
+		attrs[k] = v
+	}
+
+	attrs[DeadLetterReasonAttribute] = StringAttribute(reason.Error())
+	attrs[DeadLetterMessageIDAttribute] = StringAttribute(msg.GetMessageId())
+	if n, err := msg.GetReceiveCount(); err == nil {
+		attrs[DeadLetterReceiveCountAttribute] = IntAttribute(int64(n))
+	}
+
+	m := &OutgoingMessage{
+		Body:       msg.String(),
+		Attributes: attrs,
+	}
+	if d, ok := DeliveryFromContext(msg.Context()); ok {
+		if d.Queue != "" {
+			attrs[DeadLetterSourceAttribute] = StringAttribute(d.Queue)
+		}
+	}
+	setDeliveryFields(m, msg)
+
+	return pub.PublishMessage(ctx, m)
+}
+
+// setDeliveryFields copy the subject and FIFO group of the received message.
+func setDeliveryFields(m *OutgoingMessage, msg Message) {
+	d, ok := DeliveryFromContext(msg.Context())
+	if !ok {
+		return
+	}
+
+	m.Key = d.Subject
+	if d.GroupID != "" {
+		m.GroupID = d.GroupID
+		m.DeduplicationID = msg.GetMessageId()
+	}
+}
+
+// ReplayConfig configure how messages are moved by Replay.
+type ReplayConfig struct {
+	// MaxMessages stop the replay after moving this number of messages, all
+	// of them are moved by default.
+	MaxMessages int
+	// IdleTimeout stop the replay when no message is received during this
+	// time, by default DefaultReplayIdleTimeout.
+	IdleTimeout time.Duration
+}
+
+// Replay move messages from a dead-letter subscriber back to pub, removing the
+// attributes added by ForwardToDeadLetter. Messages are acknowledged after being
+// published, and sub is stopped with StopSubscriber, so ctx also bounds the
+// shutdown. It returns the number of messages moved:
+//
+//  dlq, _ := awssub.NewSubscriber(awssub.SQSConfig{QueueName: "orders-dlq"})
+//  n, err := pubsub.Replay(ctx, dlq, ordersPublisher, pubsub.ReplayConfig{})
+func Replay(ctx context.Context, sub Subscriber, pub Publisher, cfg ReplayConfig) (int, error) {
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultReplayIdleTimeout
+	}
+
+	msgs := sub.Start()
+	if msgs == nil {
+		return 0, sub.Err()
+	}
+
+	n, err := replay(ctx, msgs, pub, cfg)
+	if stopErr := StopSubscriber(ctx, sub); stopErr != nil && err == nil {
+		err = stopErr
+	}
+	return n, err
+}
+
+func replay(ctx context.Context, msgs <-chan Message, pub Publisher, cfg ReplayConfig) (int, error) {
+	idle := time.NewTimer(cfg.IdleTimeout)
+	defer idle.Stop()
+
+	var n int
+	for cfg.MaxMessages <= 0 || n < cfg.MaxMessages {
+		var msg Message
+		select {
+		case <-ctx.Done():
+			return n, ctx.Err()
+		case <-idle.C:
+			return n, nil
+		case m, ok := <-msgs:
+			if !ok {
+				return n, nil
+			}
+			msg = m
+		}
+
+		attrs := make(Attributes, len(msg.Attributes()))
+		for k, v := range msg.Attributes() {
+			if !strings.HasPrefix(k, deadLetterAttributePrefix) {
+				attrs[k] = v
+			}
+		}
+
+		m := &OutgoingMessage{
+			Body:       msg.String(),
+			Attributes: attrs,
+		}
+		setDeliveryFields(m, msg)
+
+		if err := pub.PublishMessage(msg.Context(), m); err != nil {
+			return n, fmt.Errorf("pubsub: unable to replay message %s: %s", msg.GetMessageId(), err)
+		}
+		if err := msg.Done(); err != nil {
+			return n, fmt.Errorf("pubsub: unable to ack replayed message %s: %s", msg.GetMessageId(), err)
+		}
+		n++
+
+		if !idle.Stop() {
+			<-idle.C
+		}
+		idle.Reset(cfg.IdleTimeout)
+	}
+
+	return n, nil
+}
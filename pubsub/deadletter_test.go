@@ -0,0 +1,240 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+type testPublisher struct {
+	mu        sync.Mutex
+	err       error
+	published []*pubsub.OutgoingMessage
+}
+
+func (p *testPublisher) Publish(ctx context.Context, key string, m string) error {
+	return p.PublishMessage(ctx, &pubsub.OutgoingMessage{Key: key, Body: m})
+}
+
+func (p *testPublisher) PublishToTopic(ctx context.Context, key string, m string, topic string) error {
+	return p.PublishMessage(ctx, &pubsub.OutgoingMessage{Key: key, Body: m, Topic: topic})
+}
+
+func (p *testPublisher) PublishMessage(ctx context.Context, m *pubsub.OutgoingMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, m)
+	return nil
+}
+
+func (p *testPublisher) messages() []*pubsub.OutgoingMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*pubsub.OutgoingMessage(nil), p.published...)
+}
+
+func TestForwardToDeadLetter(t *testing.T) {
+	pub := &testPublisher{}
+	msg := &testMessage{
+		body:  "order",
+		count: 5,
+		attrs: pubsub.Attributes{"event": pubsub.StringAttribute("order_created")},
+		ctx: pubsub.SetDelivery(context.Background(), &pubsub.Delivery{
+			Subject: "orders",
+			Queue:   "http://orders",
+		}),
+	}
+
+	err := pubsub.ForwardToDeadLetter(context.Background(), pub, msg, errors.New("invalid order"))
+	assert.NoError(t, err)
+	assert.Equal(t, []*pubsub.OutgoingMessage{{
+		Key:  "orders",
+		Body: "order",
+		Attributes: pubsub.Attributes{
+			"event":                                pubsub.StringAttribute("order_created"),
+			pubsub.DeadLetterReasonAttribute:       pubsub.StringAttribute("invalid order"),
+			pubsub.DeadLetterMessageIDAttribute:    pubsub.StringAttribute("order"),
+			pubsub.DeadLetterReceiveCountAttribute: pubsub.IntAttribute(5),
+			pubsub.DeadLetterSourceAttribute:       pubsub.StringAttribute("http://orders"),
+		},
+	}}, pub.messages())
+
+	// original message is untouched
+	assert.Len(t, msg.attrs, 1)
+}
+
+func TestConsumerDeadLetter(t *testing.T) {
+	retried := &testMessage{body: "retried", count: 2}
+	failed := &testMessage{body: "failed", count: 3}
+	crashed := &testMessage{body: "crashed", count: 4}
+
+	sub := &testSubscriber{msgs: make(chan pubsub.Message)}
+	dlq := &testPublisher{}
+
+	var (
+		mu     sync.Mutex
+		called []string
+	)
+	consumer := pubsub.NewConsumer(sub, func(ctx context.Context, msg pubsub.Message) error {
+		mu.Lock()
+		called = append(called, msg.String())
+		mu.Unlock()
+		return errors.New("unable to process")
+	}, pubsub.ConsumerConfig{
+		Workers:         1,
+		MaxReceiveCount: 3,
+		DeadLetter:      dlq,
+		OnError:         func(pubsub.Message, error) {},
+	})
+	go consumer.Start()
+
+	sub.msgs <- retried
+	sub.msgs <- failed
+	sub.msgs <- crashed
+	assert.NoError(t, consumer.Stop(context.Background()))
+
+	assert.Equal(t, []string{"retried", "failed"}, called)
+
+	done, extended := retried.state()
+	assert.False(t, done)
+	assert.Len(t, extended, 1)
+
+	done, _ = failed.state()
+	assert.True(t, done)
+	done, _ = crashed.state()
+	assert.True(t, done)
+
+	published := dlq.messages()
+	if assert.Len(t, published, 2) {
+		assert.Equal(t, "unable to process", published[0].Attributes.Get(pubsub.DeadLetterReasonAttribute))
+		assert.Equal(t, pubsub.ErrMaxReceiveCount.Error(), published[1].Attributes.Get(pubsub.DeadLetterReasonAttribute))
+	}
+}
+
+func TestConsumerDeadLetterFailure(t *testing.T) {
+	msg := &testMessage{body: "failed", count: 3}
+	sub := &testSubscriber{msgs: make(chan pubsub.Message)}
+	dlq := &testPublisher{err: errors.New("topic does not exist")}
+
+	consumer := pubsub.NewConsumer(sub, func(ctx context.Context, msg pubsub.Message) error {
+		return errors.New("unable to process")
+	}, pubsub.ConsumerConfig{
+		Workers:         1,
+		MaxReceiveCount: 3,
+		DeadLetter:      dlq,
+		OnError:         func(pubsub.Message, error) {},
+	})
+	go consumer.Start()
+
+	sub.msgs <- msg
+	assert.NoError(t, consumer.Stop(context.Background()))
+
+	// message is received again
+	done, _ := msg.state()
+	assert.False(t, done)
+}
+
+func TestReplay(t *testing.T) {
+	sub := &testSubscriber{msgs: make(chan pubsub.Message, 3)}
+	pub := &testPublisher{}
+
+	msgs := []*testMessage{
+		{body: "1", attrs: pubsub.Attributes{
+			"event":                          pubsub.StringAttribute("order_created"),
+			pubsub.DeadLetterReasonAttribute: pubsub.StringAttribute("invalid order"),
+		}},
+		{body: "2"},
+		{body: "3"},
+	}
+	for _, m := range msgs {
+		sub.msgs <- m
+	}
+
+	n, err := pubsub.Replay(context.Background(), sub, pub, pubsub.ReplayConfig{
+		MaxMessages: 2,
+		IdleTimeout: 10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.True(t, sub.stopped)
+
+	assert.Equal(t, []*pubsub.OutgoingMessage{
+		{Body: "1", Attributes: pubsub.Attributes{"event": pubsub.StringAttribute("order_created")}},
+		{Body: "2", Attributes: pubsub.Attributes{}},
+	}, pub.messages())
+
+	done, _ := msgs[1].state()
+	assert.True(t, done)
+
+	// stop when there are no more messages
+	n, err = pubsub.Replay(context.Background(), sub, pub, pubsub.ReplayConfig{
+		IdleTimeout: 10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	pub.err = errors.New("topic does not exist")
+	sub.msgs <- &testMessage{body: "4"}
+	_, err = pubsub.Replay(context.Background(), sub, pub, pubsub.ReplayConfig{
+		IdleTimeout: 10 * time.Millisecond,
+	})
+	assert.Error(t, err)
+}
+
+func TestDeadLetterFIFO(t *testing.T) {
+	pub := &testPublisher{}
+	msg := &testMessage{
+		body:  "payment",
+		count: 3,
+		ctx: pubsub.SetDelivery(context.Background(), &pubsub.Delivery{
+			Subject: "payment_captured",
+			GroupID: "order-1",
+		}),
+	}
+
+	err := pubsub.ForwardToDeadLetter(context.Background(), pub, msg, errors.New("invalid payment"))
+	assert.NoError(t, err)
+
+	sub := &testSubscriber{msgs: make(chan pubsub.Message, 1)}
+	sub.msgs <- &testMessage{
+		body: "dlq-payment",
+		ctx: pubsub.SetDelivery(context.Background(), &pubsub.Delivery{
+			GroupID: "order-1",
+		}),
+	}
+	n, err := pubsub.Replay(context.Background(), sub, pub, pubsub.ReplayConfig{
+		IdleTimeout: 10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	msgs := pub.messages()
+	if !assert.Len(t, msgs, 2) {
+		return
+	}
+	assert.Equal(t, "payment_captured", msgs[0].Key)
+	assert.Equal(t, "order-1", msgs[0].GroupID)
+	assert.Equal(t, "payment", msgs[0].DeduplicationID)
+	assert.Equal(t, "order-1", msgs[1].GroupID)
+	assert.Equal(t, "dlq-payment", msgs[1].DeduplicationID)
+}
+
+func TestReplayStopTimeout(t *testing.T) {
+	sub := &blockingSubscriber{testSubscriber{msgs: make(chan pubsub.Message)}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := pubsub.Replay(ctx, sub, &testPublisher{}, pubsub.ReplayConfig{IdleTimeout: time.Hour})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Second, "replay waited for the subscriber to stop")
+}
@@ -0,0 +1,180 @@
+package pubsub
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Defaults used when IdempotencyConfig and NewMemoryIdempotencyStore
+// arguments are not informed.
+var (
+	DefaultIdempotencyTTL        = 24 * time.Hour
+	DefaultMemoryIdempotencySize = 10000
+)
+
+// IdempotencyStore keep the keys of processed messages.
+type IdempotencyStore interface {
+	// Processed report whether key was marked as processed and didn't
+	// expire yet.
+	Processed(ctx context.Context, key string) (bool, error)
+	// MarkProcessed record key as processed during ttl.
+	MarkProcessed(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// IdempotencyConfig configure IdempotentHandler.
+type IdempotencyConfig struct {
+	// Store is required.
+	Store IdempotencyStore
+	// Key identify the message, by default GetMessageId() is used. Messages
+	// with an empty key are always handled.
+	Key func(Message) string
+	// TTL is how long a message is remembered, by default
+	// DefaultIdempotencyTTL. It should be longer than the retention of the
+	// queue.
+	TTL time.Duration
+	// OnDuplicate is called for each skipped message, like the callback
+	// returned by fdmetrics.DuplicateMessages.
+	OnDuplicate func(Message)
+}
+
+// IdempotentHandler skip messages already handled successfully, SQS and SNS
+// deliver messages at least once. Duplicates are acknowledged without calling
+// handler:
+//
+//  store := pubsub.NewSQLIdempotencyStore(db, pubsub.SQLIdempotencyConfig{Dialect: "mysql"})
+//  handler = pubsub.IdempotentHandler(handler, pubsub.IdempotencyConfig{Store: store})
+//
+// Messages are marked as processed after handler succeeds, so the same message
+// received concurrently by two consumers, like after its visibility timeout
+// expired, may still be handled twice.
+func IdempotentHandler(handler Handler, cfg IdempotencyConfig) Handler {
+	if handler == nil || cfg.Store == nil {
+		panic("pubsub: handler and idempotency store are required")
+	}
+	if cfg.Key == nil {
+		cfg.Key = Message.GetMessageId
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultIdempotencyTTL
+	}
+
+	return func(ctx context.Context, msg Message) error {
+		key := cfg.Key(msg)
+		if key == "" {
+			return handler(ctx, msg)
+		}
+
+		processed, err := cfg.Store.Processed(ctx, key)
+		if err != nil {
+			// handling it could process a duplicate, better try again later
+			return fmt.Errorf("pubsub: unable to check if message %s was processed: %s", key, err)
+		}
+		if processed {
+			if cfg.OnDuplicate != nil {
+				cfg.OnDuplicate(msg)
+			}
+			return nil
+		}
+
+		if err := handler(ctx, msg); err != nil {
+			return err
+		}
+
+		// the message was handled, returning an error would handle it again
+		if err := cfg.Store.MarkProcessed(ctx, key, cfg.TTL); err != nil {
+			DefaultLogger.Printf("Unable to mark message %s as processed: %s", key, err)
+		}
+		return nil
+	}
+}
+
+var _ IdempotencyStore = &MemoryIdempotencyStore{}
+
+// MemoryIdempotencyStore keep the most recently processed keys in memory, it
+// only deduplicates messages received by the same process.
+type MemoryIdempotencyStore struct {
+	mu    sync.Mutex
+	size  int
+	keys  map[string]*list.Element
+	order *list.List
+}
+
+type memoryIdempotencyEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// NewMemoryIdempotencyStore create a store keeping up to size keys, the least
+// recently used are removed first. DefaultMemoryIdempotencySize is used when
+// size is not positive.
+func NewMemoryIdempotencyStore(size int) *MemoryIdempotencyStore {
+	if size <= 0 {
+		size = DefaultMemoryIdempotencySize
+	}
+
+	return &MemoryIdempotencyStore{
+		size:  size,
+		keys:  make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+// Processed report whether key is in the store and didn't expire.
+func (s *MemoryIdempotencyStore) Processed(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.keys[key]
+	if !ok {
+		return false, nil
+	}
+
+	if !time.Now().Before(el.Value.(*memoryIdempotencyEntry).expiresAt) {
+		s.remove(el)
+		return false, nil
+	}
+
+	s.order.MoveToFront(el)
+	return true, nil
+}
+
+// MarkProcessed add key to the store, removing the least recently used key
+// when it's full.
+func (s *MemoryIdempotencyStore) MarkProcessed(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if el, ok := s.keys[key]; ok {
+		el.Value.(*memoryIdempotencyEntry).expiresAt = expiresAt
+		s.order.MoveToFront(el)
+		return nil
+	}
+
+	s.keys[key] = s.order.PushFront(&memoryIdempotencyEntry{
+		key:       key,
+		expiresAt: expiresAt,
+	})
+	for s.order.Len() > s.size {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+// Len return the number of keys in the store, including expired ones not
+// removed yet.
+func (s *MemoryIdempotencyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+// remove el from the store. Caller must hold the lock.
+func (s *MemoryIdempotencyStore) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.keys, el.Value.(*memoryIdempotencyEntry).key)
+}
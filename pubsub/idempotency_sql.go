@@ -0,0 +1,111 @@
+package pubsub
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DefaultIdempotencyTable is used by NewSQLIdempotencyStore when
+// SQLIdempotencyConfig.Table is not informed.
+var DefaultIdempotencyTable = "pubsub_processed_messages"
+
+// SQLIdempotencyConfig configure the SQL store.
+type SQLIdempotencyConfig struct {
+	// Dialect is the driver of the database, mysql or postgres like
+	// fddb.DBConfig.Driver.
+	Dialect string
+	// Table keeping processed keys, by default DefaultIdempotencyTable.
+	Table string
+}
+
+var _ IdempotencyStore = &SQLIdempotencyStore{}
+
+// SQLIdempotencyStore keep processed keys in a table, sharing them between all
+// instances of a consumer. The table can be created with CreateTable:
+//
+//  CREATE TABLE pubsub_processed_messages (
+//      message_key VARCHAR(255) NOT NULL PRIMARY KEY,
+//      expires_at BIGINT NOT NULL
+//  )
+//
+// Expired keys are ignored, they can be removed with DeleteExpired.
+type SQLIdempotencyStore struct {
+	db          *sql.DB
+	table       string
+	placeholder func(n int) string
+	upsert      string
+}
+
+// NewSQLIdempotencyStore create a store using db, like the one returned by
+// fddb.OpenSQL. It panics if the dialect is not supported.
+func NewSQLIdempotencyStore(db *sql.DB, cfg SQLIdempotencyConfig) *SQLIdempotencyStore {
+	if db == nil {
+		panic("pubsub: db is required")
+	}
+	if cfg.Table == "" {
+		cfg.Table = DefaultIdempotencyTable
+	}
+
+	s := &SQLIdempotencyStore{
+		db:    db,
+		table: cfg.Table,
+	}
+
+	switch cfg.Dialect {
+	case "mysql":
+		s.placeholder = func(int) string { return "?" }
+		s.upsert = fmt.Sprintf("INSERT INTO %s (message_key, expires_at) VALUES (?, ?) "+
+			"ON DUPLICATE KEY UPDATE expires_at = VALUES(expires_at)", s.table)
+	case "postgres":
+		s.placeholder = func(n int) string { return fmt.Sprintf("$%d", n) }
+		s.upsert = fmt.Sprintf("INSERT INTO %s (message_key, expires_at) VALUES ($1, $2) "+
+			"ON CONFLICT (message_key) DO UPDATE SET expires_at = EXCLUDED.expires_at", s.table)
+	default:
+		panic(fmt.Sprintf("pubsub: unsupported sql dialect %q", cfg.Dialect))
+	}
+
+	return s
+}
+
+// CreateTable create the table if it doesn't exist.
+func (s *SQLIdempotencyStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+		"message_key VARCHAR(255) NOT NULL PRIMARY KEY, "+
+		"expires_at BIGINT NOT NULL)", s.table))
+	return err
+}
+
+// Processed report whether key is in the table and didn't expire.
+func (s *SQLIdempotencyStore) Processed(ctx context.Context, key string) (bool, error) {
+	query := fmt.Sprintf("SELECT expires_at FROM %s WHERE message_key = %s", s.table, s.placeholder(1))
+
+	var expiresAt int64
+	err := s.db.QueryRowContext(ctx, query, key).Scan(&expiresAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return time.Now().Unix() < expiresAt, nil
+}
+
+// MarkProcessed insert key, or renew its expiration.
+func (s *SQLIdempotencyStore) MarkProcessed(ctx context.Context, key string, ttl time.Duration) error {
+	_, err := s.db.ExecContext(ctx, s.upsert, key, time.Now().Add(ttl).Unix())
+	return err
+}
+
+// DeleteExpired remove expired keys, returning how many were removed.
+func (s *SQLIdempotencyStore) DeleteExpired(ctx context.Context) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE expires_at <= %s", s.table, s.placeholder(1))
+
+	res, err := s.db.ExecContext(ctx, query, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
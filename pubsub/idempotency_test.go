@@ -0,0 +1,240 @@
+package pubsub_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotentHandler(t *testing.T) {
+	var handled []string
+	handler := func(ctx context.Context, msg pubsub.Message) error {
+		handled = append(handled, msg.String())
+		if msg.String() == "failed" {
+			return errors.New("unable to charge")
+		}
+		return nil
+	}
+
+	var duplicates []string
+	store := pubsub.NewMemoryIdempotencyStore(0)
+	idempotent := pubsub.IdempotentHandler(handler, pubsub.IdempotencyConfig{
+		Store: store,
+		OnDuplicate: func(msg pubsub.Message) {
+			duplicates = append(duplicates, msg.String())
+		},
+	})
+
+	ctx := context.Background()
+	assert.NoError(t, idempotent(ctx, &testMessage{body: "charge"}))
+	assert.NoError(t, idempotent(ctx, &testMessage{body: "charge"}))
+	assert.Error(t, idempotent(ctx, &testMessage{body: "failed"}))
+	assert.Error(t, idempotent(ctx, &testMessage{body: "failed"}))
+
+	assert.Equal(t, []string{"charge", "failed", "failed"}, handled)
+	assert.Equal(t, []string{"charge"}, duplicates)
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestIdempotentHandlerKey(t *testing.T) {
+	var handled int
+	idempotent := pubsub.IdempotentHandler(func(ctx context.Context, msg pubsub.Message) error {
+		handled++
+		return nil
+	}, pubsub.IdempotencyConfig{
+		Store: pubsub.NewMemoryIdempotencyStore(10),
+		Key: func(msg pubsub.Message) string {
+			return msg.Attributes().Get("payment_id")
+		},
+	})
+
+	ctx := context.Background()
+	payment := pubsub.Attributes{"payment_id": pubsub.StringAttribute("p-1")}
+	assert.NoError(t, idempotent(ctx, &testMessage{body: "1", attrs: payment}))
+	assert.NoError(t, idempotent(ctx, &testMessage{body: "2", attrs: payment}))
+	// messages without key are always handled
+	assert.NoError(t, idempotent(ctx, &testMessage{body: "3"}))
+	assert.NoError(t, idempotent(ctx, &testMessage{body: "3"}))
+	assert.Equal(t, 3, handled)
+
+	assert.Panics(t, func() {
+		pubsub.IdempotentHandler(func(context.Context, pubsub.Message) error { return nil }, pubsub.IdempotencyConfig{})
+	})
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	store := pubsub.NewMemoryIdempotencyStore(2)
+
+	assert.NoError(t, store.MarkProcessed(ctx, "a", time.Hour))
+	assert.NoError(t, store.MarkProcessed(ctx, "b", time.Hour))
+
+	// a becomes the most recently used, so b is removed
+	processed, _ := store.Processed(ctx, "a")
+	assert.True(t, processed)
+	assert.NoError(t, store.MarkProcessed(ctx, "c", time.Hour))
+	assert.Equal(t, 2, store.Len())
+
+	processed, _ = store.Processed(ctx, "b")
+	assert.False(t, processed)
+	processed, _ = store.Processed(ctx, "c")
+	assert.True(t, processed)
+
+	assert.NoError(t, store.MarkProcessed(ctx, "d", time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	processed, _ = store.Processed(ctx, "d")
+	assert.False(t, processed)
+	assert.Equal(t, 1, store.Len())
+}
+
+// testSQLDriver keep rows of the idempotency table in memory, it only
+// understands the queries of SQLIdempotencyStore.
+type testSQLDriver struct {
+	mu      sync.Mutex
+	rows    map[string]int64
+	queries []string
+}
+
+func (d *testSQLDriver) Open(string) (driver.Conn, error) { return &testSQLConn{d: d}, nil }
+
+type testSQLConn struct {
+	d *testSQLDriver
+}
+
+func (c *testSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return &testSQLStmt{d: c.d, query: query}, nil
+}
+func (c *testSQLConn) Close() error              { return nil }
+func (c *testSQLConn) Begin() (driver.Tx, error) { return nil, errors.New("not implemented") }
+
+type testSQLStmt struct {
+	d     *testSQLDriver
+	query string
+}
+
+func (s *testSQLStmt) Close() error  { return nil }
+func (s *testSQLStmt) NumInput() int { return -1 }
+
+func (s *testSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.queries = append(s.d.queries, s.query)
+
+	switch {
+	case strings.HasPrefix(s.query, "INSERT"):
+		s.d.rows[args[0].(string)] = args[1].(int64)
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "DELETE"):
+		var n int64
+		for k, expiresAt := range s.d.rows {
+			if expiresAt <= args[0].(int64) {
+				delete(s.d.rows, k)
+				n++
+			}
+		}
+		return driver.RowsAffected(n), nil
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (s *testSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.queries = append(s.d.queries, s.query)
+
+	rows := &testSQLRows{}
+	if expiresAt, ok := s.d.rows[args[0].(string)]; ok {
+		rows.values = append(rows.values, expiresAt)
+	}
+	return rows, nil
+}
+
+type testSQLRows struct {
+	values []int64
+}
+
+func (r *testSQLRows) Columns() []string { return []string{"expires_at"} }
+func (r *testSQLRows) Close() error      { return nil }
+
+func (r *testSQLRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.values[0]
+	r.values = r.values[1:]
+	return nil
+}
+
+var testSQL = &testSQLDriver{rows: make(map[string]int64)}
+
+func init() {
+	sql.Register("pubsub-test", testSQL)
+}
+
+func TestSQLIdempotencyStore(t *testing.T) {
+	db, _ := sql.Open("pubsub-test", "")
+	defer db.Close()
+
+	ctx := context.Background()
+	store := pubsub.NewSQLIdempotencyStore(db, pubsub.SQLIdempotencyConfig{Dialect: "postgres"})
+	assert.NoError(t, store.CreateTable(ctx))
+
+	processed, err := store.Processed(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, processed)
+
+	assert.NoError(t, store.MarkProcessed(ctx, "a", time.Hour))
+	assert.NoError(t, store.MarkProcessed(ctx, "b", -time.Hour))
+
+	processed, err = store.Processed(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, processed)
+
+	processed, err = store.Processed(ctx, "b")
+	assert.NoError(t, err)
+	assert.False(t, processed)
+
+	n, err := store.DeleteExpired(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	testSQL.mu.Lock()
+	queries := testSQL.queries
+	testSQL.mu.Unlock()
+	assert.Equal(t, []string{
+		"CREATE TABLE IF NOT EXISTS pubsub_processed_messages (message_key VARCHAR(255) NOT NULL PRIMARY KEY, expires_at BIGINT NOT NULL)",
+		"SELECT expires_at FROM pubsub_processed_messages WHERE message_key = $1",
+		"INSERT INTO pubsub_processed_messages (message_key, expires_at) VALUES ($1, $2) ON CONFLICT (message_key) DO UPDATE SET expires_at = EXCLUDED.expires_at",
+		"INSERT INTO pubsub_processed_messages (message_key, expires_at) VALUES ($1, $2) ON CONFLICT (message_key) DO UPDATE SET expires_at = EXCLUDED.expires_at",
+		"SELECT expires_at FROM pubsub_processed_messages WHERE message_key = $1",
+		"SELECT expires_at FROM pubsub_processed_messages WHERE message_key = $1",
+		"DELETE FROM pubsub_processed_messages WHERE expires_at <= $1",
+	}, queries)
+
+	mysql := pubsub.NewSQLIdempotencyStore(db, pubsub.SQLIdempotencyConfig{Dialect: "mysql", Table: "payments_processed"})
+	assert.NoError(t, mysql.MarkProcessed(ctx, "c", time.Hour))
+	processed, err = mysql.Processed(ctx, "c")
+	assert.NoError(t, err)
+	assert.True(t, processed)
+
+	testSQL.mu.Lock()
+	queries = testSQL.queries[len(testSQL.queries)-2:]
+	testSQL.mu.Unlock()
+	assert.Equal(t, []string{
+		"INSERT INTO payments_processed (message_key, expires_at) VALUES (?, ?) ON DUPLICATE KEY UPDATE expires_at = VALUES(expires_at)",
+		"SELECT expires_at FROM payments_processed WHERE message_key = ?",
+	}, queries)
+
+	assert.Panics(t, func() {
+		pubsub.NewSQLIdempotencyStore(db, pubsub.SQLIdempotencyConfig{Dialect: "mongodb"})
+	})
+}
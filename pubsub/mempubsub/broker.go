@@ -0,0 +1,277 @@
+// Package mempubsub is an in-memory implementation of pubsub.Publisher and
+// pubsub.Subscriber, it behaves like SNS topics delivering to SQS queues and
+// it's useful for tests and local development:
+//
+//  broker := mempubsub.NewBroker()
+//  broker.Subscribe("orders", "orders-consumer")
+//
+//  pub := broker.NewPublisher("orders")
+//  sub := broker.NewSubscriber("orders-consumer")
+//
+//  // in tests
+//  msgs := broker.AssertPublished(t, "orders", 1, time.Second)
+//  assert.Equal(t, `{"id":1}`, msgs[0].Body)
+package mempubsub
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/foodora/go-ranger/pubsub"
+)
+
+// DefaultVisibilityTimeout is the visibility timeout of queues created by
+// Subscribe or NewSubscriber.
+var DefaultVisibilityTimeout = 30 * time.Second
+
+// TestingT is the interface used by assertion helpers, it's implemented by
+// *testing.T.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// Broker keep topics and queues in memory. Messages published to a topic are
+// copied to all queues subscribed to it.
+type Broker struct {
+	mu        sync.Mutex
+	topics    map[string][]*queue
+	queues    map[string]*queue
+	published []*pubsub.OutgoingMessage
+	nextID    int
+	// propagator is given to publishers and subscribers of the broker.
+	propagator pubsub.Propagator
+	// changed is closed and replaced when messages are published or become
+	// visible, that way waiters are notified.
+	changed chan struct{}
+}
+
+// NewBroker create an empty broker.
+func NewBroker() *Broker {
+	return &Broker{
+		topics:  make(map[string][]*queue),
+		queues:  make(map[string]*queue),
+		changed: make(chan struct{}),
+	}
+}
+
+// SetPropagator set the propagator of publishers and subscribers created
+// afterwards, like the one configured in production. By default
+// pubsub.DefaultPropagator is used:
+//
+//  broker := mempubsub.NewBroker().SetPropagator(fdapm.TracePropagator{})
+func (b *Broker) SetPropagator(p pubsub.Propagator) *Broker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.propagator = p
+	return b
+}
+
+// CreateQueue create a queue with the given visibility timeout, or change the
+// timeout if it already exists.
+func (b *Broker) CreateQueue(name string, visibilityTimeout time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.queue(name).visibilityTimeout = visibilityTimeout
+}
+
+// Subscribe deliver messages published to topic to queue, creating it if
+// necessary.
+func (b *Broker) Subscribe(topic, queue string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.queue(queue)
+	for _, subscribed := range b.topics[topic] {
+		if subscribed == q {
+			return
+		}
+	}
+	b.topics[topic] = append(b.topics[topic], q)
+}
+
+// queue return the queue by name, creating it if necessary. Caller must hold
+// the lock.
+func (b *Broker) queue(name string) *queue {
+	q, ok := b.queues[name]
+	if !ok {
+		q = &queue{name: name, visibilityTimeout: DefaultVisibilityTimeout}
+		b.queues[name] = q
+	}
+	return q
+}
+
+// notify wake up everybody waiting for changes. Caller must hold the lock.
+func (b *Broker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (b *Broker) publish(m *pubsub.OutgoingMessage) error {
+	if m.Topic == "" {
+		return errors.New("mempubsub: topic not informed")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.published = append(b.published, m)
+
+	now := time.Now()
+	for _, q := range b.topics[m.Topic] {
+		b.nextID++
+		q.messages = append(q.messages, &queuedMessage{
+			id:          strconv.Itoa(b.nextID),
+			msg:         m,
+			publishedAt: now,
+		})
+	}
+
+	b.notify()
+	return nil
+}
+
+// Published return messages published to topic, in order. Attributes injected
+// by the propagator are included.
+func (b *Broker) Published(topic string) []*pubsub.OutgoingMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.publishedTo(topic)
+}
+
+func (b *Broker) publishedTo(topic string) []*pubsub.OutgoingMessage {
+	var msgs []*pubsub.OutgoingMessage
+	for _, m := range b.published {
+		if m.Topic == topic {
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs
+}
+
+// PublishedBodies return the body of messages published to topic.
+func (b *Broker) PublishedBodies(topic string) []string {
+	msgs := b.Published(topic)
+
+	bodies := make([]string, len(msgs))
+	for i, m := range msgs {
+		bodies[i] = m.Body
+	}
+	return bodies
+}
+
+// WaitForPublished wait until at least n messages are published to topic,
+// returning all of them.
+func (b *Broker) WaitForPublished(topic string, n int, timeout time.Duration) ([]*pubsub.OutgoingMessage, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		b.mu.Lock()
+		msgs := b.publishedTo(topic)
+		changed := b.changed
+		b.mu.Unlock()
+
+		if len(msgs) >= n {
+			return msgs, nil
+		}
+
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return msgs, fmt.Errorf("mempubsub: expected %d messages published to %q, got %d", n, topic, len(msgs))
+		}
+	}
+}
+
+// AssertPublished wait until n messages are published to topic, failing the
+// test if they're not published before the timeout.
+func (b *Broker) AssertPublished(t TestingT, topic string, n int, timeout time.Duration) []*pubsub.OutgoingMessage {
+	msgs, err := b.WaitForPublished(topic, n, timeout)
+	if err != nil {
+		t.Errorf("%s", err)
+	}
+	return msgs
+}
+
+// QueueLen return the number of messages in the queue, including the ones
+// received but not acknowledged yet.
+func (b *Broker) QueueLen(queue string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queue]
+	if !ok {
+		return 0
+	}
+	return len(q.messages)
+}
+
+// Reset remove all messages, topics and queues are kept.
+func (b *Broker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.published = nil
+	for _, q := range b.queues {
+		q.messages = nil
+	}
+}
+
+type queue struct {
+	name              string
+	visibilityTimeout time.Duration
+	messages          []*queuedMessage
+}
+
+type queuedMessage struct {
+	id              string
+	msg             *pubsub.OutgoingMessage
+	publishedAt     time.Time
+	firstReceivedAt time.Time
+	receiveCount    int
+	visibleAt       time.Time
+	// receipt change each time the message is received, old receipts can't
+	// acknowledge or extend it anymore.
+	receipt int
+}
+
+// receive return the next visible message, or how long until one becomes
+// visible. Caller must hold the lock.
+func (q *queue) receive(now time.Time) (*queuedMessage, time.Duration) {
+	var wait time.Duration = -1
+	for _, m := range q.messages {
+		if !m.visibleAt.After(now) {
+			if m.receiveCount == 0 {
+				m.firstReceivedAt = now
+			}
+			m.receiveCount++
+			m.receipt++
+			m.visibleAt = now.Add(q.visibilityTimeout)
+			return m, 0
+		}
+
+		if d := m.visibleAt.Sub(now); wait < 0 || d < wait {
+			wait = d
+		}
+	}
+	return nil, wait
+}
+
+// find return the message if receipt is still valid. Caller must hold the lock.
+func (q *queue) find(id string, receipt int) (int, *queuedMessage) {
+	for i, m := range q.messages {
+		if m.id == id {
+			if m.receipt != receipt {
+				return -1, nil
+			}
+			return i, m
+		}
+	}
+	return -1, nil
+}
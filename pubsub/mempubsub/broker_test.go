@@ -0,0 +1,199 @@
+package mempubsub_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/foodora/go-ranger/pubsub"
+	"github.com/foodora/go-ranger/pubsub/mempubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+type testingT struct {
+	errors []string
+}
+
+func (t *testingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func receive(t *testing.T, msgs <-chan pubsub.Message) pubsub.Message {
+	select {
+	case msg := <-msgs:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("message was not received")
+		return nil
+	}
+}
+
+func TestBrokerFanOut(t *testing.T) {
+	broker := mempubsub.NewBroker()
+	broker.Subscribe("orders", "billing")
+	broker.Subscribe("orders", "shipping")
+	broker.Subscribe("orders", "shipping")
+
+	pub := broker.NewPublisher("orders")
+	ctx := pubsub.SetCorrelationID(context.Background(), "req-123")
+	assert.NoError(t, pub.PublishMessage(ctx, &pubsub.OutgoingMessage{
+		Key:        "order_created",
+		Body:       `{"id":1}`,
+		Attributes: pubsub.Attributes{"country": pubsub.StringAttribute("de")},
+	}))
+	assert.NoError(t, pub.PublishToTopic(context.Background(), "", "not delivered", "payments"))
+	assert.Error(t, broker.NewPublisher("").Publish(context.Background(), "", "no topic"))
+
+	assert.Equal(t, 1, broker.QueueLen("billing"))
+	assert.Equal(t, 1, broker.QueueLen("shipping"))
+	assert.Equal(t, []string{"not delivered"}, broker.PublishedBodies("payments"))
+
+	for _, queue := range []string{"billing", "shipping"} {
+		sub := broker.NewSubscriber(queue)
+		msg := receive(t, sub.Start())
+
+		assert.Equal(t, `{"id":1}`, msg.String())
+		assert.Equal(t, "de", msg.Attributes().Get("country"))
+		assert.Equal(t, "req-123", pubsub.CorrelationID(msg.Context()))
+
+		d, ok := pubsub.DeliveryFromContext(msg.Context())
+		if assert.True(t, ok) {
+			assert.Equal(t, "orders", d.Topic)
+			assert.Equal(t, "order_created", d.Subject)
+			assert.Equal(t, queue, d.Queue)
+			assert.Equal(t, 1, d.ReceiveCount)
+		}
+
+		assert.NoError(t, msg.Done())
+		assert.NoError(t, sub.Stop())
+		assert.Equal(t, 0, broker.QueueLen(queue))
+	}
+}
+
+type tenantPropagator struct{}
+
+type tenantKey struct{}
+
+func (tenantPropagator) Inject(ctx context.Context, attrs map[string]string) {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		attrs["tenant"] = tenant
+	}
+}
+
+func (tenantPropagator) Extract(ctx context.Context, attrs map[string]string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, attrs["tenant"])
+}
+
+func TestBrokerPropagator(t *testing.T) {
+	broker := mempubsub.NewBroker().SetPropagator(tenantPropagator{})
+	broker.Subscribe("orders", "billing")
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "de")
+	ctx = pubsub.SetCorrelationID(ctx, "req-123")
+	assert.NoError(t, broker.NewPublisher("orders").Publish(ctx, "", "order"))
+
+	sub := broker.NewSubscriber("billing")
+	msg := receive(t, sub.Start())
+	assert.Equal(t, "de", msg.Attributes().Get("tenant"))
+	assert.Equal(t, "", msg.Attributes().Get(pubsub.CorrelationIDAttribute))
+	assert.Equal(t, "de", msg.Context().Value(tenantKey{}))
+
+	assert.NoError(t, msg.Done())
+	assert.NoError(t, sub.Stop())
+}
+
+func TestBrokerVisibilityTimeout(t *testing.T) {
+	broker := mempubsub.NewBroker()
+	broker.CreateQueue("orders", 20*time.Millisecond)
+	broker.Subscribe("orders", "orders")
+
+	sub := broker.NewSubscriber("orders")
+	msgs := sub.Start()
+	defer sub.Stop()
+
+	assert.NoError(t, broker.NewPublisher("orders").Publish(context.Background(), "", "order"))
+
+	first := receive(t, msgs)
+	count, _ := first.GetReceiveCount()
+	assert.Equal(t, 1, count)
+
+	// not acknowledged, it's received again after the visibility timeout
+	second := receive(t, msgs)
+	count, _ = second.GetReceiveCount()
+	assert.Equal(t, 2, count)
+	assert.Equal(t, first.GetMessageId(), second.GetMessageId())
+
+	// first receipt is not valid anymore
+	assert.Error(t, first.Done())
+	assert.Error(t, first.ExtendDoneDeadline(time.Hour))
+
+	assert.NoError(t, second.ExtendDoneDeadline(time.Hour))
+	select {
+	case <-msgs:
+		t.Error("message should not be visible")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.NoError(t, second.ExtendDoneDeadline(0))
+	third := receive(t, msgs)
+	assert.NoError(t, third.Done())
+	assert.Equal(t, 0, broker.QueueLen("orders"))
+}
+
+func TestBrokerSubscriberStop(t *testing.T) {
+	broker := mempubsub.NewBroker()
+	sub := broker.NewSubscriber("orders")
+
+	assert.Error(t, sub.Stop())
+
+	msgs := sub.Start()
+	assert.Nil(t, sub.Start())
+	assert.Error(t, sub.Err())
+
+	assert.NoError(t, sub.Stop())
+	_, ok := <-msgs
+	assert.False(t, ok)
+}
+
+func TestBrokerAssertPublished(t *testing.T) {
+	broker := mempubsub.NewBroker()
+	pub := broker.NewPublisher("orders")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		pub.Publish(context.Background(), "", "1")
+		pub.Publish(context.Background(), "", "2")
+	}()
+
+	msgs := broker.AssertPublished(t, "orders", 2, time.Second)
+	if assert.Len(t, msgs, 2) {
+		assert.Equal(t, "1", msgs[0].Body)
+		assert.Equal(t, "orders", msgs[0].Topic)
+	}
+
+	fake := &testingT{}
+	msgs = broker.AssertPublished(fake, "orders", 3, 10*time.Millisecond)
+	assert.Len(t, msgs, 2)
+	assert.Len(t, fake.errors, 1)
+
+	broker.Reset()
+	assert.Empty(t, broker.Published("orders"))
+}
+
+func TestBrokerConsumer(t *testing.T) {
+	broker := mempubsub.NewBroker()
+	broker.Subscribe("orders", "orders")
+
+	processed := make(chan string, 1)
+	consumer := pubsub.NewConsumer(broker.NewSubscriber("orders"), func(ctx context.Context, msg pubsub.Message) error {
+		processed <- msg.String()
+		return nil
+	}, pubsub.ConsumerConfig{Workers: 1})
+	go consumer.Start()
+
+	broker.NewPublisher("orders").Publish(context.Background(), "", "order")
+	assert.Equal(t, "order", <-processed)
+	assert.NoError(t, consumer.Stop(context.Background()))
+	assert.Equal(t, 0, broker.QueueLen("orders"))
+}
@@ -0,0 +1,71 @@
+package mempubsub
+
+import (
+	"context"
+
+	"github.com/foodora/go-ranger/pubsub"
+)
+
+type publisher struct {
+	broker     *Broker
+	topic      string
+	propagator pubsub.Propagator
+}
+
+// NewPublisher create a publisher sending messages to topic by default.
+// Values of the context are injected as attributes by the propagator of the
+// broker, check Broker.SetPropagator.
+func (b *Broker) NewPublisher(topic string) pubsub.Publisher {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return &publisher{
+		broker:     b,
+		topic:      topic,
+		propagator: b.propagator,
+	}
+}
+
+// Publish send the message to the default topic of the publisher.
+func (p *publisher) Publish(ctx context.Context, key string, m string) error {
+	return p.PublishMessage(ctx, &pubsub.OutgoingMessage{
+		Key:  key,
+		Body: m,
+	})
+}
+
+// PublishToTopic send the message to the specified topic.
+func (p *publisher) PublishToTopic(ctx context.Context, key string, m string, topic string) error {
+	return p.PublishMessage(ctx, &pubsub.OutgoingMessage{
+		Key:   key,
+		Body:  m,
+		Topic: topic,
+	})
+}
+
+// PublishMessage send the message to its topic, or the default one of the
+// publisher.
+func (p *publisher) PublishMessage(ctx context.Context, m *pubsub.OutgoingMessage) error {
+	// keep a copy, the caller may reuse the message
+	msg := *m
+	if msg.Topic == "" {
+		msg.Topic = p.topic
+	}
+
+	msg.Attributes = pubsub.InjectAttributes(ctx, p.propagator, m.Attributes)
+
+	return p.broker.publish(&msg)
+}
+
+// PublishBatch publish messages one by one, it implements
+// pubsub.BatchPublisher.
+func (p *publisher) PublishBatch(ctx context.Context, msgs []*pubsub.OutgoingMessage) pubsub.PublishResults {
+	results := make(pubsub.PublishResults, len(msgs))
+	for i, m := range msgs {
+		results[i] = pubsub.PublishResult{
+			Message: m,
+			Err:     p.PublishMessage(ctx, m),
+		}
+	}
+	return results
+}
@@ -0,0 +1,226 @@
+package mempubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/foodora/go-ranger/pubsub"
+)
+
+type (
+	subscriber struct {
+		broker     *Broker
+		queue      string
+		propagator pubsub.Propagator
+
+		mu      sync.Mutex
+		running bool
+		stop    chan struct{}
+		done    chan struct{}
+		err     error
+
+		onErrorFunc func(error)
+	}
+
+	subscriberMessage struct {
+		broker       *Broker
+		queue        *queue
+		id           string
+		receipt      int
+		receiveCount int
+		msg          *pubsub.OutgoingMessage
+		ctx          context.Context
+	}
+)
+
+// NewSubscriber create a subscriber receiving messages from queue, creating it
+// if necessary. The context of messages is extracted from their attributes by
+// the propagator of the broker, check Broker.SetPropagator.
+func (b *Broker) NewSubscriber(queue string) pubsub.Subscriber {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.queue(queue)
+	return &subscriber{
+		broker:     b,
+		queue:      queue,
+		propagator: b.propagator,
+	}
+}
+
+// Start receive messages from the queue until Stop is called.
+func (s *subscriber) Start() <-chan pubsub.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		s.err = errors.New("subscriber already is running")
+		return nil
+	}
+	s.running = true
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	output := make(chan pubsub.Message)
+	go s.receive(output, s.stop, s.done)
+
+	return output
+}
+
+func (s *subscriber) receive(output chan<- pubsub.Message, stop, done chan struct{}) {
+	defer close(done)
+	defer close(output)
+
+	for {
+		s.broker.mu.Lock()
+		q := s.broker.queues[s.queue]
+		m, wait := q.receive(time.Now())
+		changed := s.broker.changed
+		s.broker.mu.Unlock()
+
+		if m == nil {
+			// wait until a message is published or becomes visible
+			var timer *time.Timer
+			var next <-chan time.Time
+			if wait >= 0 {
+				timer = time.NewTimer(wait)
+				next = timer.C
+			}
+
+			stopped := false
+			select {
+			case <-stop:
+				stopped = true
+			case <-changed:
+			case <-next:
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			if stopped {
+				return
+			}
+			continue
+		}
+
+		msg := s.newMessage(q, m)
+		select {
+		case <-stop:
+			// message was not delivered, it can be received again
+			msg.ExtendDoneDeadline(0)
+			return
+		case output <- msg:
+		}
+	}
+}
+
+func (s *subscriber) newMessage(q *queue, m *queuedMessage) *subscriberMessage {
+	msg := &subscriberMessage{
+		broker:       s.broker,
+		queue:        q,
+		id:           m.id,
+		receipt:      m.receipt,
+		receiveCount: m.receiveCount,
+		msg:          m.msg,
+	}
+
+	ctx := pubsub.SetDelivery(context.Background(), &pubsub.Delivery{
+		MessageID:       m.id,
+		Topic:           m.msg.Topic,
+		Subject:         m.msg.Key,
+		Queue:           q.name,
+		GroupID:         m.msg.GroupID,
+		ReceiveCount:    m.receiveCount,
+		PublishedAt:     m.publishedAt,
+		FirstReceivedAt: m.firstReceivedAt,
+	})
+	msg.ctx = pubsub.ExtractAttributes(ctx, s.propagator, m.msg.Attributes)
+
+	return msg
+}
+
+// Stop will block until the subscriber has stopped.
+func (s *subscriber) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return errors.New("mempubsub subscriber is not running")
+	}
+	s.running = false
+
+	close(s.stop)
+	<-s.done
+	return nil
+}
+
+// Err return the error of the last call to Start.
+func (s *subscriber) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// SetOnErrorFunc sets subscriber's onErrorFunc field, the broker never fails
+// so it's never called.
+func (s *subscriber) SetOnErrorFunc(fn func(error)) {
+	s.onErrorFunc = fn
+}
+
+// String return the body of the message.
+func (m *subscriberMessage) String() string {
+	return m.msg.Body
+}
+
+// GetMessageId return the id of the message in the queue.
+func (m *subscriberMessage) GetMessageId() string {
+	return m.id
+}
+
+// GetReceiveCount return the number of times the message was received.
+func (m *subscriberMessage) GetReceiveCount() (int, error) {
+	return m.receiveCount, nil
+}
+
+// Attributes return the attributes sent with the message.
+func (m *subscriberMessage) Attributes() pubsub.Attributes {
+	return m.msg.Attributes
+}
+
+// Context return the context extracted from the message attributes, with the
+// pubsub.Delivery metadata.
+func (m *subscriberMessage) Context() context.Context {
+	return m.ctx
+}
+
+// Done remove the message from the queue. It fails when the message was
+// received again after its visibility timeout.
+func (m *subscriberMessage) Done() error {
+	m.broker.mu.Lock()
+	defer m.broker.mu.Unlock()
+
+	i, _ := m.queue.find(m.id, m.receipt)
+	if i < 0 {
+		return errors.New("mempubsub: message receipt is not valid anymore")
+	}
+
+	m.queue.messages = append(m.queue.messages[:i], m.queue.messages[i+1:]...)
+	return nil
+}
+
+// ExtendDoneDeadline make the message visible again after d.
+func (m *subscriberMessage) ExtendDoneDeadline(d time.Duration) error {
+	m.broker.mu.Lock()
+	defer m.broker.mu.Unlock()
+
+	_, qm := m.queue.find(m.id, m.receipt)
+	if qm == nil {
+		return errors.New("mempubsub: message receipt is not valid anymore")
+	}
+
+	qm.visibleAt = time.Now().Add(d)
+	m.broker.notify()
+	return nil
+}
@@ -0,0 +1,111 @@
+package pubsub
+
+import "context"
+
+// Propagator copy request values, like trace and correlation ids, between a
+// context and message attributes. Publishers call Inject before sending the
+// message and subscribers call Extract to build the context of each message.
+type Propagator interface {
+	// Inject add values of ctx into attrs.
+	Inject(ctx context.Context, attrs map[string]string)
+	// Extract return a copy of ctx with values read from attrs.
+	Extract(ctx context.Context, attrs map[string]string) context.Context
+}
+
+// Propagators combine multiple propagators, all of them are called in order:
+//
+//  pubsub.DefaultPropagator = pubsub.Propagators{
+//  	pubsub.CorrelationIDPropagator{},
+//  	fdapm.TracePropagator{},
+//  }
+type Propagators []Propagator
+
+// Inject implements Propagator.
+func (p Propagators) Inject(ctx context.Context, attrs map[string]string) {
+	for _, propagator := range p {
+		propagator.Inject(ctx, attrs)
+	}
+}
+
+// Extract implements Propagator.
+func (p Propagators) Extract(ctx context.Context, attrs map[string]string) context.Context {
+	for _, propagator := range p {
+		ctx = propagator.Extract(ctx, attrs)
+	}
+	return ctx
+}
+
+// DefaultPropagator is used by publishers and subscribers that don't have one
+// configured.
+var DefaultPropagator Propagator = CorrelationIDPropagator{}
+
+// InjectAttributes merge attrs with the values of ctx injected by propagator,
+// attributes of the message have precedence. When propagator is nil
+// DefaultPropagator is used. It always returns a new map.
+func InjectAttributes(ctx context.Context, propagator Propagator, attrs Attributes) Attributes {
+	if propagator == nil {
+		propagator = DefaultPropagator
+	}
+
+	injected := make(map[string]string)
+	if propagator != nil {
+		propagator.Inject(ctx, injected)
+	}
+
+	merged := make(Attributes, len(attrs)+len(injected))
+	for k, v := range injected {
+		merged[k] = StringAttribute(v)
+	}
+	for k, v := range attrs {
+		merged[k] = v
+	}
+	return merged
+}
+
+// ExtractAttributes return ctx with the values extracted from attrs by
+// propagator. When propagator is nil DefaultPropagator is used.
+func ExtractAttributes(ctx context.Context, propagator Propagator, attrs Attributes) context.Context {
+	if propagator == nil {
+		propagator = DefaultPropagator
+	}
+	if propagator == nil {
+		return ctx
+	}
+	return propagator.Extract(ctx, attrs.Strings())
+}
+
+// CorrelationIDAttribute is the message attribute used to send correlation ids.
+const CorrelationIDAttribute = "correlation_id"
+
+type correlationIDKey struct{}
+
+// SetCorrelationID set the id used to follow a flow across services, usually
+// the request id of the http request that started it.
+func SetCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID return the id set by SetCorrelationID or an empty string.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// CorrelationIDPropagator send the correlation id of the context as the
+// CorrelationIDAttribute message attribute.
+type CorrelationIDPropagator struct{}
+
+// Inject implements Propagator.
+func (CorrelationIDPropagator) Inject(ctx context.Context, attrs map[string]string) {
+	if id := CorrelationID(ctx); id != "" {
+		attrs[CorrelationIDAttribute] = id
+	}
+}
+
+// Extract implements Propagator.
+func (CorrelationIDPropagator) Extract(ctx context.Context, attrs map[string]string) context.Context {
+	if id := attrs[CorrelationIDAttribute]; id != "" {
+		return SetCorrelationID(ctx, id)
+	}
+	return ctx
+}
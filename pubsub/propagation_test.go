@@ -0,0 +1,70 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/foodora/go-ranger/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+type testPropagator struct {
+	key, value string
+}
+
+func (p testPropagator) Inject(ctx context.Context, attrs map[string]string) {
+	attrs[p.key] = p.value
+}
+
+func (p testPropagator) Extract(ctx context.Context, attrs map[string]string) context.Context {
+	return context.WithValue(ctx, p.key, attrs[p.key])
+}
+
+func TestCorrelationIDPropagator(t *testing.T) {
+	attrs := map[string]string{}
+	pubsub.CorrelationIDPropagator{}.Inject(context.Background(), attrs)
+	assert.Empty(t, attrs)
+
+	ctx := pubsub.SetCorrelationID(context.Background(), "req-123")
+	pubsub.CorrelationIDPropagator{}.Inject(ctx, attrs)
+	assert.Equal(t, map[string]string{pubsub.CorrelationIDAttribute: "req-123"}, attrs)
+
+	ctx = pubsub.CorrelationIDPropagator{}.Extract(context.Background(), attrs)
+	assert.Equal(t, "req-123", pubsub.CorrelationID(ctx))
+
+	ctx = pubsub.CorrelationIDPropagator{}.Extract(context.Background(), map[string]string{})
+	assert.Equal(t, "", pubsub.CorrelationID(ctx))
+}
+
+func TestPropagators(t *testing.T) {
+	p := pubsub.Propagators{
+		testPropagator{"a", "1"},
+		testPropagator{"b", "2"},
+	}
+
+	attrs := map[string]string{}
+	p.Inject(context.Background(), attrs)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, attrs)
+
+	ctx := p.Extract(context.Background(), attrs)
+	assert.Equal(t, "1", ctx.Value("a"))
+	assert.Equal(t, "2", ctx.Value("b"))
+}
+
+func TestInjectAttributes(t *testing.T) {
+	ctx := pubsub.SetCorrelationID(context.Background(), "req-123")
+	attrs := pubsub.Attributes{"a": pubsub.StringAttribute("message")}
+
+	merged := pubsub.InjectAttributes(ctx, testPropagator{"a", "1"}, attrs)
+	assert.Equal(t, pubsub.Attributes{"a": pubsub.StringAttribute("message")}, merged)
+
+	merged = pubsub.InjectAttributes(ctx, nil, attrs)
+	assert.Equal(t, "req-123", merged.Get(pubsub.CorrelationIDAttribute))
+	assert.Equal(t, "message", merged.Get("a"))
+	assert.Len(t, attrs, 1)
+
+	assert.Equal(t, "req-123", pubsub.CorrelationID(pubsub.ExtractAttributes(context.Background(), nil, merged)))
+	ctx = pubsub.ExtractAttributes(context.Background(), testPropagator{"a", ""}, merged)
+	assert.Equal(t, "message", ctx.Value("a"))
+	assert.Equal(t, "", pubsub.CorrelationID(ctx))
+}
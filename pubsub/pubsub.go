@@ -12,6 +12,10 @@ type Publisher interface {
 
 	// Publish will publish a message with context.
 	PublishToTopic(ctx context.Context, key string, m string, topic string) error
+
+	// PublishMessage will publish a message with attributes to its topic,
+	// or the default one when it's not informed.
+	PublishMessage(ctx context.Context, m *OutgoingMessage) error
 }
 
 // Subscriber ...
@@ -26,6 +30,34 @@ type Subscriber interface {
 	SetOnErrorFunc(fn func(error))
 }
 
+// ContextStopper is implemented by subscribers able to bound the time of their
+// graceful shutdown.
+type ContextStopper interface {
+	// StopWithContext stop the subscriber like Stop, returning ctx.Err()
+	// when ctx is done before it finishes.
+	StopWithContext(ctx context.Context) error
+}
+
+// StopSubscriber stop sub, returning when ctx is done even if Stop didn't
+// return yet.
+func StopSubscriber(ctx context.Context, sub Subscriber) error {
+	if s, ok := sub.(ContextStopper); ok {
+		return s.StopWithContext(ctx)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sub.Stop()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Message ...
 type Message interface {
 	String() string
@@ -33,4 +65,10 @@ type Message interface {
 	Done() error
 	GetReceiveCount() (int, error)
 	GetMessageId() string
+	// Attributes return the attributes sent with the message.
+	Attributes() Attributes
+	// Context return the context sent by the publisher, with values like trace
+	// and correlation ids extracted by the subscriber's Propagator, and the
+	// Delivery metadata.
+	Context() context.Context
 }
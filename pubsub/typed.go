@@ -0,0 +1,83 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// DecodeError is given to OnDecodeError when a message can't be decoded.
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return "pubsub: unable to decode message: " + e.Err.Error()
+}
+
+// TypedHandlerConfig configure how TypedHandler decode messages.
+type TypedHandlerConfig struct {
+	// Codecs are used to decode messages by their ContentTypeAttribute, by
+	// default DefaultCodecs.
+	Codecs Codecs
+	// OnDecodeError is called with messages that can't be decoded, they're
+	// acknowledged when it returns nil. By default the *DecodeError is
+	// returned to the consumer, so the message is retried and dead-lettered
+	// according to its ConsumerConfig.
+	OnDecodeError func(ctx context.Context, msg Message, err error) error
+}
+
+// TypedHandler adapt fn to a Handler, decoding messages into the type of its
+// second argument. fn must be a func(context.Context, T) error, where T is a
+// pointer when the codec requires it, like ProtobufCodec:
+//
+//  handler := pubsub.TypedHandler(func(ctx context.Context, o *Order) error {
+//  	return process(ctx, o)
+//  }, pubsub.TypedHandlerConfig{})
+//  consumer := pubsub.NewConsumer(sub, handler, pubsub.ConsumerConfig{})
+//
+// It panics when fn doesn't have the expected signature.
+func TypedHandler(fn interface{}, cfg TypedHandlerConfig) Handler {
+	fv := reflect.ValueOf(fn)
+	ft := reflect.TypeOf(fn)
+	if fv.Kind() != reflect.Func || ft.NumIn() != 2 || ft.NumOut() != 1 ||
+		ft.In(0) != contextType || ft.Out(0) != errorType {
+		panic(fmt.Sprintf("pubsub: handler must be a func(context.Context, T) error, got %T", fn))
+	}
+
+	if cfg.Codecs == nil {
+		cfg.Codecs = DefaultCodecs
+	}
+
+	argType := ft.In(1)
+	return func(ctx context.Context, msg Message) error {
+		// decode into a pointer, dereferencing it when T is not one
+		var v reflect.Value
+		if argType.Kind() == reflect.Ptr {
+			v = reflect.New(argType.Elem())
+		} else {
+			v = reflect.New(argType)
+		}
+
+		if err := cfg.Codecs.Decode(msg, v.Interface()); err != nil {
+			err = &DecodeError{Err: err}
+			if cfg.OnDecodeError == nil {
+				return err
+			}
+			return cfg.OnDecodeError(ctx, msg, err)
+		}
+
+		if argType.Kind() != reflect.Ptr {
+			v = v.Elem()
+		}
+
+		out := fv.Call([]reflect.Value{reflect.ValueOf(ctx), v})
+		err, _ := out[0].Interface().(error)
+		return err
+	}
+}